@@ -0,0 +1,262 @@
+package ast
+
+import (
+	"csigma/parser"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Exportação da AST para ferramentas: um grafo do Graphviz (--emit=ast-dot),
+// com os comandos e as árvores das expressões, e um JSON sem perdas
+// (--emit=ast-json), que Load lê de volta para os nós do pacote parser.
+//
+// As expressões do Sigma são lineares (avaliadas da esquerda para a direita),
+// então 'a + b * 2' vira a árvore ((a + b) * 2): cada operador tem à esquerda
+// tudo o que veio antes dele.
+
+// --- GRAPHVIZ (DOT) ---
+
+// DOT: O programa como um grafo 'dot' (renderize com 'dot -Tpng arq.dot').
+// Depois do Analisador Semântico, as expressões mostram o tipo (SIGMA_INT...).
+func DOT(statements []parser.Statement) string {
+	g := &graph{}
+	g.sb.WriteString("digraph AST {\n")
+	g.sb.WriteString("    node [shape=box, fontname=\"monospace\"];\n")
+	root := g.node("Programa", "shape=ellipse")
+	for _, stmt := range statements {
+		g.edge(root, g.statement(stmt), "")
+	}
+	g.sb.WriteString("}\n")
+	return g.sb.String()
+}
+
+// graph: O texto do grafo e o contador dos nós (n0, n1...).
+type graph struct {
+	sb    strings.Builder
+	count int
+}
+
+// node: Cria um nó com o rótulo (e atributos extras) e devolve o seu nome.
+func (g *graph) node(label, attrs string) string {
+	name := fmt.Sprintf("n%d", g.count)
+	g.count++
+	if attrs != "" {
+		attrs = ", " + attrs
+	}
+	fmt.Fprintf(&g.sb, "    %s [label=%s%s];\n", name, quote(label), attrs)
+	return name
+}
+
+func (g *graph) edge(from, to, label string) {
+	if label != "" {
+		fmt.Fprintf(&g.sb, "    %s -> %s [label=%s];\n", from, to, quote(label))
+		return
+	}
+	fmt.Fprintf(&g.sb, "    %s -> %s;\n", from, to)
+}
+
+// statement: O nó de um comando, com os filhos.
+func (g *graph) statement(stmt parser.Statement) string {
+	switch s := stmt.(type) {
+	case *parser.VarDeclNode:
+		label := fmt.Sprintf("var %s = %s", s.Name, s.Value)
+		if s.IsString {
+			label = fmt.Sprintf("var %s = \"%s\"", s.Name, s.Value)
+		}
+		if s.ElemType != "" {
+			size := fmt.Sprint(s.Size)
+			if s.SizeConst != "" {
+				size = s.SizeConst
+				if s.Size > 0 {
+					size += fmt.Sprintf(" = %d", s.Size)
+				}
+			}
+			label = fmt.Sprintf("var %s: %s[%s]", s.Name, s.ElemType, size)
+		}
+		return g.node(label+lineSuffix(s.Line), "")
+	case *parser.ConstDeclNode:
+		label := "const " + s.Name
+		if s.Valor != "" {
+			label += " = " + s.Valor
+		}
+		n := g.node(label+lineSuffix(s.Line), "")
+		g.edge(n, g.expr(&s.ExprNode), "")
+		return n
+	case *parser.PrintNode:
+		return g.print(s, lineSuffix(s.Line))
+	case *parser.InputNode:
+		label := "input " + s.VarName
+		if s.Tipo != "" {
+			label += " : " + s.Tipo
+		}
+		n := g.node(label+lineSuffix(s.Line), "")
+		if s.Index != nil {
+			g.edge(n, g.expr(s.Index), "índice")
+		}
+		if s.Prompt != nil {
+			g.edge(n, g.print(s.Prompt, ""), "prompt")
+		}
+		return n
+	case *parser.AssignmentNode:
+		n := g.node("="+lineSuffix(s.Line), "")
+		dest := g.node(s.Dest, "shape=ellipse")
+		if s.DestIndex != nil {
+			g.edge(dest, g.expr(s.DestIndex), "índice")
+		}
+		g.edge(n, dest, "destino")
+		g.edge(n, g.expr(&s.ExprNode), "valor")
+		return n
+	}
+	return g.node(fmt.Sprintf("%T", stmt), "")
+}
+
+// print: 'print' ou 'write', com os pedaços do texto (fixos e expressões).
+func (g *graph) print(s *parser.PrintNode, suffix string) string {
+	cmd := "print"
+	if s.NoNewline {
+		cmd = "write"
+	}
+	if s.IsString && s.Parts == nil {
+		return g.node(fmt.Sprintf("%s \"%s\"%s", cmd, s.Value, suffix), "")
+	}
+	n := g.node(cmd+suffix, "")
+	for _, part := range s.Parts {
+		if part.Expr != nil {
+			g.edge(n, g.expr(part.Expr), "")
+		} else {
+			g.edge(n, g.node("\""+part.Text+"\"", "shape=plaintext"), "")
+		}
+	}
+	return n
+}
+
+// expr: A árvore da expressão: o último operador é a raiz, com o tipo.
+func (g *graph) expr(e *parser.ExprNode) string {
+	left := g.operand(e.First)
+	for _, op := range e.Ops {
+		n := g.node(op.Operator, "shape=circle")
+		g.edge(n, left, "")
+		g.edge(n, g.operand(op.Operand), "")
+		left = n
+	}
+	if e.Tipo != "" {
+		n := g.node(e.Tipo, "shape=note")
+		g.edge(n, left, "")
+		left = n
+	}
+	return left
+}
+
+// operand: Literal, texto, variável (com o índice) ou chamada (com os argumentos).
+func (g *graph) operand(o parser.Operand) string {
+	switch {
+	case o.IsCall:
+		n := g.node(o.Value+"()", "shape=ellipse")
+		for _, arg := range o.Args {
+			g.edge(n, g.operand(arg), "")
+		}
+		return n
+	case o.IsVar:
+		n := g.node(o.Value, "shape=ellipse")
+		if o.Index != nil {
+			g.edge(n, g.expr(o.Index), "índice")
+		}
+		return n
+	}
+	return g.node(o.String(), "shape=plaintext")
+}
+
+func lineSuffix(line int) string {
+	if line == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\nlinha %d", line)
+}
+
+// quote: Um texto entre aspas na sintaxe do DOT (\n vira quebra de linha).
+func quote(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	s = strings.ReplaceAll(s, "\"", "\\\"")
+	return "\"" + strings.ReplaceAll(s, "\n", "\\n") + "\""
+}
+
+// --- JSON ---
+
+// Version: A versão do formato JSON. Muda quando um nó muda de forma.
+const Version = 1
+
+// document: O JSON completo: a versão e os comandos, na ordem do fonte.
+type document struct {
+	Version    int    `json:"version"`
+	Statements []node `json:"statements"`
+}
+
+// node: Um comando. Kind diz o tipo do nó; Node traz todos os campos dele
+// (com os nomes do pacote parser), inclusive o trecho do fonte (Span) e o
+// que o Analisador Semântico preencheu.
+type node struct {
+	Kind string          `json:"kind"`
+	Node json.RawMessage `json:"node"`
+}
+
+// kinds: O nome de cada tipo de comando no JSON.
+var kinds = map[string]func() parser.Statement{
+	"var":    func() parser.Statement { return &parser.VarDeclNode{} },
+	"const":  func() parser.Statement { return &parser.ConstDeclNode{} },
+	"print":  func() parser.Statement { return &parser.PrintNode{} },
+	"input":  func() parser.Statement { return &parser.InputNode{} },
+	"assign": func() parser.Statement { return &parser.AssignmentNode{} },
+}
+
+// JSON: A AST serializada, sem perdas.
+func JSON(statements []parser.Statement) ([]byte, error) {
+	doc := document{Version: Version, Statements: []node{}}
+	for _, stmt := range statements {
+		var kind string
+		switch stmt.(type) {
+		case *parser.VarDeclNode:
+			kind = "var"
+		case *parser.ConstDeclNode:
+			kind = "const"
+		case *parser.PrintNode:
+			kind = "print"
+		case *parser.InputNode:
+			kind = "input"
+		case *parser.AssignmentNode:
+			kind = "assign"
+		default:
+			return nil, fmt.Errorf("comando desconhecido na AST: %T", stmt)
+		}
+		data, err := json.Marshal(stmt)
+		if err != nil {
+			return nil, err
+		}
+		doc.Statements = append(doc.Statements, node{Kind: kind, Node: data})
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// Load: Lê o JSON gerado por JSON de volta para os nós do parser.
+func Load(data []byte) ([]parser.Statement, error) {
+	var doc document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Version != Version {
+		return nil, fmt.Errorf("versão %d da AST não suportada (esperada %d)", doc.Version, Version)
+	}
+	var statements []parser.Statement
+	for i, n := range doc.Statements {
+		newNode, ok := kinds[n.Kind]
+		if !ok {
+			return nil, fmt.Errorf("comando %d: tipo desconhecido '%s'", i, n.Kind)
+		}
+		stmt := newNode()
+		if err := json.Unmarshal(n.Node, stmt); err != nil {
+			return nil, fmt.Errorf("comando %d (%s): %v", i, n.Kind, err)
+		}
+		statements = append(statements, stmt)
+	}
+	return statements, nil
+}
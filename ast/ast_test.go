@@ -0,0 +1,46 @@
+package ast
+
+import (
+	"csigma/lexer"
+	"csigma/parser"
+	"csigma/semantic"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Load(JSON(comandos)) devolve os mesmos comandos, com os tipos anotados pelo
+// Analisador Semântico, para todos os exemplos.
+func TestJSONRoundTrip(t *testing.T) {
+	files, err := filepath.Glob("../exemplos/*.sig")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("nenhum exemplo encontrado (%v)", err)
+	}
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			src, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			statements, err := parser.NewParser(lexer.Tokenize(string(src))).ParseProgram()
+			if err != nil {
+				t.Skipf("o exemplo não passa pelo parser: %v", err) // teste.sig, com palavras-chave em maiúsculas
+			}
+			semantic.NewAnalyzer().Validar(statements)
+
+			data, err := JSON(statements)
+			if err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := Load(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(loaded, statements) {
+				again, _ := JSON(loaded)
+				t.Errorf("Load(JSON(comandos)) mudou a AST:\n%s\nquer:\n%s", again, data)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package ast
+
+import (
+	"csigma/parser"
+	"fmt"
+	"strings"
+)
+
+// --- ÁRVORE EM TEXTO ---
+
+// Tree: Os comandos como árvores desenhadas em texto, para o terminal (o REPL
+// e o depurador). Mesma estrutura do grafo do DOT:
+//
+//	= (linha 2)
+//	├── destino: x
+//	└── valor: SIGMA_INT
+//	    └── *
+//	        ├── x
+//	        └── 3
+func Tree(statements []parser.Statement) string {
+	var sb strings.Builder
+	for _, stmt := range statements {
+		n := treeStatement(stmt)
+		sb.WriteString(n.label + "\n")
+		n.render(&sb, "")
+	}
+	return sb.String()
+}
+
+// tnode: Um nó da árvore em texto.
+type tnode struct {
+	label string
+	kids  []*tnode
+}
+
+func (n *tnode) add(kids ...*tnode) *tnode {
+	n.kids = append(n.kids, kids...)
+	return n
+}
+
+// render: Os filhos do nó, com as linhas que os ligam.
+func (n *tnode) render(sb *strings.Builder, indent string) {
+	for i, kid := range n.kids {
+		branch, next := "├── ", "│   "
+		if i == len(n.kids)-1 {
+			branch, next = "└── ", "    "
+		}
+		sb.WriteString(indent + branch + kid.label + "\n")
+		kid.render(sb, indent+next)
+	}
+}
+
+func treeStatement(stmt parser.Statement) *tnode {
+	switch s := stmt.(type) {
+	case *parser.VarDeclNode:
+		label := fmt.Sprintf("var %s = %s", s.Name, s.Value)
+		if s.IsString {
+			label = fmt.Sprintf("var %s = \"%s\"", s.Name, s.Value)
+		}
+		if s.ElemType != "" {
+			size := fmt.Sprint(s.Size)
+			if s.SizeConst != "" {
+				size = s.SizeConst
+			}
+			label = fmt.Sprintf("var %s: %s[%s]", s.Name, s.ElemType, size)
+		}
+		return &tnode{label: label + lineLabel(s.Line)}
+	case *parser.ConstDeclNode:
+		label := "const " + s.Name
+		if s.Valor != "" {
+			label += " = " + s.Valor
+		}
+		return (&tnode{label: label + lineLabel(s.Line)}).add(treeExpr(&s.ExprNode, ""))
+	case *parser.PrintNode:
+		return treePrint(s, lineLabel(s.Line))
+	case *parser.InputNode:
+		label := "input " + s.VarName
+		if s.Tipo != "" {
+			label += " : " + s.Tipo
+		}
+		n := &tnode{label: label + lineLabel(s.Line)}
+		if s.Index != nil {
+			n.add(treeExpr(s.Index, "índice: "))
+		}
+		if s.Prompt != nil {
+			p := treePrint(s.Prompt, "")
+			p.label = "prompt: " + p.label
+			n.add(p)
+		}
+		return n
+	case *parser.AssignmentNode:
+		dest := &tnode{label: "destino: " + s.Dest}
+		if s.DestIndex != nil {
+			dest.add(treeExpr(s.DestIndex, "índice: "))
+		}
+		return (&tnode{label: "=" + lineLabel(s.Line)}).add(dest, treeExpr(&s.ExprNode, "valor: "))
+	}
+	return &tnode{label: fmt.Sprintf("%T", stmt)}
+}
+
+func treePrint(s *parser.PrintNode, suffix string) *tnode {
+	cmd := "print"
+	if s.NoNewline {
+		cmd = "write"
+	}
+	if s.IsString && s.Parts == nil {
+		return &tnode{label: fmt.Sprintf("%s \"%s\"%s", cmd, s.Value, suffix)}
+	}
+	n := &tnode{label: cmd + suffix}
+	for _, part := range s.Parts {
+		if part.Expr != nil {
+			n.add(treeExpr(part.Expr, ""))
+		} else {
+			n.add(&tnode{label: "\"" + part.Text + "\""})
+		}
+	}
+	return n
+}
+
+// treeExpr: A árvore da expressão sob um nó com o tipo (se já anotado): o
+// último operador é a raiz.
+func treeExpr(e *parser.ExprNode, prefix string) *tnode {
+	root := treeOperand(e.First)
+	for _, op := range e.Ops {
+		root = (&tnode{label: op.Operator}).add(root, treeOperand(op.Operand))
+	}
+	if e.Tipo == "" {
+		root.label = prefix + root.label
+		return root
+	}
+	return (&tnode{label: prefix + e.Tipo}).add(root)
+}
+
+func treeOperand(o parser.Operand) *tnode {
+	switch {
+	case o.IsCall:
+		n := &tnode{label: o.Value + "()"}
+		for _, arg := range o.Args {
+			n.add(treeOperand(arg))
+		}
+		return n
+	case o.IsVar:
+		n := &tnode{label: o.Value}
+		if o.Index != nil {
+			n.add(treeExpr(o.Index, "índice: "))
+		}
+		return n
+	}
+	return &tnode{label: o.String()}
+}
+
+func lineLabel(line int) string {
+	if line == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (linha %d)", line)
+}
@@ -0,0 +1,520 @@
+package cgen
+
+import (
+	"csigma/ir"
+	"csigma/parser"
+	"fmt"
+	"strings"
+)
+
+// Backend C: Traduz a AST (já validada pelo Analisador Semântico) para um
+// arquivo C99 independente, compilado pelo compilador C do sistema. Não passa
+// pela IR nem pelo Assembly: é uma segunda implementação da semântica do Sigma,
+// útil para portar programas e para conferir a saída dos backends x86_64.
+//
+//	var x = 10          => int64_t x = 10;
+//	var v: float[3]     => static double v[3];
+//	const MAX = 100     => #define MAX INT64_C(100)
+//	res = a + b * 2     => res = (a + b) * 2;     (esquerda para a direita)
+//	print "x = {x}"     => printf("x = %" PRId64 "\n", x);
+
+// Extension: Extensão do arquivo gerado.
+const Extension = ".c"
+
+// CompileCommand: Compila o arquivo C gerado. -fwrapv dá à aritmética inteira
+// o mesmo transbordo (overflow) em complemento de dois das instruções x86_64.
+func CompileCommand(src, exe string, optimize bool) []string {
+	cmd := []string{"cc", "-std=c99", "-fwrapv"}
+	if optimize {
+		cmd = append(cmd, "-O2")
+	}
+	return append(cmd, src, "-o", exe)
+}
+
+// generator: Estado da tradução AST -> C.
+type generator struct {
+	opts   ir.Options
+	source string            // Fonte .sig para as diretivas #line (-g)
+	names  map[string]string // Nome Sigma -> nome C (renomeia palavras reservadas)
+
+	// Comando em tradução: a linha (para sigma_idx), o recuo e os temporários
+	// que precisam ser calculados antes dele (ver spill).
+	curLine int
+	indent  string
+	pre     strings.Builder
+	temps   int
+}
+
+// Generate: O programa C completo. As variáveis são locais do main, como na
+// moldura de pilha do backend Assembly, e os vetores são 'static' (zerados,
+// fora da pilha, como na .bss); as constantes viram macros. Com
+// source (-g), cada comando leva um #line apontando para a sua linha no .sig,
+// e o gdb mostra o fonte Sigma em vez do C.
+func Generate(statements []parser.Statement, opts ir.Options, source string) string {
+	g := &generator{
+		opts:   opts,
+		source: source,
+		names:  map[string]string{},
+	}
+
+	var body, defines strings.Builder
+	usesEOF := false
+	for _, stmt := range statements {
+		switch s := stmt.(type) {
+		case *parser.VarDeclNode:
+			body.WriteString(g.line(s.Line))
+			body.WriteString(g.varDecl(s))
+		case *parser.ConstDeclNode:
+			defines.WriteString(g.constDecl(s))
+		case *parser.AssignmentNode:
+			body.WriteString(g.line(s.Line))
+			usesEOF = usesEOF || callsEOF(&s.ExprNode) || callsEOF(s.DestIndex)
+			body.WriteString(g.assignment(s))
+		case *parser.PrintNode:
+			body.WriteString(g.line(s.Line))
+			usesEOF = usesEOF || printCallsEOF(s)
+			body.WriteString(g.print(s, "    "))
+		case *parser.InputNode:
+			body.WriteString(g.line(s.Line))
+			usesEOF = true
+			body.WriteString(g.input(s))
+		}
+	}
+	var out strings.Builder
+	out.WriteString("/* Gerado pelo CSigma (--backend=c) */\n")
+	out.WriteString("#include <inttypes.h>\n#include <signal.h>\n#include <stdint.h>\n#include <stdio.h>\n#include <stdlib.h>\n\n")
+	out.WriteString("#define SIGMA_LEN(v) ((int64_t)(sizeof (v) / sizeof (v)[0]))\n")
+	out.WriteString(runtime)
+	// As macros das constantes vêm depois do runtime para não trocarem os nomes
+	// dos seus parâmetros.
+	if defines.Len() > 0 {
+		out.WriteString("\n" + defines.String())
+	}
+	out.WriteString("\nint main(void)\n{\n")
+	if usesEOF {
+		out.WriteString("    int64_t sigma_eof = 0;\n")
+	}
+	out.WriteString(body.String())
+	out.WriteString("    return 0;\n}\n")
+	return out.String()
+}
+
+// line: A diretiva #line do comando (só com -g).
+func (g *generator) line(n int) string {
+	if g.source == "" || n == 0 {
+		return ""
+	}
+	return fmt.Sprintf("#line %d %s\n", n, cString(g.source))
+}
+
+// runtime: As mesmas rotinas de apoio do backend Assembly, em C.
+const runtime = `
+/* sigma_scan: fflush + scanf. Devolve 1 (ok), 0 (texto invalido, com o resto
+   da linha descartado) ou -1 (fim da entrada). */
+static int sigma_scan(const char *fmt, void *p)
+{
+    int r, c;
+
+    fflush(NULL);
+    r = scanf(fmt, p);
+    if (r == 1 || r == EOF)
+        return r == 1 ? 1 : -1;
+    while ((c = getchar()) != '\n')
+        if (c == EOF)
+            return -1;
+    return 0;
+}
+
+/* sigma_idx: Verifica o indice de v[i] (sem sinal: negativos tambem falham)
+   e o devolve, para ser usado no proprio acesso: v[sigma_idx(i, ...)]. */
+static int64_t sigma_idx(int64_t i, int64_t size, int64_t line, const char *name)
+{
+    if ((uint64_t)i >= (uint64_t)size) {
+        fprintf(stderr, "Erro de execucao (linha %" PRId64 "): indice %" PRId64 " fora dos limites de '%s' (0..%" PRId64 ")\n",
+                line, i, name, size - 1);
+        exit(1);
+    }
+    return i;
+}
+
+/* sigma_input_error: Entrada invalida com --input=abort. */
+static void sigma_input_error(const char *name)
+{
+    fprintf(stderr, "Erro de execucao: entrada invalida para a variavel '%s'\n", name);
+    exit(1);
+}
+
+/* sigma_div: Divisao inteira como o idiv: trunca em direcao a zero e derruba o
+   programa (SIGFPE) na divisao por zero ou no transbordo de INT64_MIN / -1. */
+static int64_t sigma_div(int64_t a, int64_t b)
+{
+    if (b == 0 || (a == INT64_MIN && b == -1))
+        raise(SIGFPE);
+    return a / b;
+}
+`
+
+// --- DECLARAÇÕES ---
+
+func (g *generator) varDecl(s *parser.VarDeclNode) string {
+	name := g.declare(s.Name)
+	switch {
+	case s.Size > 0 && s.ElemType == "float":
+		return fmt.Sprintf("    static double %s[%d];\n", name, s.Size)
+	case s.Size > 0:
+		return fmt.Sprintf("    static int64_t %s[%d];\n", name, s.Size)
+	case s.IsString:
+		return fmt.Sprintf("    const char *%s = %s;\n", name, cString(s.Value))
+	case strings.Contains(s.Value, "."):
+		return fmt.Sprintf("    double %s = %s;\n", name, s.Value)
+	}
+	return fmt.Sprintf("    int64_t %s = %s;\n", name, s.Value)
+}
+
+// constDecl: Constantes viram macros com o valor já calculado pelo Analisador
+// Semântico, para servirem também de tamanho de vetor ('var v[MAX]').
+func (g *generator) constDecl(s *parser.ConstDeclNode) string {
+	name := g.declare(s.Name)
+	value := "INT64_C(" + s.Valor + ")"
+	switch s.Tipo {
+	case "SIGMA_FLT":
+		value = s.Valor
+	case "SIGMA_STR":
+		value = cString(s.Valor)
+	}
+	if len(s.Ops) > 0 {
+		value += " /* " + s.ExprNode.String() + " */"
+	}
+	return fmt.Sprintf("#define %s %s\n", name, value)
+}
+
+// --- COMANDOS ---
+
+// assignment: Como na IR, a expressão é calculada antes do índice do destino.
+// O C não garante a ordem entre os dois lados do '=', então, se ambos
+// puderem falhar, o valor vai antes para um temporário.
+func (g *generator) assignment(s *parser.AssignmentNode) string {
+	g.start(s.Line, "    ")
+	value := g.expr(&s.ExprNode)
+	dest := g.name(s.Dest)
+	if s.DestIndex != nil {
+		o := parser.Operand{Value: s.Dest, IsVar: true, Index: s.DestIndex}
+		if g.operandEffects(o) && g.effects(&s.ExprNode) {
+			value = g.spill(value, s.ExprNode.Tipo)
+		}
+		dest = g.operand(o)
+	}
+	return g.flush() + fmt.Sprintf("    %s = %s;\n", dest, value)
+}
+
+// print: Uma chamada ao printf com o mesmo formato do backend Assembly.
+// Os argumentos do printf também não têm ordem garantida: antes de um que
+// possa falhar, os anteriores que também podem vão para temporários.
+func (g *generator) print(s *parser.PrintNode, indent string) string {
+	g.start(s.Line, indent)
+	var format strings.Builder
+	var args, tipos []string
+	pending := -1 // O último argumento que pode falhar, se ainda não calculado
+
+	if s.Parts == nil {
+		format.WriteString(strings.ReplaceAll(s.Value, "%", "%%"))
+	}
+	for _, part := range s.Parts {
+		if part.Expr == nil {
+			format.WriteString(strings.ReplaceAll(part.Text, "%", "%%"))
+			continue
+		}
+		switch part.Expr.Tipo {
+		case "SIGMA_FLT":
+			format.WriteString("%g")
+		case "SIGMA_STR":
+			format.WriteString("%s")
+		default:
+			format.WriteString("%\x00PRId64\x00")
+		}
+		if g.effects(part.Expr) {
+			if pending >= 0 {
+				args[pending] = g.spill(args[pending], tipos[pending])
+			}
+			pending = len(args)
+		}
+		arg := g.expr(part.Expr)
+		if part.Expr.Tipo == "SIGMA_INT" && len(part.Expr.Ops) == 0 && isLiteral(part.Expr.First) {
+			arg = "INT64_C(" + arg + ")" // O printf espera 64 bits
+		}
+		args = append(args, arg)
+		tipos = append(tipos, part.Expr.Tipo)
+	}
+	if !s.NoNewline {
+		format.WriteString("\n")
+	}
+
+	call := "printf(" + cString(format.String())
+	if len(args) > 0 {
+		call += ", " + strings.Join(args, ", ")
+	}
+	return g.flush() + indent + call + ");\n"
+}
+
+// input: O laço repete o prompt e a leitura até vir um número (ou o fim da
+// entrada, que liga eof() e zera a variável).
+func (g *generator) input(s *parser.InputNode) string {
+	var sb strings.Builder
+	const in = "        "
+
+	dest := g.name(s.VarName)
+	format := "\"%\" SCNd64"
+	zero := "0"
+	if s.Tipo == "SIGMA_FLT" {
+		format, zero = "\"%lf\"", "0.0"
+	}
+
+	target := s.VarName
+	if s.Index != nil {
+		target += "[" + s.Index.String() + "]"
+	}
+	sb.WriteString("    for (;;) { /* input " + target + " */\n")
+	if s.Prompt != nil {
+		sb.WriteString(g.print(s.Prompt, in))
+	}
+	if s.Index != nil {
+		// O índice é usado duas vezes (leitura e fim da entrada): se ele
+		// puder falhar, é calculado uma vez só, antes do scanf.
+		g.start(s.Line, in)
+		i := g.index(s.VarName, s.Index)
+		if g.operandEffects(parser.Operand{Value: s.VarName, IsVar: true, Index: s.Index}) {
+			i = g.spill(i, "SIGMA_INT")
+		}
+		sb.WriteString(g.flush())
+		dest += "[" + i + "]"
+	}
+	sb.WriteString(fmt.Sprintf("%sint r = sigma_scan(%s, &%s);\n", in, format, dest))
+	sb.WriteString(in + "if (r > 0)\n" + in + "    break;\n")
+	sb.WriteString(in + "if (r < 0) {\n")
+	sb.WriteString(fmt.Sprintf("%s    sigma_eof = 1;\n%s    %s = %s;\n%s    break;\n%s}\n", in, in, dest, zero, in, in))
+	if g.opts.AbortOnInvalidInput {
+		sb.WriteString(fmt.Sprintf("%ssigma_input_error(%s);\n", in, cString(s.VarName)))
+	} else {
+		sb.WriteString(in + "printf(\"Entrada invalida, tente novamente.\\n\");\n")
+	}
+	sb.WriteString("    }\n")
+	return sb.String()
+}
+
+// --- EXPRESSÕES ---
+
+// expr: A aritmética linear do Sigma em C. Os parênteses só aparecem quando a
+// precedência do C mudaria a ordem (esquerda para a direita): 'a + b * 2'
+// vira '(a + b) * 2'. A divisão inteira passa por sigma_div.
+//
+// sigma_div e sigma_idx podem encerrar o programa, e o C não define a ordem
+// entre os dois lados de um operador. Para que o primeiro erro seja o mesmo
+// dos backends x86_64, que calculam da esquerda para a direita, o que já foi
+// acumulado vai para um temporário antes de um operando que possa falhar:
+// '10 / z * v[a]' calcula sigma_div(10, z) antes de verificar a.
+func (g *generator) expr(e *parser.ExprNode) string {
+	acc := g.operand(e.First)
+	accEffects := g.operandEffects(e.First)
+	// Dois literais inteiros seguidos seriam calculados no int do C (32 bits);
+	// com INT64_C a cadeia inteira fica em 64 bits.
+	if e.Tipo == "SIGMA_INT" && len(e.Ops) > 0 && isLiteral(e.First) && isLiteral(e.Ops[0].Operand) {
+		acc = "INT64_C(" + acc + ")"
+	}
+	additive := false
+	for _, op := range e.Ops {
+		if accEffects && g.operandEffects(op.Operand) {
+			acc, accEffects, additive = g.spill(acc, e.Tipo), false, false
+		}
+		accEffects = accEffects || g.operandEffects(op.Operand)
+		rhs := g.operand(op.Operand)
+		switch {
+		case op.Operator == "/" && e.Tipo == "SIGMA_INT":
+			acc = "sigma_div(" + acc + ", " + rhs + ")"
+			accEffects = true
+			additive = false
+		case op.Operator == "+" || op.Operator == "-":
+			acc = acc + " " + op.Operator + " " + rhs
+			additive = true
+		default:
+			if additive {
+				acc = "(" + acc + ")"
+			}
+			acc = acc + " " + op.Operator + " " + rhs
+			additive = false
+		}
+	}
+	return acc
+}
+
+func (g *generator) operand(o parser.Operand) string {
+	switch {
+	case o.IsString:
+		return cString(o.Value)
+	case o.IsCall && o.Value == "len":
+		return "SIGMA_LEN(" + g.name(o.Args[0].Value) + ")"
+	case o.IsCall:
+		return "sigma_eof"
+	case o.Index != nil:
+		return g.name(o.Value) + "[" + g.index(o.Value, o.Index) + "]"
+	case o.IsVar:
+		return g.name(o.Value)
+	}
+	return o.Value
+}
+
+func isLiteral(o parser.Operand) bool {
+	return !o.IsVar && !o.IsCall && !o.IsString
+}
+
+// index: O índice de v[i] já verificado: sigma_idx(i, tamanho, linha, "v").
+// Só os literais, que o Analisador Semântico confere, dispensam a verificação.
+func (g *generator) index(name string, idx *parser.ExprNode) string {
+	i := g.expr(idx)
+	if !g.checked(idx) {
+		return i
+	}
+	return fmt.Sprintf("sigma_idx(%s, SIGMA_LEN(%s), %d, %s)", i, g.name(name), g.curLine, cString(name))
+}
+
+func (g *generator) checked(idx *parser.ExprNode) bool {
+	return len(idx.Ops) > 0 || !isLiteral(idx.First)
+}
+
+// effects: A expressão pode encerrar o programa (divisão inteira ou acesso
+// verificado a um vetor)?
+func (g *generator) effects(e *parser.ExprNode) bool {
+	if g.operandEffects(e.First) {
+		return true
+	}
+	for _, op := range e.Ops {
+		if op.Operator == "/" && e.Tipo == "SIGMA_INT" || g.operandEffects(op.Operand) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *generator) operandEffects(o parser.Operand) bool {
+	return o.Index != nil && (g.checked(o.Index) || g.effects(o.Index))
+}
+
+// --- TEMPORÁRIOS ---
+
+// start: Começa um comando: a linha das verificações e o recuo dos temporários.
+func (g *generator) start(line int, indent string) {
+	g.curLine, g.indent = line, indent
+	g.pre.Reset()
+}
+
+// spill: Calcula value num temporário, antes do comando em tradução, e
+// devolve o nome dele.
+func (g *generator) spill(value, tipo string) string {
+	ctype := "int64_t"
+	switch tipo {
+	case "SIGMA_FLT":
+		ctype = "double"
+	case "SIGMA_STR":
+		ctype = "const char *"
+	}
+	name := fmt.Sprintf("sigma_t%d", g.temps)
+	g.temps++
+	g.pre.WriteString(fmt.Sprintf("%s%s %s = %s;\n", g.indent, ctype, name, value))
+	return name
+}
+
+// flush: Os temporários do comando, que vêm antes dele.
+func (g *generator) flush() string {
+	s := g.pre.String()
+	g.pre.Reset()
+	return s
+}
+
+// callsEOF: A expressão (ou algum índice dentro dela) usa eof()?
+func callsEOF(e *parser.ExprNode) bool {
+	if e == nil {
+		return false
+	}
+	operands := []parser.Operand{e.First}
+	for _, op := range e.Ops {
+		operands = append(operands, op.Operand)
+	}
+	for _, o := range operands {
+		if o.IsCall && o.Value == "eof" || callsEOF(o.Index) {
+			return true
+		}
+	}
+	return false
+}
+
+func printCallsEOF(s *parser.PrintNode) bool {
+	for _, part := range s.Parts {
+		if callsEOF(part.Expr) {
+			return true
+		}
+	}
+	return false
+}
+
+// --- NOMES E TEXTOS ---
+
+// reserved: Nomes que um identificador Sigma não pode ter no C gerado
+// (palavras-chave do C99 e os nomes usados pelo próprio arquivo).
+var reserved = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`auto break case char const continue default do double else enum extern
+		float for goto if inline int long register restrict return short signed sizeof static struct
+		switch typedef union unsigned void volatile while _Bool _Complex _Imaginary
+		main printf scanf fprintf fflush getchar exit raise stderr stdin stdout EOF NULL
+		r sigma_eof sigma_scan sigma_idx sigma_input_error sigma_div SIGMA_LEN`) {
+		reserved[w] = true
+	}
+}
+
+// declare: Registra o nome C de uma variável ou constante.
+func (g *generator) declare(name string) string {
+	c := name
+	if reserved[name] || strings.HasPrefix(name, "sigma_t") || strings.HasPrefix(name, "int") || strings.HasPrefix(name, "uint") ||
+		strings.HasPrefix(name, "PRI") || strings.HasPrefix(name, "SCN") || strings.HasPrefix(name, "INT") {
+		c = "sg_" + name
+	}
+	g.names[name] = c
+	return c
+}
+
+func (g *generator) name(sigma string) string {
+	if c, ok := g.names[sigma]; ok {
+		return c
+	}
+	return sigma
+}
+
+// cString: Um literal de texto do C. O marcador "\x00PRId64\x00" (deixado por
+// print) fecha e reabre as aspas em volta da macro: "x = %" PRId64 "\n".
+// '?' repetido é escapado para não formar trígrafos (??=, ??/).
+func cString(text string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for i := 0; i < len(text); i++ {
+		ch := text[i]
+		switch {
+		case ch == 0:
+			end := strings.IndexByte(text[i+1:], 0)
+			sb.WriteString("\" " + text[i+1:i+1+end] + " \"")
+			i += end + 1
+		case ch == '"' || ch == '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(ch)
+		case ch == '\n':
+			sb.WriteString("\\n")
+		case ch == '?' && i > 0 && text[i-1] == '?':
+			sb.WriteString("\\?")
+		case ch < 32 || ch == 127:
+			sb.WriteString(fmt.Sprintf("\\%03o", ch))
+		default:
+			sb.WriteByte(ch)
+		}
+	}
+	sb.WriteByte('"')
+	return strings.ReplaceAll(sb.String(), " \"\"", "")
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bufio"
+	"csigma/debugger"
+	"csigma/interp"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runDebug: 'csigma debug arquivo.sig' executa o programa passo a passo no
+// interpretador (pacote debugger). Os comandos do depurador vêm do terminal
+// ou, com -commands, de um arquivo (roteiro); nesse caso a entrada padrão fica
+// só para o 'input' do programa.
+func runDebug(args []string) int {
+	fs := flag.NewFlagSet("debug", flag.ContinueOnError)
+	commands := fs.String("commands", "", "arquivo com os comandos do depurador, um por linha (sessao nao interativa)")
+	inputPolicy := fs.String("input", "retry", "politica para entrada invalida: retry (pede de novo) ou abort (erro de execucao)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Uso: csigma debug [-commands arquivo] [-input retry|abort] arquivo.sig")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 || (*inputPolicy != "retry" && *inputPolicy != "abort") {
+		fs.Usage()
+		return 2
+	}
+
+	src, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "debug: %v\n", err)
+		return 1
+	}
+	stdin := bufio.NewReader(os.Stdin)
+	opts := interp.Options{AbortOnInvalidInput: *inputPolicy == "abort"}
+	d, err := debugger.New(string(src), stdin, os.Stdout, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", fs.Arg(0), err)
+		return 1
+	}
+
+	if *commands != "" {
+		f, err := os.Open(*commands)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "debug: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		d.Run(bufio.NewReader(f), false, false)
+		return 0
+	}
+	fi, err := os.Stdin.Stat()
+	interactive := err == nil && fi.Mode()&os.ModeCharDevice != 0
+	d.Run(stdin, interactive, true)
+	return 0
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"csigma/format"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// runFmt: 'csigma fmt [-w] [-d] arquivos...' formata os programas (pacote
+// format). Sem arquivos, lê a entrada padrão. Sem -w nem -d, mostra o fonte
+// formatado. Devolve o código de saída: 1 se algum arquivo tiver erro.
+func runFmt(args []string) int {
+	fs := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	write := fs.Bool("w", false, "grava o resultado no proprio arquivo em vez de mostrar")
+	diff := fs.Bool("d", false, "mostra as diferencas (diff -u) em vez do fonte formatado")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Uso: csigma fmt [-w] [-d] [arquivo.sig ...]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if fs.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(os.Stderr, "fmt: -w precisa de arquivos")
+			return 2
+		}
+		src, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "fmt: %v\n", err)
+			return 1
+		}
+		if err := fmtFile("<stdin>", src, false, *diff); err != nil {
+			fmt.Fprintf(os.Stderr, "fmt: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	status := 0
+	for _, path := range fs.Args() {
+		src, err := os.ReadFile(path)
+		if err == nil {
+			err = fmtFile(path, src, *write, *diff)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "fmt: %s: %v\n", path, err)
+			status = 1
+		}
+	}
+	return status
+}
+
+// fmtFile: Formata um fonte e mostra, compara ou grava o resultado.
+func fmtFile(path string, src []byte, write, diff bool) error {
+	out, err := format.Source(string(src))
+	if err != nil {
+		return err
+	}
+	if diff {
+		fmt.Print(format.Diff(path, string(src), out))
+	}
+	if write {
+		if out == string(src) {
+			return nil
+		}
+		return os.WriteFile(path, []byte(out), 0644)
+	}
+	if !diff {
+		fmt.Print(out)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"csigma/lint"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// runLint: 'csigma lint [-config arq] arquivos...' mostra os diagnósticos do
+// pacote lint, um por linha ('arq.sig:linha:coluna: gravidade: mensagem
+// [regra]'). Sem -config, usa o .sigmalint.json mais próximo de cada arquivo.
+// Devolve 1 se houver erros (de sintaxe, semânticos ou de regras configuradas
+// como erro).
+func runLint(args []string) int {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	configPath := fs.String("config", "", "arquivo de configuracao das regras (padrao: "+lint.ConfigFile+" na pasta do fonte ou acima)")
+	list := fs.Bool("regras", false, "lista as regras e as gravidades padrao")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Uso: csigma lint [-config arquivo.json] [-regras] arquivo.sig ...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if *list {
+		for _, r := range lint.Rules {
+			fmt.Printf("%-26s %-6s %s\n", r.ID, r.Severity, r.Description)
+		}
+		return 0
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	status := 0
+	for _, path := range fs.Args() {
+		cfgPath := *configPath
+		if cfgPath == "" {
+			cfgPath = lint.FindConfig(filepath.Dir(path))
+		}
+		var cfg lint.Config
+		if cfgPath != "" {
+			var err error
+			if cfg, err = lint.LoadConfig(cfgPath); err != nil {
+				fmt.Fprintf(os.Stderr, "lint: %v\n", err)
+				return 2
+			}
+		}
+
+		src, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "lint: %v\n", err)
+			status = 1
+			continue
+		}
+		diags, err := lint.Check(string(src), cfg)
+		if err != nil {
+			fmt.Printf("%s: %s: %v\n", path, lint.Error, err)
+			status = 1
+			continue
+		}
+		for _, d := range diags {
+			fmt.Printf("%s:%s\n", path, d)
+			if d.Severity == lint.Error {
+				status = 1
+			}
+		}
+	}
+	return status
+}
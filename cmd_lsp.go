@@ -0,0 +1,22 @@
+package main
+
+import (
+	"csigma/lsp"
+	"fmt"
+	"os"
+)
+
+// runLsp: 'csigma lsp' atende um editor pelo Language Server Protocol, na
+// entrada e saída padrão (pacote lsp). Devolve 1 se a sessão terminar sem o
+// 'shutdown' pedido pelo protocolo.
+func runLsp(args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintln(os.Stderr, "Uso: csigma lsp (sem argumentos; o editor conversa pela entrada e saida padrao)")
+		return 2
+	}
+	if err := lsp.Serve(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "lsp: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"csigma/repl"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// runRepl: 'csigma repl' abre uma sessão interativa (pacote repl): cada
+// comando é analisado e executado na hora pelo interpretador. O histórico
+// fica em ~/.csigma_history.
+func runRepl(args []string) int {
+	fs := flag.NewFlagSet("repl", flag.ContinueOnError)
+	inputPolicy := fs.String("input", "retry", "politica para entrada invalida: retry (pede de novo) ou abort (erro de execucao)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Uso: csigma repl [-input retry|abort]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 || (*inputPolicy != "retry" && *inputPolicy != "abort") {
+		fs.Usage()
+		return 2
+	}
+
+	opts := repl.Options{AbortOnInvalidInput: *inputPolicy == "abort"}
+	if home, err := os.UserHomeDir(); err == nil {
+		opts.History = filepath.Join(home, ".csigma_history")
+	}
+	if err := repl.Run(os.Stdin, os.Stdout, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "repl: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,42 @@
+package codegen
+
+import "csigma/ir"
+
+// Backend: Um alvo do gerador de código. Todos partem das mesmas instruções
+// x86_64 (seleção, alocação de registradores e peephole são comuns) e só
+// mudam a sintaxe do arquivo e o montador chamado.
+type Backend interface {
+	// Name: O nome usado em --backend.
+	Name() string
+	// Generate: O arquivo de montagem completo.
+	Generate(prog *ir.Program, opts Options) string
+	// Extension: A extensão do arquivo gerado (".asm", ".s").
+	Extension() string
+	// AssembleCommand: A linha de comando que monta src no objeto obj.
+	AssembleCommand(src, obj string) []string
+}
+
+// Assembler: Backend que monta o arquivo objeto sozinho, sem chamar um
+// montador externo (AssembleCommand fica vazio).
+type Assembler interface {
+	Assemble(prog *ir.Program, opts Options) ([]byte, error)
+}
+
+// Linker: Assembler que também grava o executável sozinho, sem linker
+// externo, quando o programa não usa a LibC.
+type Linker interface {
+	Link(prog *ir.Program, opts Options, pie bool) ([]byte, error)
+}
+
+// libcFuncs: As funções da LibC que o código gerado chama. Ficam fora do
+// objeto e são chamadas pela PLT, como pede um executável PIE.
+var libcFuncs = map[string]bool{
+	"printf": true, "dprintf": true, "scanf": true, "fflush": true, "getchar": true, "exit": true,
+}
+
+// Backends: Os alvos disponíveis, pelo nome.
+var Backends = map[string]Backend{
+	"nasm": NASM{},
+	"gas":  GAS{},
+	"elf":  Object{},
+}
@@ -1,103 +1,687 @@
 package codegen
 
 import (
-	"csigma/parser"
+	"csigma/dwarf"
+	"csigma/ir"
+	"csigma/regalloc"
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
 )
 
-// GenerateNASM: O tradutor final que converte a AST em código de montagem (Assembly).
-func GenerateNASM(statements []parser.Statement) string {
-	var dataSection strings.Builder
-	var textSection strings.Builder
-	msgCount := 0
+// Registradores de argumento da convenção System V AMD64, na ordem de uso.
+var (
+	intArgRegs = []string{"rdi", "rsi", "rdx", "rcx", "r8", "r9"}
+	fltArgRegs = []string{"xmm0", "xmm1", "xmm2", "xmm3", "xmm4", "xmm5", "xmm6", "xmm7"}
+)
+
+// Options: Ajustes de geração escolhidos na linha de comando.
+type Options struct {
+	// RegAlloc mantém temporários e variáveis em registradores (alocação por
+	// varredura linear). Sem ela, cada temporário e cada variável mora em
+	// uma posição fixa da pilha.
+	RegAlloc bool
+	// Peephole limpa o código gerado (cópias redundantes, saltos para a linha
+	// seguinte...). Ligado por padrão; --no-peephole mostra o código bruto.
+	Peephole bool
+	// NoLibc troca as funções da LibC pelo runtime próprio sobre chamadas de
+	// sistema (nolibc.go), com o ponto de entrada _start.
+	NoLibc bool
+	// Debug acrescenta a informação de depuração DWARF (debug.go): linhas do
+	// fonte Source e variáveis, para o gdb.
+	Debug  bool
+	Source string
+
+	listing bool // Marcas de linha só para a listagem (listing.go)
+}
+
+// Instr: Uma linha da seção de código. Guardar a instrução separada em
+// mnemônico e operandos (em vez de texto pronto) permite que o otimizador
+// peephole reconheça padrões e que cada backend a escreva na sua sintaxe.
+// Os operandos seguem a sintaxe Intel (destino primeiro), a mesma do NASM.
+type Instr struct {
+	Label   string   // Se preenchido, a linha é um rótulo (ex: "main", ".entry")
+	Op      string   // Mnemônico (mov, add, call...); vazio em rótulos e comentários
+	Args    []string // Operandos na sintaxe Intel do NASM
+	Comment string
+	Line    int // Marca de linha do fonte (com opts.Debug): não gera código
+}
+
+// Data: Uma constante da seção de dados: um texto terminado em zero (formatos
+// de E/S, textos do programa) ou um literal decimal de 64 bits. Os vetores e
+// os buffers do runtime sem LibC também são reservados aqui (Space), e vão
+// para a .bss.
+type Data struct {
+	Label   string
+	Text    string // O zero final é acrescentado por quem escreve a seção
+	Float   string // Literal decimal (ex: "2.5"); vazio nos textos
+	Space   int    // Se > 0, reserva Space bytes zerados na seção .bss
+	Comment string
+}
+
+// listing: O programa traduzido para x86_64, antes de escolher a sintaxe:
+// constantes e instruções (o 'main' seguido do runtime).
+type listing struct {
+	data  []*Data
+	text  []*Instr
+	debug []dwarf.Section   // Só com opts.Debug
+	spans map[*Instr][2]int // Início e fim de cada instrução no .text (montador interno)
+}
+
+// String: A linha no formato do NASM, com o comentário alinhado na coluna 40.
+func (in *Instr) String() string {
+	switch {
+	case in.Label != "":
+		return in.Label + ":"
+	case in.Op == "" && in.Comment == "":
+		return ""
+	case in.Op == "":
+		return "    ; " + in.Comment
+	}
+	code := "    " + in.Op
+	if len(in.Args) > 0 {
+		code += " " + strings.Join(in.Args, ", ")
+	}
+	if in.Comment == "" {
+		return code
+	}
+	if len(code) < 40 {
+		code += strings.Repeat(" ", 40-len(code))
+	} else {
+		code += " "
+	}
+	return code + "; " + in.Comment
+}
+
+// generator: Agrupa as seções do arquivo e o estado da tradução,
+// para que as funções auxiliares possam emitir dados e código ao mesmo tempo.
+type generator struct {
+	opts Options
+	data []*Data
+	text []*Instr // Código da função sendo traduzida
+
+	floats  map[uint64]string // Bits do literal decimal -> rótulo no pool (flt_N)
+	runtime map[string]bool   // Rotinas do runtime Sigma chamadas pelo programa
+
+	alloc    *regalloc.Allocation // Registrador de cada valor da função atual
+	vars     map[string]int       // Variáveis na pilha: o elemento 0 fica em [rbp - N]
+	slots    map[*ir.Temp]int     // Posição na pilha dos temporários sem registrador
+	tempBase int                  // Os temporários começam abaixo de [rbp - tempBase]
+	line     int                  // Linha do fonte da última marca (opts.Debug)
+}
+
+// generate: A parte comum a todos os backends: converte a IR em instruções
+// x86_64. Cada valor da IR mora em um registrador callee-saved (com
+// opts.RegAlloc) ou em uma posição fixa da moldura de pilha do 'main'
+// (variáveis simples e temporários). A seção de dados guarda apenas
+// constantes, e os vetores ficam na .bss: na pilha, um vetor grande passaria
+// do limite dela (8 MiB, em geral) e o programa cairia sem mensagem nenhuma.
+func generate(prog *ir.Program, opts Options) *listing {
+	g := &generator{opts: opts, floats: map[uint64]string{}, runtime: map[string]bool{}}
+	for _, s := range prog.Strings {
+		g.data = append(g.data, &Data{Label: s.Label, Text: s.Text})
+	}
+	for _, v := range prog.Globals {
+		if v.Size > 0 {
+			g.data = append(g.data, &Data{Label: arrayLabel(v.Name), Space: 8 * v.Size,
+				Comment: fmt.Sprintf("Vetor %s[%d] (%s)", v.Name, v.Size, v.Type)})
+		}
+	}
+
+	var text []*Instr
+	var debug []dwarf.Section
+	for _, fn := range prog.Funcs {
+		g.text = nil
+		g.genFunction(prog, fn)
+		if opts.Peephole {
+			g.text = Peephole(g.text, g.debugSlots(prog))
+		}
+		if opts.Debug {
+			debug = g.debugInfo(prog, fn.Name)
+		}
+		text = append(text, g.text...)
+	}
+	// O runtime é escrito à mão: não passa pelo peephole.
+	g.text = nil
+	g.genRuntime()
+	if opts.NoLibc {
+		g.genNoLibc()
+	}
+	return &listing{data: g.data, text: append(text, g.text...), debug: debug}
+}
+
+// CountInstructions: Quantas instruções de máquina o 'main' tem no Assembly
+// gerado por qualquer backend (sem rótulos, comentários e diretivas). Usado para medir o efeito
+// das otimizações no relatório.
+func CountInstructions(asm string) int {
+	n, inMain := 0, false
+	for _, line := range strings.Split(asm, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case line == "main:":
+			inMain = true
+		case inMain && line != "" && !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "."):
+			return n // Começou outra rotina (o runtime)
+		case inMain && trimmed != "" && !strings.HasPrefix(trimmed, ";") && !strings.HasPrefix(trimmed, "#") && !strings.HasSuffix(trimmed, ":"):
+			n++
+		}
+	}
+	return n
+}
+
+// --- EMISSÃO ---
+
+// emit: Acrescenta uma instrução ao código. O comentário, se houver, é
+// preenchido por quem chamou: g.emit("push", "rbp").Comment = "...".
+func (g *generator) emit(op string, args ...string) *Instr {
+	in := &Instr{Op: op, Args: args}
+	g.text = append(g.text, in)
+	return in
+}
 
-	// --- SEÇÃO DE DADOS (.data) ---
-	// Reservada para constantes e variáveis globais.
-	dataSection.WriteString("section .data\n")
-	// db = Define Byte. Usado para strings e formatos de E/S.
-	dataSection.WriteString(fmt.Sprintf("%-40s; Formato para leitura (long int)\n", "    fmt_in db '%ld', 0"))
-	dataSection.WriteString(fmt.Sprintf("%-40s; Formato para escrita (long int + \n)\n", "    fmt_out_num db '%ld', 10, 0"))
+func (g *generator) label(name string) {
+	g.text = append(g.text, &Instr{Label: name})
+}
+
+// comment: Linha em branco seguida de um comentário solto (cabeçalho de um comando).
+func (g *generator) comment(text string) {
+	g.text = append(g.text, &Instr{}, &Instr{Comment: text})
+}
+
+// genFunction: Prólogo, blocos básicos na ordem da IR e epílogo (no 'ret').
+func (g *generator) genFunction(prog *ir.Program, fn *ir.Function) {
+	g.alloc = &regalloc.Allocation{Reg: map[*ir.Temp]string{}, VarReg: map[string]string{}, Shared: map[*ir.Temp]string{}}
+	if g.opts.RegAlloc {
+		g.alloc = regalloc.Allocate(prog, fn, regalloc.Registers)
+	}
+	g.slots = map[*ir.Temp]int{}
+	for _, t := range fn.Temps {
+		_, shared := g.alloc.Shared[t]
+		if _, inReg := g.alloc.Reg[t]; !shared && !inReg {
+			g.slots[t] = len(g.slots)
+		}
+	}
 
-	// --- SEÇÃO DE CÓDIGO (.text) ---
-	textSection.WriteString("\nsection .text\n")
-	textSection.WriteString("extern printf, scanf                    ; Declara funções da LibC\n")
-	textSection.WriteString("global main                             ; Ponto de entrada para o Linker\n\nmain:\n")
+	// Moldura da pilha (stack frame), de cima para baixo a partir de RBP:
+	// registradores preservados, variáveis simples e temporários.
+	saved := 8 * len(g.alloc.Used)
+	g.vars = map[string]int{}
+	off := saved
+	for _, v := range prog.Globals {
+		if _, inReg := g.alloc.VarReg[v.Name]; inReg || v.Size > 0 {
+			continue
+		}
+		off += 8
+		g.vars[v.Name] = off
+	}
+	g.tempBase = off
+	// Arredondada para manter a pilha alinhada em 16 bytes nas chamadas.
+	frame := (off+8*len(g.slots)+15)&^15 - saved
 
+	g.text = append(g.text, &Instr{})
+	g.label(fn.Name)
+	g.line = 0
+	g.mark(firstLine(prog, fn))
 	// Prólogo da Função: Prepara a base da pilha (Stack Frame)
-	textSection.WriteString("    push rbp                            ; Salva o ponteiro da base da pilha anterior\n")
-	textSection.WriteString("    mov rbp, rsp                        ; Define a nova base da pilha\n")
-	textSection.WriteString("    sub rsp, 32                         ; Alinha a pilha (16-byte alignment)\n\n")
-
-	// --- PROCESSAMENTO DA AST ---
-	for _, stmt := range statements {
-		switch s := stmt.(type) {
-
-		case *parser.VarDeclNode:
-			// dq = Define Quadword (64 bits). Reserva espaço para inteiros Sigma.
-			line := fmt.Sprintf("    %-20s dq %s", s.Name, s.Value)
-			dataSection.WriteString(fmt.Sprintf("%-40s; Reserva memoria para %s\n", line, s.Name))
-
-		case *parser.AssignmentNode:
-			textSection.WriteString(fmt.Sprintf("\n    ; --- Calculo Aritmetico: %s ---\n", s.Dest))
-			// RAX é o acumulador principal para operações matemáticas.
-			textSection.WriteString(fmt.Sprintf("    mov rax, [%s]          ; Carrega o primeiro valor em RAX\n", s.First))
-
-			for _, op := range s.Ops {
-				val := op.Value
-				if op.IsVar {
-					val = "[" + op.Value + "]" // Acessa o valor no endereço da memória
-				}
-
-				switch op.Operator {
-				case "+":
-					textSection.WriteString(fmt.Sprintf("    add rax, %-25s ; Soma ao acumulador\n", val))
-				case "-":
-					textSection.WriteString(fmt.Sprintf("    sub rax, %-25s ; Subtrai do acumulador\n", val))
-				case "*":
-					// imul: Multiplicação com sinal de 64 bits.
-					textSection.WriteString(fmt.Sprintf("    imul rax, %-24s ; Multiplicacao\n", val))
-				case "/":
-					// idiv exige que o dividendo esteja em RDX:RAX.
-					textSection.WriteString(fmt.Sprintf("    mov rbx, %-25s ; Carrega o divisor em RBX\n", val))
-					textSection.WriteString("    xor rdx, rdx               ; Zera RDX para evitar 'overflow' na divisao\n")
-					textSection.WriteString("    idiv rbx                   ; Divide RAX por RBX (Resultado em RAX)\n")
-				}
+	g.emit("push", "rbp").Comment = "Salva o ponteiro da base da pilha anterior"
+	g.emit("mov", "rbp", "rsp").Comment = "Define a nova base da pilha"
+	for _, r := range g.alloc.Used {
+		g.emit("push", r).Comment = "Callee-saved: preservado para quem chamou o main"
+	}
+	if frame > 0 {
+		g.emit("sub", "rsp", strconv.Itoa(frame)).Comment = "Espaco das variaveis e temporarios"
+	}
+	g.initVars(prog)
+
+	for _, b := range fn.Blocks {
+		g.label("." + b.Label)
+		for _, in := range b.Instrs {
+			if in.Comment != "" {
+				g.comment("--- " + in.Comment + " ---")
 			}
-			// Move o resultado final do acumulador RAX para o endereço de destino na memória.
-			textSection.WriteString(fmt.Sprintf("    mov [%s], rax          ; Armazena resultado final\n", s.Dest))
-
-		case *parser.PrintNode:
-			if s.IsString {
-				msgName := fmt.Sprintf("msg_%d", msgCount)
-				// 10 = Newline (\n), 0 = Null Terminator (Padrão C)
-				dataSection.WriteString(fmt.Sprintf("    %-20s db '%s', 10, 0\n", msgName, s.Value))
-
-				// lea: Load Effective Address. Passa o endereço da string para RDI.
-				textSection.WriteString(fmt.Sprintf("    lea rdi, [%s]           ; RDI = Primeiro argumento (string)\n", msgName))
-				textSection.WriteString("    xor eax, eax                        ; AL=0 indica que não há vetores SSE\n")
-				textSection.WriteString("    call printf\n")
-				msgCount++
-			} else {
-				textSection.WriteString("    lea rdi, [fmt_out_num]              ; RDI = Formato de saida\n")
-				textSection.WriteString(fmt.Sprintf("    mov rsi, [%s]           ; RSI = Segundo argumento (valor)\n", s.Value))
-				textSection.WriteString("    xor eax, eax\n")
-				textSection.WriteString("    call printf\n")
+			g.mark(in.Line)
+			g.genInstr(in)
+		}
+	}
+}
+
+// initVars: Valores iniciais das variáveis, gravados no prólogo (a pilha não
+// vem zerada como a seção .data). Os vetores já começam zerados na .bss.
+func (g *generator) initVars(prog *ir.Program) {
+	liveIn := map[string]bool{}
+	for _, v := range g.alloc.LiveIn {
+		liveIn[v] = true
+	}
+
+	for _, v := range prog.Globals {
+		dst := g.varLoc(v.Name)
+		if v.Size > 0 || isReg(dst) && !liveIn[v.Name] {
+			continue // Promovida e gravada antes de ser lida: o valor inicial não importa
+		}
+		g.mark(v.Line)
+		switch v.Type {
+		case ir.Ptr:
+			g.movValue(dst, ir.SymAddr{Name: v.Init})
+		case ir.F64:
+			// Os bits do double, como um inteiro de 64 bits.
+			f, _ := strconv.ParseFloat(v.Init, 64)
+			g.mov(dst, strconv.FormatInt(int64(math.Float64bits(f)), 10))
+		default:
+			g.mov(dst, v.Init)
+		}
+		g.text[len(g.text)-1].Comment = fmt.Sprintf("%s = %s", v.Name, v.Init)
+	}
+}
+
+// genInstr: Seleção de instruções para cada operação da IR.
+func (g *generator) genInstr(in *ir.Instr) {
+	switch in.Op {
+	case ir.OpCopy:
+		g.movValue(g.loc(in.Dst), in.Args[0])
+
+	case ir.OpLoad:
+		if _, shared := g.alloc.Shared[in.Dst]; shared {
+			return // O temporário é a própria variável: nada a copiar
+		}
+		g.mov(g.loc(in.Dst), g.memOperand(in.Mem))
+
+	case ir.OpStore:
+		if t, ok := in.Args[0].(*ir.Temp); ok && in.Mem.Index == nil && g.alloc.Shared[t] == in.Mem.Sym {
+			return // O resultado já foi calculado direto na variável
+		}
+		// Decimais são copiados como 64 bits quaisquer: não precisam passar pelo XMM.
+		g.movValue(g.memOperand(in.Mem), in.Args[0])
+
+	case ir.OpAddr:
+		// lea: Load Effective Address. Calcula o endereço sem acessar a memória.
+		dst := g.loc(in.Dst)
+		reg := "rax"
+		if isReg(dst) {
+			reg = dst
+		}
+		g.emit("lea", reg, g.memOperand(in.Mem)).Comment = "Endereco de " + in.Mem.String()
+		g.mov(dst, reg)
+
+	case ir.OpAdd, ir.OpSub, ir.OpMul, ir.OpDiv, ir.OpShl:
+		if in.Dst.Ty == ir.F64 {
+			g.genFloatArith(in)
+		} else {
+			g.genIntArith(in)
+		}
+
+	case ir.OpCall:
+		g.genCall(in)
+
+	case ir.OpBranch:
+		a, b := g.loc(in.Args[0]), g.intOperand(in.Args[1])
+		if !isReg(a) && (!isReg(b) || isImm(a)) {
+			g.mov("rax", a)
+			a = "rax"
+		}
+		g.emit("cmp", a, b).Comment = in.String()
+		g.emit(jumpOps[in.Cond], "."+in.Targets[0].Label)
+		// Se o destino for o bloco seguinte, o peephole remove este jmp.
+		g.emit("jmp", "."+in.Targets[1].Label)
+
+	case ir.OpJump:
+		g.emit("jmp", "."+in.Targets[0].Label)
+
+	case ir.OpRet:
+		// Epílogo da Função: Limpa a pilha e retorna ao SO.
+		g.movValue("rax", in.Args[0])
+		if len(g.alloc.Used) == 0 {
+			g.emit("mov", "rsp", "rbp").Comment = "Descarta variaveis e temporarios"
+		} else {
+			g.emit("lea", "rsp", fmt.Sprintf("[rbp - %d]", 8*len(g.alloc.Used))).Comment = "Descarta variaveis e temporarios"
+			for i := len(g.alloc.Used) - 1; i >= 0; i-- {
+				g.emit("pop", g.alloc.Used[i])
 			}
+		}
+		g.emit("pop", "rbp").Comment = "Restaura o RBP original"
+		g.emit("ret")
 
-		case *parser.InputNode:
-			textSection.WriteString("    lea rdi, [fmt_in]                       ; RDI = Formato de entrada\n")
-			textSection.WriteString(fmt.Sprintf("    lea rsi, [%s]               ; RSI = Endereco onde salvar\n", s.VarName))
-			textSection.WriteString("    xor eax, eax\n")
-			textSection.WriteString("    call scanf\n")
+	case ir.OpUnreachable:
+		// A chamada anterior encerra o programa: nada a emitir.
+	}
+}
+
+// jumpOps: Salto condicional de cada condição da IR. 'jb' compara sem sinal.
+var jumpOps = map[ir.Cond]string{
+	ir.CondEq: "je", ir.CondNe: "jne", ir.CondLt: "jl", ir.CondGt: "jg", ir.CondUlt: "jb",
+}
+
+var intOps = map[ir.Op]string{ir.OpAdd: "add", ir.OpSub: "sub", ir.OpMul: "imul", ir.OpShl: "shl"}
+
+// genIntArith: Formato de dois endereços do x86 (add destino, fonte). Se o
+// destino está em um registrador, a conta é feita nele; senão, em RAX.
+func (g *generator) genIntArith(in *ir.Instr) {
+	dst, a, b := g.loc(in.Dst), in.Args[0], in.Args[1]
+
+	if in.Op == ir.OpDiv {
+		// idiv exige que o dividendo esteja em RDX:RAX.
+		g.movValue("rax", a)
+		g.movValue("rcx", b)
+		g.emit("cqo").Comment = "Estende o sinal de RAX para RDX"
+		g.emit("idiv", "rcx").Comment = "Divide RDX:RAX por RCX (Resultado em RAX)"
+		g.mov(dst, "rax")
+		return
+	}
+
+	op := intOps[in.Op]
+	commutative := in.Op == ir.OpAdd || in.Op == ir.OpMul
+	switch {
+	case isReg(dst) && g.loc(a) == dst:
+		g.emit(op, dst, g.intOperand(b))
+	case isReg(dst) && commutative && g.loc(b) == dst:
+		g.emit(op, dst, g.intOperand(a))
+	case isReg(dst) && g.loc(b) != dst:
+		g.movValue(dst, a)
+		g.emit(op, dst, g.intOperand(b))
+	default:
+		// RAX é o acumulador principal para operações matemáticas.
+		g.movValue("rax", a)
+		g.emit(op, "rax", g.intOperand(b))
+		g.mov(dst, "rax")
+	}
+}
+
+// genFloatArith: Mesma lógica, com as instruções escalares do SSE2 sobre XMM0.
+func (g *generator) genFloatArith(in *ir.Instr) {
+	g.loadFloat("xmm0", in.Args[0])
+	src := g.loc(in.Args[1])
+	if isReg(src) {
+		g.loadFloat("xmm1", in.Args[1])
+		src = "xmm1"
+	}
+	g.emit(floatOps[in.Op], "xmm0", src).Comment = "Aritmetica decimal"
+	dst := g.loc(in.Dst)
+	if isReg(dst) {
+		g.emit("movq", dst, "xmm0")
+	} else {
+		g.emit("movsd", dst, "xmm0")
+	}
+}
+
+var floatOps = map[ir.Op]string{ir.OpAdd: "addsd", ir.OpSub: "subsd", ir.OpMul: "mulsd", ir.OpDiv: "divsd"}
+
+// genCall: Distribui os argumentos nos registradores (inteiros/ponteiros e
+// decimais têm filas separadas na convenção System V) e chama a função.
+func (g *generator) genCall(in *ir.Instr) {
+	if strings.HasPrefix(in.Func, "sigma_") {
+		g.runtime[in.Func] = true
+	}
+
+	// Os argumentos que não cabem nos registradores vão para a pilha, na ordem
+	// da chamada (System V): o primeiro em [rsp], o seguinte em [rsp + 8]...
+	// Eles são gravados antes dos registradores, porque a cópia passa por RAX.
+	var regs, stack []ir.Value
+	nInt, nFlt := 0, 0
+	for _, arg := range in.Args {
+		switch {
+		case arg.Type() == ir.F64 && nFlt < len(fltArgRegs):
+			nFlt++
+			regs = append(regs, arg)
+		case arg.Type() != ir.F64 && nInt < len(intArgRegs):
+			nInt++
+			regs = append(regs, arg)
+		default:
+			stack = append(stack, arg)
 		}
 	}
+	size := (8*len(stack) + 15) / 16 * 16 // A pilha continua alinhada em 16 bytes
+	if size > 0 {
+		g.emit("sub", "rsp", strconv.Itoa(size)).Comment = fmt.Sprintf("%d argumento(s) na pilha", len(stack))
+	}
+	for i, arg := range stack {
+		slot := "[rsp]"
+		if i > 0 {
+			slot = fmt.Sprintf("[rsp + %d]", 8*i)
+		}
+		g.movValue(slot, arg)
+	}
+	nInt, nFlt = 0, 0
+	for _, arg := range regs {
+		if arg.Type() == ir.F64 {
+			g.loadFloat(fltArgRegs[nFlt], arg)
+			nFlt++
+		} else {
+			g.movValue(intArgRegs[nInt], arg)
+			nInt++
+		}
+	}
+	if in.Func == ir.FuncPrintf {
+		// Funções variádicas: AL = quantidade de registradores XMM usados.
+		if nFlt == 0 {
+			g.emit("xor", "eax", "eax").Comment = "AL=0 indica que não há vetores SSE"
+		} else {
+			g.emit("mov", "eax", strconv.Itoa(nFlt)).Comment = "AL = registradores XMM usados"
+		}
+	}
+	g.emit("call", g.libc(in.Func))
+	if size > 0 {
+		g.emit("add", "rsp", strconv.Itoa(size)).Comment = "Descarta os argumentos da pilha"
+	}
+	if in.Dst != nil {
+		g.mov(g.loc(in.Dst), "rax")
+	}
+}
+
+// --- OPERANDOS ---
+
+// loc: Onde o valor está: registrador, memória ou imediato. Endereços de
+// símbolos (SymAddr) não têm um operando direto e devolvem "".
+func (g *generator) loc(v ir.Value) string {
+	switch x := v.(type) {
+	case *ir.Temp:
+		if name, ok := g.alloc.Shared[x]; ok {
+			return g.varLoc(name)
+		}
+		if r, ok := g.alloc.Reg[x]; ok {
+			return r
+		}
+		// Os temporários ficam abaixo das variáveis, no fim da moldura.
+		return fmt.Sprintf("[rbp - %d]", g.tempBase+8*(g.slots[x]+1))
+	case ir.IntConst:
+		return x.String()
+	case ir.FloatConst:
+		return g.floatOperand(x)
+	}
+	return ""
+}
+
+// varLoc: Registrador da variável promovida, ou a sua posição na pilha.
+func (g *generator) varLoc(name string) string {
+	if r, ok := g.alloc.VarReg[name]; ok {
+		return r
+	}
+	return fmt.Sprintf("[rbp - %d]", g.vars[name])
+}
+
+// mov: Copia 64 bits de src para dst. O x86 não aceita memória -> memória
+// (nem imediatos de 64 bits gravados direto na memória): esses casos passam por RAX.
+func (g *generator) mov(dst, src string) {
+	if dst == src {
+		return
+	}
+	if !isReg(dst) && (!isReg(src) && !isImm(src) || isWideImm(src)) {
+		g.emit("mov", "rax", src)
+		src = "rax"
+	}
+	if !isReg(dst) && isImm(src) {
+		g.emit("mov", "qword "+dst, src)
+		return
+	}
+	g.emit("mov", dst, src)
+}
 
-	// Epílogo da Função: Limpa a pilha e retorna ao SO.
-	textSection.WriteString("\n    add rsp, 32                       ; Restaura espaco da pilha\n")
-	textSection.WriteString("    pop rbp                             ; Restaura o RBP original\n")
-	textSection.WriteString("    mov rax, 0                          ; Return 0\n")
-	textSection.WriteString("    ret\n")
+// movValue: Como mov, mas aceita também o endereço de um símbolo (lea).
+func (g *generator) movValue(dst string, v ir.Value) {
+	s, ok := v.(ir.SymAddr)
+	if !ok {
+		g.mov(dst, g.loc(v))
+		return
+	}
+	reg := dst
+	if !isReg(dst) {
+		reg = "rax"
+	}
+	g.emit("lea", reg, "["+s.Name+"]")
+	g.mov(dst, reg)
+}
 
-	return dataSection.String() + textSection.String()
+// loadFloat: Coloca um decimal em um registrador XMM. Se ele morar em um
+// registrador de uso geral, os bits são copiados com movq.
+func (g *generator) loadFloat(reg string, v ir.Value) {
+	src := g.loc(v)
+	if isReg(src) {
+		g.emit("movq", reg, src)
+		return
+	}
+	g.emit("movsd", reg, src)
+}
+
+// intOperand: Operando-fonte inteiro. add/sub/imul/cmp só aceitam imediatos
+// de 32 bits com sinal: valores maiores passam antes por R11.
+func (g *generator) intOperand(v ir.Value) string {
+	src := g.loc(v)
+	if src == "" || isWideImm(src) {
+		g.movValue("r11", v)
+		return "r11"
+	}
+	return src
+}
+
+// floatOperand: O SSE não aceita imediatos, então literais decimais
+// são guardados na seção de dados (flt_N dq 2.5) e lidos da memória.
+func (g *generator) floatOperand(c ir.FloatConst) string {
+	bits := math.Float64bits(c.V)
+	label, ok := g.floats[bits]
+	if !ok {
+		label = fmt.Sprintf("flt_%d", len(g.floats))
+		g.floats[bits] = label
+		g.data = append(g.data, &Data{Label: label, Float: c.String()})
+	}
+	return "qword [" + label + "]"
+}
+
+// memOperand: Endereço de @x ou @v[i]. O índice usa o endereçamento escalado
+// do x86 ([r10 + rcx*8]); índices constantes viram deslocamento a partir do
+// rótulo ([vet_v + 16]). Um acesso a vetor sempre tem '+' no operando: assim
+// o peephole sabe que ele não é a posição de uma variável simples.
+func (g *generator) memOperand(m *ir.Mem) string {
+	if m.Index == nil {
+		return g.varLoc(m.Sym)
+	}
+	base := arrayLabel(m.Sym)
+	if idx, ok := m.Index.(ir.IntConst); ok {
+		return fmt.Sprintf("[%s + %d]", base, 8*idx.V)
+	}
+	idx := g.loc(m.Index)
+	if !isReg(idx) {
+		g.mov("rcx", idx)
+		idx = "rcx"
+	}
+	// Relativo ao RIP não aceita índice: o início do vetor passa antes por R10.
+	g.emit("lea", "r10", "["+base+"]").Comment = "Inicio de " + m.Sym
+	return fmt.Sprintf("[r10 + %s*8]", idx)
+}
+
+// arrayLabel: O rótulo do vetor na .bss. O prefixo evita conflito com os
+// rótulos do compilador (fmt_0, main...) e com os nomes da LibC.
+func arrayLabel(name string) string {
+	return "vet_" + name
+}
+
+// isReg: O operando é um registrador (não é memória nem imediato)?
+func isReg(s string) bool {
+	return s != "" && !strings.Contains(s, "[") && !isImm(s)
+}
+
+func isImm(s string) bool {
+	return s != "" && (s[0] == '-' || s[0] >= '0' && s[0] <= '9')
+}
+
+// isWideImm: Imediatos fora de 32 bits com sinal só cabem em 'mov reg, imm64'.
+func isWideImm(s string) bool {
+	if !isImm(s) {
+		return false
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	return err == nil && (v > math.MaxInt32 || v < math.MinInt32)
+}
+
+// genRuntime: Rotinas de apoio emitidas uma única vez, depois do 'main',
+// e apenas quando o programa as chama.
+func (g *generator) genRuntime() {
+	if g.runtime[ir.FuncScan] {
+		// sigma_scan(formato, endereco): fflush + scanf. Em caso de texto inválido,
+		// descarta o resto da linha para que a próxima leitura não trave no mesmo lixo.
+		g.comment("--- Runtime Sigma: leitura verificada ---")
+		g.label("sigma_scan")
+		g.emit("push", "rbx").Comment = "Preserva registradores callee-saved"
+		g.emit("push", "r12")
+		g.emit("push", "r13").Comment = "3 pushes: pilha volta ao alinhamento de 16"
+		g.emit("mov", "r12", "rdi")
+		g.emit("mov", "r13", "rsi")
+		// Sem '\n' o printf deixa o prompt no buffer; fflush(NULL) esvazia
+		// todos os buffers de saída antes de esperar pelo teclado.
+		g.emit("xor", "edi", "edi").Comment = "RDI = NULL (todos os streams)"
+		g.emit("call", g.libc("fflush")).Comment = "Garante que o prompt apareça"
+		g.emit("mov", "rdi", "r12")
+		g.emit("mov", "rsi", "r13")
+		g.emit("xor", "eax", "eax")
+		g.emit("call", g.libc("scanf"))
+		g.emit("cmp", "eax", "1")
+		g.emit("je", ".fim")
+		g.emit("cmp", "eax", "-1").Comment = "EOF"
+		g.emit("je", ".fim")
+		g.label(".descarta")
+		g.emit("call", g.libc("getchar")).Comment = "Consome a linha invalida"
+		g.emit("cmp", "eax", "-1")
+		g.emit("je", ".fim")
+		g.emit("cmp", "eax", "10")
+		g.emit("jne", ".descarta")
+		g.emit("xor", "eax", "eax").Comment = "0 = entrada invalida"
+		g.label(".fim")
+		g.emit("movsxd", "rax", "eax").Comment = "Resultado com sinal em 64 bits"
+		g.emit("pop", "r13")
+		g.emit("pop", "r12")
+		g.emit("pop", "rbx")
+		g.emit("ret")
+	}
+
+	if g.runtime[ir.FuncBoundsError] {
+		// sigma_bounds_error(linha, nome, indice, tamanho): relata o acesso fora do
+		// vetor no stderr e encerra com código 1.
+		g.data = append(g.data, &Data{Label: "fmt_err_bounds", Text: "Erro de execucao (linha %ld): indice %ld fora dos limites de '%s' (0..%ld)\n"})
+		g.text = append(g.text, &Instr{})
+		g.label("sigma_bounds_error")
+		g.emit("and", "rsp", "-16").Comment = "Alinha a pilha em 16 bytes"
+		g.emit("lea", "r9", "[rcx - 1]").Comment = "R9 = Ultimo indice valido"
+		g.emit("mov", "r8", "rsi").Comment = "R8 = Nome do vetor"
+		g.emit("mov", "rcx", "rdx").Comment = "RCX = Indice acessado"
+		g.emit("mov", "rdx", "rdi").Comment = "RDX = Linha"
+		g.emit("mov", "edi", "2").Comment = "RDI = stderr (fd 2)"
+		g.emit("lea", "rsi", "[fmt_err_bounds]")
+		g.emit("xor", "eax", "eax")
+		g.emit("call", g.libc("dprintf"))
+		g.emit("mov", "edi", "1").Comment = "Codigo de saida 1"
+		g.emit("call", g.libc("exit"))
+	}
+
+	if g.runtime[ir.FuncInputError] {
+		// sigma_input_error(nome): relata o erro no stderr e encerra com código 1.
+		g.data = append(g.data, &Data{Label: "fmt_err_input", Text: "Erro de execucao: entrada invalida para a variavel '%s'\n", Comment: "Erro fatal (stderr)"})
+		g.text = append(g.text, &Instr{})
+		g.label("sigma_input_error")
+		g.emit("and", "rsp", "-16").Comment = "Alinha a pilha em 16 bytes"
+		g.emit("mov", "rdx", "rdi").Comment = "RDX = Nome da variavel"
+		g.emit("mov", "edi", "2").Comment = "RDI = stderr (fd 2)"
+		g.emit("lea", "rsi", "[fmt_err_input]")
+		g.emit("xor", "eax", "eax")
+		g.emit("call", g.libc("dprintf"))
+		g.emit("mov", "edi", "1").Comment = "Codigo de saida 1"
+		g.emit("call", g.libc("exit"))
+	}
 }
@@ -0,0 +1,147 @@
+package codegen
+
+import (
+	"csigma/dwarf"
+	"csigma/ir"
+	"fmt"
+	"strings"
+)
+
+// Informação de depuração (-g): durante a geração, cada mudança de linha do
+// fonte Sigma deixa no código uma marca (Instr.Line), que o peephole atravessa
+// como um comentário. Depois do peephole as marcas viram rótulos
+// (.dbg_linha_N), e o pacote dwarf monta com eles a tabela de linhas e a
+// descrição das variáveis do 'main'.
+
+// mark: Marca o início do código da linha do fonte (0 = criado pelo compilador).
+func (g *generator) mark(line int) {
+	if !g.opts.Debug && !g.opts.listing || line == 0 || line == g.line {
+		return
+	}
+	g.line = line
+	g.text = append(g.text, &Instr{Line: line})
+}
+
+// firstLine: A primeira linha do fonte com código, onde o 'main' começa.
+func firstLine(prog *ir.Program, fn *ir.Function) int {
+	for _, v := range prog.Globals {
+		if v.Line > 0 {
+			return v.Line
+		}
+	}
+	for _, b := range fn.Blocks {
+		for _, in := range b.Instrs {
+			if in.Line > 0 {
+				return in.Line
+			}
+		}
+	}
+	return 1
+}
+
+// debugSlots: Com opts.Debug, as posições na pilha das variáveis do fonte. O
+// peephole não apaga as gravações nelas: sem leitura no programa, o valor
+// ainda é lido pelo depurador (DW_AT_location aponta para lá).
+func (g *generator) debugSlots(prog *ir.Program) map[string]bool {
+	if !g.opts.Debug {
+		return nil
+	}
+	keep := map[string]bool{}
+	for _, v := range prog.Globals {
+		if loc := g.varLoc(v.Name); v.Line != 0 && v.Size == 0 && isStackSlot(loc) {
+			keep[mem(loc)] = true
+		}
+	}
+	return keep
+}
+
+// debugInfo: Troca as marcas da função (já otimizada) por rótulos, marca o fim
+// dela e descreve as variáveis, onde quer que tenham ficado.
+func (g *generator) debugInfo(prog *ir.Program, fn string) []dwarf.Section {
+	unit := &dwarf.Unit{
+		Producer: "CSigma",
+		File:     g.opts.Source,
+		Low:      fn,
+		High:     fn + ".dbg_fim",
+	}
+	var text []*Instr
+	last := -1 // Marca ainda sem instrução depois dela
+	for i, in := range g.text {
+		switch {
+		case in.Line != 0:
+			if last >= 0 {
+				// Duas marcas seguidas (o peephole apagou o código da primeira).
+				unit.Rows = unit.Rows[:len(unit.Rows)-1]
+				text = append(text[:last], text[last+1:]...)
+			}
+			in.Label = fmt.Sprintf(".dbg_linha_%d", i)
+			unit.Rows = append(unit.Rows, dwarf.Row{Label: fn + in.Label, Line: in.Line})
+			last = len(text)
+		case in.Op != "":
+			last = -1
+		}
+		text = append(text, in)
+	}
+	g.text = append(text, &Instr{Label: ".dbg_fim"})
+
+	for _, v := range prog.Globals {
+		if v.Line == 0 {
+			continue
+		}
+		dv := dwarf.Var{Name: v.Name, Line: v.Line, Type: dwarf.Int, Count: v.Size}
+		switch v.Type {
+		case ir.F64:
+			dv.Type = dwarf.Float
+		case ir.Ptr:
+			dv.Type = dwarf.String
+		}
+		if v.Size > 0 {
+			dv.Static = arrayLabel(v.Name)
+		} else if r, ok := g.alloc.VarReg[v.Name]; ok {
+			dv.Location = dwarf.Register(r)
+		} else if off, ok := g.vars[v.Name]; ok {
+			dv.Location = dwarf.Frame(-off)
+		}
+		unit.Vars = append(unit.Vars, dv)
+	}
+	return unit.Sections()
+}
+
+// debugRef: O rótulo referenciado pela informação de depuração, na sintaxe do
+// GNU as: "main.dbg_linha_3" (nome completo no NASM) vira ".Lmain_dbg_linha_3".
+func debugRef(symbol string) string {
+	if i := strings.Index(symbol, "."); i > 0 {
+		return gasLabel(symbol[i:], symbol[:i])
+	}
+	return symbol
+}
+
+// writeDebug: Escreve as seções de depuração em um arquivo assembly: os bytes
+// com a diretiva 'bytes' (16 por linha), e cada referência com a diretiva do
+// seu tamanho ('refs'), passando o símbolo por 'ref'.
+func writeDebug(sb *strings.Builder, sections []dwarf.Section, header string, bytes string, refs map[int]string, ref func(string) string) {
+	for _, sec := range sections {
+		fmt.Fprintf(sb, "\n"+header+"\n%s:\n", sec.Name, sec.Label)
+		pos := 0
+		emit := func(end int) {
+			for pos < end {
+				n := end - pos
+				if n > 16 {
+					n = 16
+				}
+				vals := make([]string, n)
+				for i, b := range sec.Data[pos : pos+n] {
+					vals[i] = fmt.Sprintf("0x%02x", b)
+				}
+				fmt.Fprintf(sb, "    %s %s\n", bytes, strings.Join(vals, ", "))
+				pos += n
+			}
+		}
+		for _, r := range sec.Refs {
+			emit(r.Offset)
+			fmt.Fprintf(sb, "    %s %s\n", refs[r.Size], ref(r.Symbol))
+			pos += r.Size
+		}
+		emit(len(sec.Data))
+	}
+}
@@ -0,0 +1,513 @@
+package codegen
+
+import (
+	"csigma/elf"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Montador interno: traduz as instruções (sintaxe Intel, como em Instr.String)
+// para código de máquina x86_64, sem NASM. Só conhece as formas que o gerador
+// emite. Saltos e chamadas usam sempre deslocamentos de 32 bits, então o
+// tamanho de cada instrução é conhecido na primeira passada; os alvos são
+// preenchidos no fim (ou deixados para o linker, nas funções da LibC).
+//
+//	mov rax, [rbp - 16]   => 48 8B 45 F0
+//	   REX.W  opcode  ModRM(mod=01 reg=rax rm=rbp)  disp8
+
+// operand: Um operando já decodificado.
+type operand struct {
+	kind  int // opReg, opXMM, opImm, opMem, opLabel
+	reg   int // Número do registrador (0-15); em opMem, a base (-1 sem base)
+	wide  bool
+	low   bool // Registrador de 8 bits (al, cl, dl, bl)
+	imm   int64
+	index int // Registrador de índice (-1 sem índice)
+	scale int
+	label string // Rótulo de dados ([fmt_0]) ou destino de salto/chamada
+}
+
+const (
+	opReg = iota
+	opXMM
+	opImm
+	opMem
+	opLabel
+)
+
+var gprNumbers = map[string]int{
+	"rax": 0, "rcx": 1, "rdx": 2, "rbx": 3, "rsp": 4, "rbp": 5, "rsi": 6, "rdi": 7,
+}
+
+// parseOperand: "rax", "eax", "dl", "xmm1", "-16", "qword [rbp - 48 + rcx*8]", "[fmt_0]" ou "printf".
+// O tamanho do acesso à memória (qword, byte) já vem implícito no opcode.
+func parseOperand(s string) (operand, error) {
+	s = strings.TrimPrefix(mem(s), "byte ")
+	switch {
+	case strings.HasPrefix(s, "["):
+		return parseMem(s)
+	case isImm(s):
+		v, err := strconv.ParseInt(s, 10, 64)
+		return operand{kind: opImm, imm: v}, err
+	case strings.HasPrefix(s, "xmm"):
+		n, err := strconv.Atoi(s[3:])
+		return operand{kind: opXMM, reg: n}, err
+	}
+	if n, ok := gprNumbers[s]; ok {
+		return operand{kind: opReg, reg: n, wide: true}, nil
+	}
+	if n, ok := gprNumbers["r"+s[1:]]; ok && s[0] == 'e' {
+		return operand{kind: opReg, reg: n}, nil
+	}
+	if n, ok := gprNumbers["r"+s[:1]+"x"]; ok && len(s) == 2 && s[1] == 'l' {
+		return operand{kind: opReg, reg: n, low: true}, nil
+	}
+	if strings.HasPrefix(s, "r") {
+		if n, err := strconv.Atoi(strings.TrimSuffix(s[1:], "d")); err == nil && n >= 8 && n <= 15 {
+			return operand{kind: opReg, reg: n, wide: !strings.HasSuffix(s, "d")}, nil
+		}
+	}
+	return operand{kind: opLabel, label: s}, nil
+}
+
+// parseMem: [base - desloc + indice*escala] ou [rotulo + desloc].
+func parseMem(s string) (operand, error) {
+	op := operand{kind: opMem, reg: -1, index: -1}
+	inner := strings.ReplaceAll(strings.Trim(s, "[]"), " - ", " + -")
+	for _, term := range strings.Split(inner, " + ") {
+		switch {
+		case strings.Contains(term, "*"):
+			parts := strings.SplitN(term, "*", 2)
+			r, err := parseOperand(parts[0])
+			if err != nil || r.kind != opReg {
+				return op, fmt.Errorf("indice invalido em %s", s)
+			}
+			op.index = r.reg
+			op.scale, _ = strconv.Atoi(parts[1])
+		case isImm(term):
+			v, err := strconv.ParseInt(term, 10, 32)
+			if err != nil {
+				return op, err
+			}
+			op.imm += v
+		default:
+			r, _ := parseOperand(term)
+			if r.kind == opReg {
+				op.reg = r.reg
+			} else {
+				op.label = term
+			}
+		}
+	}
+	return op, nil
+}
+
+// encoder: Estado da montagem de uma função após a outra.
+type encoder struct {
+	code   []byte
+	fn     string         // Último rótulo global (dono dos rótulos locais .x)
+	labels map[string]int // Rótulo -> posição no código
+	fixups []fixup        // Saltos e chamadas a completar
+	relocs []elf.Reloc
+}
+
+// fixup: Deslocamento de 32 bits em 'at' que deve apontar para 'label'.
+type fixup struct {
+	at    int
+	label string
+}
+
+// Assemble: Monta a listagem em um objeto ELF64 (.o) pronto para o linker.
+func assemble(l *listing) (*elf.Object, error) {
+	obj := &elf.Object{}
+	e := &encoder{labels: map[string]int{}}
+	l.spans = map[*Instr][2]int{}
+
+	// --- DADOS ---
+	for _, d := range l.data {
+		if d.Space > 0 {
+			obj.Symbols = append(obj.Symbols, elf.Symbol{Name: d.Label, Section: elf.SecBss, Value: obj.Bss})
+			obj.Bss += uint64(d.Space)
+			continue
+		}
+		obj.Symbols = append(obj.Symbols, elf.Symbol{Name: d.Label, Section: elf.SecData, Value: uint64(len(obj.Data))})
+		if d.Float != "" {
+			v, err := strconv.ParseFloat(d.Float, 64)
+			if err != nil {
+				return nil, err
+			}
+			obj.Data = append(obj.Data, le64(math.Float64bits(v))...)
+			continue
+		}
+		obj.Data = append(append(obj.Data, d.Text...), 0)
+	}
+
+	// --- CÓDIGO ---
+	var funcs []string
+	for _, in := range l.text {
+		switch {
+		case in.Label != "":
+			name := in.Label
+			if strings.HasPrefix(name, ".") {
+				name = e.fn + name
+			} else {
+				e.fn = name
+				funcs = append(funcs, name)
+			}
+			e.labels[name] = len(e.code)
+		case in.Op != "":
+			start := len(e.code)
+			if err := e.instr(in); err != nil {
+				return nil, fmt.Errorf("montador interno: '%s': %v", strings.TrimSpace(in.String()), err)
+			}
+			l.spans[in] = [2]int{start, len(e.code)}
+		}
+	}
+
+	// Saltos para rótulos locais e chamadas às rotinas do runtime têm o destino
+	// conhecido; as demais chamadas (LibC) ficam para o linker.
+	external := map[string]bool{}
+	for _, f := range e.fixups {
+		target, ok := e.labels[f.label]
+		if !ok {
+			if strings.Contains(f.label, ".") {
+				return nil, fmt.Errorf("montador interno: rotulo indefinido '%s'", f.label)
+			}
+			e.relocs = append(e.relocs, elf.Reloc{Offset: uint64(f.at), Symbol: f.label, Type: elf.R_X86_64_PLT32, Addend: -4})
+			external[f.label] = true
+			continue
+		}
+		binary.LittleEndian.PutUint32(e.code[f.at:], uint32(int32(target-(f.at+4))))
+	}
+
+	for _, name := range funcs {
+		obj.Symbols = append(obj.Symbols, elf.Symbol{Name: name, Section: elf.SecText, Value: uint64(e.labels[name]), Global: name == "main" || name == "_start", Func: true})
+	}
+	for _, f := range e.fixups {
+		if external[f.label] {
+			obj.Symbols = append(obj.Symbols, elf.Symbol{Name: f.label, Global: true})
+			delete(external, f.label)
+		}
+	}
+	obj.Text = e.code
+	obj.Relocs = e.relocs
+
+	// --- DEPURAÇÃO (-g) ---
+	// Endereços do código: relativos à função do rótulo (main + deslocamento).
+	// Endereços dos vetores: ao próprio rótulo na .bss. Referências entre as
+	// seções: ao rótulo do início da seção de destino.
+	data := map[string]bool{}
+	for _, d := range l.data {
+		data[d.Label] = true
+	}
+	for i, sec := range l.debug {
+		obj.Symbols = append(obj.Symbols, elf.Symbol{Name: sec.Label, Section: elf.SecExtra + i})
+		extra := elf.Section{Name: sec.Name, Data: sec.Data}
+		for _, r := range sec.Refs {
+			rel := elf.Reloc{Offset: uint64(r.Offset), Symbol: r.Symbol, Type: elf.R_X86_64_32}
+			switch {
+			case r.Size == 8 && data[r.Symbol]:
+				rel.Type = elf.R_X86_64_64
+			case r.Size == 8:
+				fn := strings.SplitN(r.Symbol, ".", 2)[0]
+				target, ok := e.labels[r.Symbol]
+				if !ok {
+					return nil, fmt.Errorf("montador interno: rotulo indefinido '%s'", r.Symbol)
+				}
+				rel = elf.Reloc{Offset: uint64(r.Offset), Symbol: fn, Type: elf.R_X86_64_64, Addend: int64(target - e.labels[fn])}
+			}
+			extra.Relocs = append(extra.Relocs, rel)
+		}
+		obj.Extra = append(obj.Extra, extra)
+	}
+	return obj, nil
+}
+
+// Códigos das instruções de dois operandos da ULA (add, or, and, sub, xor, cmp):
+// a extensão /n do opcode 81/83 e a base dos opcodes 01/03 (= n*8 + 1 ou 3).
+var aluOps = map[string]byte{"add": 0, "or": 1, "and": 4, "sub": 5, "xor": 6, "cmp": 7}
+
+// conds: O código de condição (tttn) de cada salto condicional (0F 80+cc).
+var conds = map[string]byte{"jb": 0x2, "jae": 0x3, "je": 0x4, "jne": 0x5, "jbe": 0x6, "ja": 0x7, "jl": 0xC, "jge": 0xD, "jle": 0xE, "jg": 0xF}
+
+// shifts: A extensão /n dos deslocamentos (D1 por 1 bit, C1 por imediato).
+var shifts = map[string]int{"shl": 4, "shr": 5, "sar": 7}
+
+// unary: A extensão /n das instruções de um operando do grupo F7.
+var unary = map[string]int{"neg": 3, "div": 6, "idiv": 7}
+
+// sseOps: Operações escalares de precisão dupla (prefixo F2, 0F xx).
+var sseOps = map[string]byte{"addsd": 0x58, "mulsd": 0x59, "subsd": 0x5C, "divsd": 0x5E}
+
+// instr: Codifica uma instrução no fim do código.
+func (e *encoder) instr(in *Instr) error {
+	ops := make([]operand, len(in.Args))
+	for i, a := range in.Args {
+		op, err := parseOperand(a)
+		if err != nil {
+			return err
+		}
+		ops[i] = op
+	}
+	two := len(ops) == 2
+	var dst, src operand
+	if len(ops) > 0 {
+		dst = ops[0]
+	}
+	if two {
+		src = ops[1]
+	}
+
+	switch {
+	case in.Op == "ret" && len(ops) == 0:
+		e.bytes(0xC3)
+	case in.Op == "cqo" && len(ops) == 0:
+		e.bytes(0x48, 0x99)
+	case in.Op == "rep stosq" && len(ops) == 0:
+		e.bytes(0xF3, 0x48, 0xAB)
+	case in.Op == "syscall" && len(ops) == 0:
+		e.bytes(0x0F, 0x05)
+
+	case (in.Op == "push" || in.Op == "pop") && len(ops) == 1 && dst.kind == opReg:
+		if dst.reg >= 8 {
+			e.bytes(0x41)
+		}
+		base := byte(0x50)
+		if in.Op == "pop" {
+			base = 0x58
+		}
+		e.bytes(base + byte(dst.reg&7))
+
+	case (in.Op == "call" || in.Op == "jmp") && len(ops) == 1 && dst.kind == opLabel:
+		if in.Op == "call" {
+			e.bytes(0xE8)
+		} else {
+			e.bytes(0xE9)
+		}
+		e.target(dst.label)
+	case conds[in.Op] != 0 && len(ops) == 1 && dst.kind == opLabel:
+		e.bytes(0x0F, 0x80+conds[in.Op])
+		e.target(dst.label)
+
+	case in.Op == "mov" && two:
+		return e.mov(dst, src)
+
+	case in.Op == "lea" && two && dst.kind == opReg && src.kind == opMem:
+		e.modrm(0, true, []byte{0x8D}, dst.reg, src, 0)
+
+	case aluOps[in.Op] != 0 || in.Op == "add":
+		if !two {
+			return fmt.Errorf("operandos invalidos")
+		}
+		return e.alu(aluOps[in.Op], dst, src)
+
+	case in.Op == "imul" && two && dst.kind == opReg && src.kind == opImm:
+		if src.imm == int64(int8(src.imm)) {
+			e.modrm(0, true, []byte{0x6B}, dst.reg, dst, 1)
+			e.bytes(byte(src.imm))
+		} else {
+			e.modrm(0, true, []byte{0x69}, dst.reg, dst, 4)
+			e.imm32(src.imm)
+		}
+	case in.Op == "imul" && two && dst.kind == opReg && (src.kind == opReg || src.kind == opMem):
+		e.modrm(0, true, []byte{0x0F, 0xAF}, dst.reg, src, 0)
+
+	case shifts[in.Op] != 0 && two && dst.kind == opReg && src.kind == opImm && src.imm == 1:
+		e.modrm(0, dst.wide, []byte{0xD1}, shifts[in.Op], dst, 0)
+	case shifts[in.Op] != 0 && two && dst.kind == opReg && src.kind == opImm:
+		e.modrm(0, dst.wide, []byte{0xC1}, shifts[in.Op], dst, 1)
+		e.bytes(byte(src.imm))
+	case unary[in.Op] != 0 && len(ops) == 1 && dst.kind == opReg:
+		e.modrm(0, dst.wide, []byte{0xF7}, unary[in.Op], dst, 0)
+	case in.Op == "movsxd" && two && dst.kind == opReg && src.kind == opReg:
+		e.modrm(0, true, []byte{0x63}, dst.reg, src, 0)
+	case in.Op == "movzx" && two && dst.kind == opReg && src.kind == opMem:
+		e.modrm(0, dst.wide, []byte{0x0F, 0xB6}, dst.reg, src, 0)
+
+	// --- SSE2 ---
+	case in.Op == "movsd" && two && dst.kind == opXMM && (src.kind == opXMM || src.kind == opMem):
+		e.modrm(0xF2, false, []byte{0x0F, 0x10}, dst.reg, src, 0)
+	case in.Op == "movsd" && two && dst.kind == opMem && src.kind == opXMM:
+		e.modrm(0xF2, false, []byte{0x0F, 0x11}, src.reg, dst, 0)
+	case in.Op == "movq" && two && dst.kind == opXMM && src.kind == opReg:
+		e.modrm(0x66, true, []byte{0x0F, 0x6E}, dst.reg, src, 0)
+	case in.Op == "movq" && two && dst.kind == opReg && src.kind == opXMM:
+		e.modrm(0x66, true, []byte{0x0F, 0x7E}, src.reg, dst, 0)
+	case in.Op == "movq" && two && dst.kind == opXMM && (src.kind == opXMM || src.kind == opMem):
+		e.modrm(0xF3, false, []byte{0x0F, 0x7E}, dst.reg, src, 0)
+	case in.Op == "movq" && two && dst.kind == opMem && src.kind == opXMM:
+		e.modrm(0x66, false, []byte{0x0F, 0xD6}, src.reg, dst, 0)
+	case sseOps[in.Op] != 0 && two && dst.kind == opXMM && (src.kind == opXMM || src.kind == opMem):
+		e.modrm(0xF2, false, []byte{0x0F, sseOps[in.Op]}, dst.reg, src, 0)
+	case in.Op == "ucomisd" && two && dst.kind == opXMM && (src.kind == opXMM || src.kind == opMem):
+		e.modrm(0x66, false, []byte{0x0F, 0x2E}, dst.reg, src, 0)
+	case in.Op == "cvtsi2sd" && two && dst.kind == opXMM && src.kind == opReg:
+		e.modrm(0xF2, src.wide, []byte{0x0F, 0x2A}, dst.reg, src, 0)
+	case in.Op == "cvtsd2si" && two && dst.kind == opReg && src.kind == opXMM:
+		e.modrm(0xF2, dst.wide, []byte{0x0F, 0x2D}, dst.reg, src, 0)
+
+	default:
+		return fmt.Errorf("instrucao nao suportada")
+	}
+	return nil
+}
+
+// mov: As formas de cópia de 64 (ou 32) bits.
+func (e *encoder) mov(dst, src operand) error {
+	switch {
+	case dst.kind == opReg && src.kind == opReg:
+		e.modrm(0, dst.wide, []byte{0x89}, src.reg, dst, 0)
+	case dst.kind == opReg && src.kind == opMem:
+		e.modrm(0, dst.wide, []byte{0x8B}, dst.reg, src, 0)
+	case dst.kind == opMem && src.kind == opReg && src.low:
+		e.modrm(0, false, []byte{0x88}, src.reg, dst, 0)
+	case dst.kind == opMem && src.kind == opReg:
+		e.modrm(0, src.wide, []byte{0x89}, src.reg, dst, 0)
+	case dst.kind == opMem && src.kind == opImm:
+		e.modrm(0, true, []byte{0xC7}, 0, dst, 4)
+		e.imm32(src.imm)
+	case dst.kind == opReg && src.kind == opImm:
+		switch {
+		case src.imm >= 0 && src.imm <= math.MaxUint32 || !dst.wide:
+			// mov r32, imm32: zera a metade de cima (forma curta, sem REX.W).
+			if dst.reg >= 8 {
+				e.bytes(0x41)
+			}
+			e.bytes(0xB8 + byte(dst.reg&7))
+			e.imm32(src.imm)
+		case src.imm == int64(int32(src.imm)):
+			e.modrm(0, true, []byte{0xC7}, 0, dst, 4)
+			e.imm32(src.imm)
+		default:
+			e.bytes(rex(true, 0, -1, dst.reg), 0xB8+byte(dst.reg&7))
+			e.bytes(le64(uint64(src.imm))...)
+		}
+	default:
+		return fmt.Errorf("operandos invalidos")
+	}
+	return nil
+}
+
+// alu: add/and/sub/xor/cmp nas formas r/m, r ; r, r/m ; r/m, imm.
+func (e *encoder) alu(n byte, dst, src operand) error {
+	wide := dst.wide || dst.kind == opMem
+	switch {
+	case src.kind == opImm && src.imm == int64(int8(src.imm)):
+		e.modrm(0, wide, []byte{0x83}, int(n), dst, 1)
+		e.bytes(byte(src.imm))
+	case src.kind == opImm:
+		e.modrm(0, wide, []byte{0x81}, int(n), dst, 4)
+		e.imm32(src.imm)
+	case src.kind == opReg && (dst.kind == opReg || dst.kind == opMem):
+		e.modrm(0, src.wide, []byte{n*8 + 1}, src.reg, dst, 0)
+	case dst.kind == opReg && src.kind == opMem:
+		e.modrm(0, dst.wide, []byte{n*8 + 3}, dst.reg, src, 0)
+	default:
+		return fmt.Errorf("operandos invalidos")
+	}
+	return nil
+}
+
+// modrm: prefixo, REX, opcode, ModRM, SIB e deslocamento. 'reg' vai no campo
+// reg do ModRM (registrador ou extensão /n do opcode); 'rm' é registrador ou
+// memória. 'trailing' é o tamanho do imediato que vem depois (a relocação de
+// [rotulo] é relativa ao fim da instrução).
+func (e *encoder) modrm(prefix byte, wide bool, opcode []byte, reg int, rm operand, trailing int) {
+	if prefix != 0 {
+		e.bytes(prefix)
+	}
+	index, base := -1, rm.reg
+	if rm.kind == opMem {
+		index = rm.index
+	}
+	if r := rex(wide, reg, index, base); r != 0 {
+		e.bytes(r)
+	}
+	e.bytes(opcode...)
+
+	if rm.kind != opMem {
+		e.bytes(0xC0 | byte(reg&7)<<3 | byte(rm.reg&7))
+		return
+	}
+	if rm.reg < 0 && rm.index < 0 {
+		// [rotulo + desloc]: relativo ao RIP (mod=00, rm=101), completado pelo linker.
+		e.bytes(byte(reg&7)<<3 | 5)
+		e.relocs = append(e.relocs, elf.Reloc{Offset: uint64(len(e.code)), Symbol: rm.label, Type: elf.R_X86_64_PC32, Addend: rm.imm - 4 - int64(trailing)})
+		e.imm32(0)
+		return
+	}
+
+	// mod: 00 sem deslocamento (RBP/R13 como base sempre pedem um), 01 com 8 bits, 10 com 32.
+	mod := byte(0x80)
+	switch {
+	case rm.imm == 0 && base&7 != 5:
+		mod = 0
+	case rm.imm == int64(int8(rm.imm)):
+		mod = 0x40
+	}
+	if rm.index >= 0 || base&7 == 4 {
+		// SIB: escala, índice (100 = nenhum) e base.
+		idx := byte(4)
+		if rm.index >= 0 {
+			idx = byte(rm.index & 7)
+		}
+		scale := map[int]byte{0: 0, 1: 0, 2: 1, 4: 2, 8: 3}[rm.scale]
+		e.bytes(mod|byte(reg&7)<<3|4, scale<<6|idx<<3|byte(base&7))
+	} else {
+		e.bytes(mod | byte(reg&7)<<3 | byte(base&7))
+	}
+	switch mod {
+	case 0x40:
+		e.bytes(byte(rm.imm))
+	case 0x80:
+		e.imm32(rm.imm)
+	}
+}
+
+// rex: O prefixo REX (0100WRXB), ou 0 quando não é necessário.
+func rex(wide bool, reg, index, base int) byte {
+	r := byte(0)
+	if wide {
+		r |= 8
+	}
+	if reg >= 8 {
+		r |= 4
+	}
+	if index >= 8 {
+		r |= 2
+	}
+	if base >= 8 {
+		r |= 1
+	}
+	if r == 0 {
+		return 0
+	}
+	return 0x40 | r
+}
+
+// target: Deslocamento de 32 bits para um rótulo, completado no fim da montagem.
+func (e *encoder) target(label string) {
+	if strings.HasPrefix(label, ".") {
+		label = e.fn + label
+	}
+	e.fixups = append(e.fixups, fixup{at: len(e.code), label: label})
+	e.imm32(0)
+}
+
+func (e *encoder) bytes(b ...byte) {
+	e.code = append(e.code, b...)
+}
+
+func (e *encoder) imm32(v int64) {
+	e.bytes(le64(uint64(v))[:4]...)
+}
+
+// le64: Os 8 bytes de v, do menos para o mais significativo (little-endian).
+func le64(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, v)
+	return b
+}
@@ -0,0 +1,148 @@
+package codegen
+
+import (
+	"csigma/elf"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// Os bytes esperados são os do GNU as (.intel_syntax noprefix) para a mesma
+// instrução.
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		op, args string
+		want     string
+	}{
+		// Registrador -> registrador: REX.W, REX.B para r8-r15.
+		{"mov", "rax, rbx", "4889d8"},
+		{"mov", "r12, rax", "4989c4"},
+		{"cmp", "rax, rcx", "4839c8"},
+		{"xor", "r8d, r8d", "4531c0"},
+		{"imul", "rax, rcx", "480fafc1"},
+
+		// ModRM com memória: deslocamento de 8 e de 32 bits.
+		{"mov", "rax, qword [rbp - 16]", "488b45f0"},
+		{"mov", "qword [rbp - 200], rax", "48898538ffffff"},
+		{"lea", "rdi, [rbp - 32]", "488d7de0"},
+		{"add", "rax, [rbp - 8]", "480345f8"},
+		{"mov", "byte [rdi], dl", "8817"},
+		{"movzx", "eax, byte [rsi]", "0fb606"},
+
+		// SIB: base RSP/R12 (rm=100), R13 sem deslocamento (mod=01, disp8 0) e
+		// índice escalado, com REX.X para r8-r15.
+		{"mov", "rax, [rsp + 8]", "488b442408"},
+		{"mov", "rax, [r12]", "498b0424"},
+		{"mov", "rax, [r13]", "498b4500"},
+		{"mov", "rax, [rbp - 48 + rcx*8]", "488b44cdd0"},
+		{"mov", "[rbp - 48 + r9*8], rdx", "4a8954cdd0"},
+
+		// Imediatos: a menor forma que guarda o valor.
+		{"mov", "eax, 5", "b805000000"},
+		{"mov", "rax, 5", "b805000000"},
+		{"mov", "rax, -1", "48c7c0ffffffff"},
+		{"mov", "rax, 81985529216486895", "48b8efcdab8967452301"},
+		{"mov", "qword [rbp - 8], 7", "48c745f807000000"},
+		{"add", "rax, 1", "4883c001"},
+		{"and", "rsp, -16", "4883e4f0"},
+		{"sub", "rsp, 256", "4881ec00010000"},
+		{"imul", "rax, 10", "486bc00a"},
+		{"imul", "rax, 1000", "4869c0e8030000"},
+		{"shl", "rax, 1", "48d1e0"},
+		{"sar", "rax, 3", "48c1f803"},
+
+		// Um operando (extensão /n do opcode) e sem operandos.
+		{"idiv", "rcx", "48f7f9"},
+		{"neg", "rax", "48f7d8"},
+		{"push", "r12", "4154"},
+		{"pop", "rbp", "5d"},
+		{"cqo", "", "4899"},
+		{"ret", "", "c3"},
+		{"syscall", "", "0f05"},
+
+		// SSE2: prefixo obrigatório antes do REX.
+		{"movsd", "xmm0, [rbp - 8]", "f20f1045f8"},
+		{"movsd", "[rbp - 8], xmm1", "f20f114df8"},
+		{"addsd", "xmm0, xmm1", "f20f58c1"},
+		{"ucomisd", "xmm0, xmm1", "660f2ec1"},
+		{"cvtsi2sd", "xmm0, rax", "f2480f2ac0"},
+		{"cvtsd2si", "rax, xmm0", "f2480f2dc0"},
+		{"movq", "xmm0, rax", "66480f6ec0"},
+		{"movq", "rax, xmm0", "66480f7ec0"},
+	}
+	for _, tt := range tests {
+		in := &Instr{Op: tt.op}
+		if tt.args != "" {
+			in.Args = strings.Split(tt.args, ", ")
+		}
+		e := &encoder{labels: map[string]int{}}
+		if err := e.instr(in); err != nil {
+			t.Errorf("%s: %v", in, err)
+			continue
+		}
+		if got := hex.EncodeToString(e.code); got != tt.want {
+			t.Errorf("%s = %s, quer %s", in, got, tt.want)
+		}
+	}
+}
+
+// Um rótulo de dados vira [rip + 0] com a relocação PC32; o addend soma o
+// deslocamento ([vet_v + 16]) e desconta o imediato que vem depois dele.
+func TestEncodeRIPRelative(t *testing.T) {
+	tests := []struct {
+		op, args string
+		want     string
+		offset   uint64
+		addend   int64
+	}{
+		{"lea", "rdi, [fmt_0]", "488d3d00000000", 3, -4},
+		{"movsd", "xmm0, [fmt_0]", "f20f100500000000", 4, -4},
+		{"mov", "qword [fmt_0], 7", "48c7050000000007000000", 3, -8},
+		{"mov", "rax, [fmt_0 + 16]", "488b0500000000", 3, 12},
+	}
+	for _, tt := range tests {
+		in := &Instr{Op: tt.op, Args: strings.Split(tt.args, ", ")}
+		e := &encoder{labels: map[string]int{}}
+		if err := e.instr(in); err != nil {
+			t.Errorf("%s: %v", in, err)
+			continue
+		}
+		if got := hex.EncodeToString(e.code); got != tt.want {
+			t.Errorf("%s = %s, quer %s", in, got, tt.want)
+		}
+		want := elf.Reloc{Offset: tt.offset, Symbol: "fmt_0", Type: elf.R_X86_64_PC32, Addend: tt.addend}
+		if len(e.relocs) != 1 || e.relocs[0] != want {
+			t.Errorf("%s: relocações %+v, quer %+v", in, e.relocs, want)
+		}
+	}
+}
+
+// Saltos e chamadas: sempre rel32, com o destino preenchido no fim.
+func TestEncodeJumps(t *testing.T) {
+	tests := []struct {
+		op, args string
+		want     string
+	}{
+		{"jmp", ".fim", "e900000000"},
+		{"call", "sigma_exit", "e800000000"},
+		{"jne", ".fim", "0f8500000000"},
+		{"jl", ".fim", "0f8c00000000"},
+	}
+	for _, tt := range tests {
+		e := &encoder{labels: map[string]int{}, fn: "main"}
+		if err := e.instr(&Instr{Op: tt.op, Args: []string{tt.args}}); err != nil {
+			t.Errorf("%s %s: %v", tt.op, tt.args, err)
+			continue
+		}
+		if got := hex.EncodeToString(e.code); got != tt.want {
+			t.Errorf("%s %s = %s, quer %s", tt.op, tt.args, got, tt.want)
+		}
+		label := tt.args
+		if strings.HasPrefix(label, ".") {
+			label = "main" + label
+		}
+		if len(e.fixups) != 1 || e.fixups[0] != (fixup{at: len(e.code) - 4, label: label}) {
+			t.Errorf("%s %s: fixups %+v", tt.op, tt.args, e.fixups)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package codegen
+
+import (
+	"csigma/ir"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GAS: Sintaxe AT&T do GNU as, montada com 'gcc -c' (não precisa do NASM).
+// As instruções são as mesmas; muda a escrita:
+//
+//	mov rax, [rbp - 16]            => mov -16(%rbp), %rax     (fonte primeiro)
+//	mov qword [rbp - 8], 5         => movq $5, -8(%rbp)       (tamanho no mnemônico)
+//	mov rax, [rbp - 48 + rcx*8]    => mov -48(%rbp,%rcx,8), %rax
+//	lea rdi, [fmt_0]               => lea fmt_0(%rip), %rdi
+//	call printf                    => call printf@PLT         (PIE)
+type GAS struct{}
+
+func (GAS) Name() string      { return "gas" }
+func (GAS) Extension() string { return ".s" }
+
+func (GAS) AssembleCommand(src, obj string) []string {
+	return []string{"gcc", "-c", src, "-o", obj}
+}
+
+func (GAS) Generate(prog *ir.Program, opts Options) string {
+	l := generate(prog, opts)
+	var sb strings.Builder
+
+	// --- SEÇÃO DE DADOS (.data) ---
+	sb.WriteString("    .data\n")
+	var bss []*Data
+	for _, d := range l.data {
+		if d.Space > 0 {
+			bss = append(bss, d)
+			continue
+		}
+		line := fmt.Sprintf("%s: .asciz %s", d.Label, gasString(d.Text))
+		if d.Float != "" {
+			line = fmt.Sprintf("%s: .double %s", d.Label, d.Float)
+		}
+		if d.Comment != "" {
+			line = fmt.Sprintf("%-40s# %s", line, d.Comment)
+		}
+		sb.WriteString(line + "\n")
+	}
+
+	// --- SEÇÃO DE CÓDIGO (.text) ---
+	// Funções externas não precisam ser declaradas: o GNU as assume que todo
+	// símbolo indefinido vem de fora.
+	// --- SEÇÃO DE BUFFERS (.bss) ---
+	if len(bss) > 0 {
+		sb.WriteString("\n    .bss\n    .balign 8                           # Elementos de 8 bytes alinhados\n")
+		for _, d := range bss {
+			line := fmt.Sprintf("%s: .zero %d", d.Label, d.Space)
+			if d.Comment != "" {
+				line = fmt.Sprintf("%-40s# %s", line, d.Comment)
+			}
+			sb.WriteString(line + "\n")
+		}
+	}
+
+	sb.WriteString("\n    .text\n")
+	if opts.NoLibc {
+		sb.WriteString("    .globl _start                       # Ponto de entrada para o Linker (sem LibC)\n")
+	} else {
+		sb.WriteString("    .globl main                         # Ponto de entrada para o Linker\n")
+	}
+	fn := ""
+	for _, in := range l.text {
+		if in.Label != "" && !strings.HasPrefix(in.Label, ".") {
+			fn = in.Label
+		}
+		sb.WriteString(gasLine(in, fn) + "\n")
+	}
+	writeDebug(&sb, l.debug, "    .section %s,\"\",@progbits", ".byte",
+		map[int]string{4: ".long", 8: ".quad"}, debugRef)
+	// Pilha não executável (sem isso o linker emite um aviso).
+	sb.WriteString("\n    .section .note.GNU-stack,\"\",@progbits\n")
+	return sb.String()
+}
+
+// gasLine: Uma instrução em AT&T, com o comentário alinhado na coluna 40.
+func gasLine(in *Instr, fn string) string {
+	switch {
+	case in.Label != "":
+		return gasLabel(in.Label, fn) + ":"
+	case in.Op == "" && in.Comment == "":
+		return ""
+	case in.Op == "":
+		return "    # " + in.Comment
+	}
+
+	args := make([]string, len(in.Args))
+	sized, hasReg := false, false
+	for i, a := range in.Args {
+		// AT&T: fonte primeiro, destino por último.
+		j := len(in.Args) - 1 - i
+		switch {
+		case strings.HasPrefix(a, "qword "):
+			sized = true
+			args[j] = gasMem(strings.TrimPrefix(a, "qword "))
+		case strings.HasPrefix(a, "byte "):
+			args[j] = gasMem(strings.TrimPrefix(a, "byte "))
+		case strings.HasPrefix(a, "["):
+			args[j] = gasMem(a)
+		case isImm(a):
+			args[j] = "$" + a
+		case gasRegs[a]:
+			hasReg = true
+			args[j] = "%" + a
+		case strings.HasPrefix(in.Op, "j"):
+			args[j] = gasLabel(a, fn)
+		case libcFuncs[a]:
+			args[j] = a + "@PLT" // Função da LibC, pela PLT
+		default:
+			args[j] = a // Rotina do runtime (call sigma_scan)
+		}
+	}
+
+	op := in.Op
+	if alias, ok := gasOps[op]; ok {
+		op = alias
+	}
+	// movzx lê um byte: movzbl para destino de 32 bits, movzbq para 64.
+	if op == "movzx" {
+		op = "movzbq"
+		if strings.HasPrefix(in.Args[0], "e") || strings.HasSuffix(in.Args[0], "d") {
+			op = "movzbl"
+		}
+	}
+	// Sem registrador, o tamanho do acesso à memória vai no mnemônico.
+	if sized && !hasReg {
+		op += "q"
+	}
+	code := "    " + op
+	if len(args) > 0 {
+		code += " " + strings.Join(args, ", ")
+	}
+	if in.Comment == "" {
+		return code
+	}
+	if len(code) < 40 {
+		code += strings.Repeat(" ", 40-len(code))
+	} else {
+		code += " "
+	}
+	return code + "# " + in.Comment
+}
+
+// gasOps: Mnemônicos que mudam de nome na sintaxe AT&T.
+var gasOps = map[string]string{
+	"cqo":    "cqto",
+	"movsxd": "movslq",
+}
+
+var gasRegs = map[string]bool{}
+
+func init() {
+	for _, r := range []string{"ax", "bx", "cx", "dx", "si", "di", "bp", "sp"} {
+		gasRegs["r"+r], gasRegs["e"+r] = true, true
+	}
+	for _, r := range []string{"al", "bl", "cl", "dl"} {
+		gasRegs[r] = true
+	}
+	for i := 8; i <= 15; i++ {
+		gasRegs["r"+strconv.Itoa(i)], gasRegs["r"+strconv.Itoa(i)+"d"] = true, true
+	}
+	for i := 0; i <= 15; i++ {
+		gasRegs["xmm"+strconv.Itoa(i)] = true
+	}
+}
+
+// gasLabel: Rótulos locais do NASM (.entry) pertencem ao último rótulo global
+// (main.entry). No GNU as eles viram símbolos locais do arquivo (.Lmain_entry).
+func gasLabel(label, fn string) string {
+	if !strings.HasPrefix(label, ".") {
+		return label
+	}
+	return ".L" + fn + "_" + label[1:]
+}
+
+// gasMem: [base - desloc + indice*escala] vira desloc(%base,%indice,escala).
+// Um rótulo sem registrador é relativo ao RIP: [fmt_0] vira fmt_0(%rip).
+func gasMem(operand string) string {
+	inner := strings.Trim(operand, "[]")
+	inner = strings.ReplaceAll(inner, " - ", " + -")
+	disp, base, index, label := 0, "", "", ""
+	for _, term := range strings.Split(inner, " + ") {
+		switch {
+		case strings.Contains(term, "*"):
+			index = "%" + strings.Replace(term, "*", ",", 1)
+		case isImm(term):
+			n, _ := strconv.Atoi(term)
+			disp += n
+		case gasRegs[term]:
+			base = "%" + term
+		default:
+			label = term
+		}
+	}
+
+	d := ""
+	switch {
+	case label != "" && disp != 0:
+		d = fmt.Sprintf("%s%+d", label, disp)
+	case label != "":
+		d = label
+	case disp != 0:
+		d = strconv.Itoa(disp)
+	}
+	switch {
+	case index != "":
+		return fmt.Sprintf("%s(%s,%s)", d, base, index)
+	case base != "":
+		return fmt.Sprintf("%s(%s)", d, base)
+	}
+	return d + "(%rip)"
+}
+
+// gasString: Um texto entre aspas para o .asciz (que já acrescenta o zero).
+// Aspas, barras e bytes fora do ASCII visível viram sequências de escape.
+func gasString(text string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for i := 0; i < len(text); i++ {
+		ch := text[i]
+		switch {
+		case ch == '"' || ch == '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(ch)
+		case ch == '\n':
+			sb.WriteString("\\n")
+		case ch < 32 || ch > 126:
+			sb.WriteString(fmt.Sprintf("\\%03o", ch))
+		default:
+			sb.WriteByte(ch)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
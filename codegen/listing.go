@@ -0,0 +1,123 @@
+package codegen
+
+import (
+	"csigma/ir"
+	"fmt"
+	"strings"
+)
+
+// Listagem fonte/assembly (--emit=listing): cada linha do fonte Sigma seguida
+// das instruções geradas para ela, na sintaxe do backend. As linhas vêm das
+// mesmas marcas da informação de depuração (debug.go). Com o montador interno
+// (--backend=elf), cada instrução mostra também o endereço no .text e os
+// bytes do código de máquina.
+//
+//	  18 | res = a + b * 2 / c
+//	     | 0000a2  48 8b 45 f0              mov rax, [rbp - 16]
+
+// listingBytes: Bytes de código de máquina por linha da listagem.
+const listingBytes = 8
+
+// Listing: A listagem do programa (sem as rotinas do runtime, que não têm
+// linha no fonte). source é o texto do arquivo .sig. opts são as mesmas do
+// arquivo gerado, para a listagem mostrar o código que é montado (com -g, o
+// peephole guarda as gravações que o depurador lê).
+func Listing(b Backend, prog *ir.Program, opts Options, source string) (string, error) {
+	opts.listing = true
+	l := generate(prog, opts)
+	var code []byte
+	if _, ok := b.(Assembler); ok {
+		obj, err := assemble(l)
+		if err != nil {
+			return "", err
+		}
+		code = obj.Text
+	}
+	_, gas := b.(GAS)
+	format := func(in *Instr, fn string) string {
+		if gas {
+			return gasLine(in, fn)
+		}
+		return nasmLine(in)
+	}
+	funcs := map[string]bool{}
+	for _, fn := range prog.Funcs {
+		funcs[fn.Name] = true
+	}
+
+	lines := strings.Split(strings.TrimRight(source, "\n"), "\n")
+	marked := map[int]bool{}
+	for _, in := range l.text {
+		marked[in.Line] = true
+	}
+	var sb strings.Builder
+	printed := 0 // Última linha do fonte já mostrada (ou pulada)
+	showLine := func(n int) {
+		sb.WriteString(fmt.Sprintf("%4d | %s\n", n, strings.TrimRight(lines[n-1], "\r")))
+	}
+	fn, pc := "", 0
+	for _, in := range l.text {
+		if in.Label != "" && !strings.HasPrefix(in.Label, ".") {
+			if !funcs[in.Label] {
+				break // Começou o runtime
+			}
+			fn = in.Label
+		}
+		switch {
+		case in.Line > 0 && in.Line <= len(lines):
+			// O código nem sempre segue a ordem do fonte (as variáveis são
+			// iniciadas no prólogo): as linhas puladas que têm código aparecem
+			// depois, junto dele.
+			for printed < in.Line-1 {
+				printed++
+				if !marked[printed] {
+					showLine(printed)
+				}
+			}
+			showLine(in.Line)
+			if in.Line > printed {
+				printed = in.Line
+			}
+			continue
+		case in.Op == "" && in.Label == "":
+			continue // Comentários e linhas em branco: o fonte já diz o comando
+		case strings.HasPrefix(in.Label, ".dbg_"):
+			continue // Fim do 'main' para o depurador (-g)
+		}
+
+		text := format(in, fn)
+		if code == nil {
+			sb.WriteString("     | " + text + "\n")
+			continue
+		}
+		span, ok := l.spans[in]
+		if !ok {
+			span = [2]int{pc, pc} // Rótulo: o endereço da instrução seguinte
+		}
+		pc = span[1]
+		bytes := code[span[0]:span[1]]
+		for first := true; first || len(bytes) > 0; first = false {
+			n := len(bytes)
+			if n > listingBytes {
+				n = listingBytes
+			}
+			hex := make([]string, n)
+			for i, c := range bytes[:n] {
+				hex[i] = fmt.Sprintf("%02x", c)
+			}
+			if first {
+				sb.WriteString(fmt.Sprintf("     | %06x  %-24s%s\n", span[0], strings.Join(hex, " "), text))
+			} else {
+				sb.WriteString(fmt.Sprintf("     |         %s\n", strings.Join(hex, " ")))
+			}
+			bytes = bytes[n:]
+		}
+	}
+	for printed < len(lines) {
+		printed++
+		if !marked[printed] {
+			showLine(printed)
+		}
+	}
+	return sb.String(), nil
+}
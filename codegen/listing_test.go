@@ -0,0 +1,53 @@
+package codegen
+
+import (
+	"csigma/ir"
+	"csigma/lexer"
+	"csigma/parser"
+	"csigma/semantic"
+	"strings"
+	"testing"
+)
+
+// A listagem mostra o código do arquivo gerado, linha por linha e na mesma
+// ordem: com -g, as gravações que o depurador lê; no NASM, o 'wrt ..plt'.
+func TestListingMatchesOutput(t *testing.T) {
+	src := "var a = 1\nvar b = 2\nvar c = 0\nvar d = 7\nc = a + b\nd = c * 2\nprint \"{c}\"\n"
+	statements, err := parser.NewParser(lexer.Tokenize(src)).ParseProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+	analyzer := semantic.NewAnalyzer()
+	analyzer.Validar(statements)
+	if len(analyzer.Erros) > 0 {
+		t.Fatal(analyzer.Erros)
+	}
+
+	for _, b := range []Backend{NASM{}, GAS{}} {
+		for _, debug := range []bool{false, true} {
+			prog := ir.Lower(statements, ir.Options{})
+			opts := Options{RegAlloc: true, Peephole: true, Debug: debug, Source: "teste.sig"}
+			out := strings.Split(b.Generate(prog, opts), "\n")
+			listing, err := Listing(b, prog, opts, src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pos := 0
+			for _, line := range strings.Split(listing, "\n") {
+				text := strings.TrimPrefix(line, "     | ")
+				if text == line || text == "" {
+					continue // Linha do fonte
+				}
+				for pos < len(out) && out[pos] != text {
+					pos++
+				}
+				if pos == len(out) {
+					t.Fatalf("%s (-g=%v): %q não está no arquivo gerado (ou está fora de ordem)", b.Name(), debug, text)
+				}
+			}
+			if _, ok := b.(NASM); ok && !strings.Contains(listing, "call printf wrt ..plt") {
+				t.Errorf("nasm (-g=%v): listagem sem 'call printf wrt ..plt':\n%s", debug, listing)
+			}
+		}
+	}
+}
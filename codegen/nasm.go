@@ -0,0 +1,118 @@
+package codegen
+
+import (
+	"csigma/ir"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NASM: Sintaxe Intel, montada com 'nasm -f elf64'. É a sintaxe das próprias
+// instruções (Instr.String), então basta escrevê-las. O código é independente
+// de posição (PIE): 'default rel' faz [fmt_0] ser relativo ao RIP, e as
+// funções da LibC são chamadas pela PLT (call printf wrt ..plt).
+type NASM struct{}
+
+func (NASM) Name() string      { return "nasm" }
+func (NASM) Extension() string { return ".asm" }
+
+func (NASM) AssembleCommand(src, obj string) []string {
+	return []string{"nasm", "-f", "elf64", src, "-o", obj}
+}
+
+func (NASM) Generate(prog *ir.Program, opts Options) string {
+	l := generate(prog, opts)
+	var sb strings.Builder
+
+	// --- SEÇÃO DE DADOS (.data) ---
+	// Reservada para constantes: textos, formatos de E/S e literais decimais.
+	// As variáveis simples do Sigma moram na pilha do 'main'.
+	sb.WriteString("section .data\n")
+	var bss []*Data
+	for _, d := range l.data {
+		if d.Space > 0 {
+			bss = append(bss, d)
+			continue
+		}
+		// db = Define Byte (textos); dq = Define Quadword (decimais de 64 bits).
+		line := fmt.Sprintf("    %s db %s", d.Label, nasmBytes(d.Text))
+		if d.Float != "" {
+			line = fmt.Sprintf("    %s dq %s", d.Label, d.Float)
+		}
+		if d.Comment != "" {
+			line = fmt.Sprintf("%-40s; %s", line, d.Comment)
+		}
+		sb.WriteString(line + "\n")
+	}
+
+	// --- SEÇÃO DE BUFFERS (.bss) ---
+	// Vetores e buffers do runtime sem LibC: resb = Reserve Bytes (zerados ao carregar).
+	if len(bss) > 0 {
+		sb.WriteString("\nsection .bss align=8                    ; Elementos de 8 bytes alinhados\n")
+		for _, d := range bss {
+			line := fmt.Sprintf("    %s resb %d", d.Label, d.Space)
+			if d.Comment != "" {
+				line = fmt.Sprintf("%-40s; %s", line, d.Comment)
+			}
+			sb.WriteString(line + "\n")
+		}
+	}
+
+	// --- SEÇÃO DE CÓDIGO (.text) ---
+	sb.WriteString("\nsection .text\n")
+	sb.WriteString("default rel                             ; [rotulo] relativo ao RIP (PIE)\n")
+	if opts.NoLibc {
+		sb.WriteString("global _start                           ; Ponto de entrada para o Linker (sem LibC)\n")
+	} else {
+		sb.WriteString("extern printf, scanf, fflush, getchar   ; Declara funções da LibC\n")
+		sb.WriteString("extern dprintf, exit\n")
+		sb.WriteString("global main                             ; Ponto de entrada para o Linker\n")
+	}
+	for _, in := range l.text {
+		sb.WriteString(nasmLine(in) + "\n")
+	}
+
+	// --- INFORMAÇÃO DE DEPURAÇÃO (-g) ---
+	// Seções que não são carregadas na memória: só o gdb as lê do arquivo.
+	writeDebug(&sb, l.debug, "section %s noalloc noexec nowrite progbits align=1", "db",
+		map[int]string{4: "dd", 8: "dq"}, func(s string) string { return s })
+	// Pilha não executável (sem isso o linker emite um aviso).
+	sb.WriteString("\nsection .note.GNU-stack noalloc noexec nowrite progbits\n")
+	return sb.String()
+}
+
+// nasmLine: Uma instrução em Intel, com as funções da LibC chamadas pela PLT.
+func nasmLine(in *Instr) string {
+	if in.Op == "call" && libcFuncs[in.Args[0]] {
+		call := *in
+		call.Args = []string{in.Args[0] + " wrt ..plt"}
+		in = &call
+	}
+	return in.String()
+}
+
+// nasmBytes: Converte um texto em operandos de 'db', terminados em zero (Padrão C).
+// Aspas simples e caracteres de controle (como o '\n') não podem ficar entre
+// '...' no NASM, então viram o código numérico do byte (39, 10...).
+func nasmBytes(text string) string {
+	var out []string
+	var chunk strings.Builder
+	flush := func() {
+		if chunk.Len() > 0 {
+			out = append(out, "'"+chunk.String()+"'")
+			chunk.Reset()
+		}
+	}
+	for i := 0; i < len(text); i++ {
+		ch := text[i]
+		if ch == '\'' || ch < 32 {
+			flush()
+			out = append(out, strconv.Itoa(int(ch)))
+			continue
+		}
+		chunk.WriteByte(ch)
+	}
+	flush()
+	out = append(out, "0")
+	return strings.Join(out, ", ")
+}
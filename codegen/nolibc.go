@@ -0,0 +1,868 @@
+package codegen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Runtime sem LibC (--nolibc): as poucas funções da LibC que o código gerado
+// usa são substituídas por rotinas próprias sobre as chamadas de sistema do
+// Linux (read, write, exit_group). O executável fica estático e pequeno, e é
+// ligado com 'ld' puro, a partir do ponto de entrada _start.
+//
+//	printf  => sigma_printf     getchar => sigma_getchar
+//	dprintf => sigma_dprintf    scanf   => sigma_scanf   (só %ld e %lf)
+//	fflush  => sigma_flush      exit    => sigma_exit
+//
+// A saída padrão passa por um buffer (como no printf): ele é esvaziado antes
+// de cada leitura e na saída do programa. As mensagens de erro (stderr) são
+// escritas na hora.
+var noLibcNames = map[string]string{
+	"printf": "sigma_printf", "dprintf": "sigma_dprintf", "scanf": "sigma_scanf",
+	"getchar": "sigma_getchar", "fflush": "sigma_flush", "exit": "sigma_exit",
+}
+
+// libc: O nome da função da LibC, ou da rotina que a substitui com --nolibc.
+func (g *generator) libc(name string) string {
+	if g.opts.NoLibc {
+		if own, ok := noLibcNames[name]; ok {
+			return own
+		}
+	}
+	return name
+}
+
+// Tamanho dos buffers de entrada e de saída.
+const ioBufferSize = 4096
+
+// genNoLibc: Acrescenta o runtime sem LibC (e os seus dados) ao programa.
+func (g *generator) genNoLibc() {
+	g.data = append(g.data,
+		&Data{Label: "sigma_str_inf", Text: "inf"},
+		&Data{Label: "sigma_str_nan", Text: "nan"},
+	)
+	// Potências 10^1, 10^2, 10^4 ... 10^256, em sequência a partir de
+	// sigma_pow10_0: sigma_pow10 as combina pelos bits do expoente.
+	for i, p := range []string{"10.0", "100.0", "10000.0", "100000000.0", "1e16", "1e32", "1e64", "1e128", "1e256"} {
+		g.data = append(g.data, &Data{Label: fmt.Sprintf("sigma_pow10_%d", i), Float: p})
+	}
+	g.data = append(g.data,
+		&Data{Label: "sigma_out_buf", Space: ioBufferSize, Comment: "Buffer da saida padrao"},
+		&Data{Label: "sigma_out_len", Space: 8},
+		&Data{Label: "sigma_in_buf", Space: ioBufferSize, Comment: "Buffer da entrada padrao"},
+		&Data{Label: "sigma_in_pos", Space: 8},
+		&Data{Label: "sigma_in_len", Space: 8},
+	)
+	g.text = append(g.text, parseAsm(strings.ReplaceAll(noLibcRuntime, "BUFSIZE", strconv.Itoa(ioBufferSize)))...)
+}
+
+// parseAsm: Converte um trecho de Assembly escrito à mão (sintaxe do NASM) em
+// instruções, para que todos os backends (e o montador interno) o aceitem.
+func parseAsm(src string) []*Instr {
+	var text []*Instr
+	for _, line := range strings.Split(strings.Trim(src, "\n"), "\n") {
+		code, comment := line, ""
+		if i := strings.Index(line, ";"); i >= 0 {
+			code, comment = line[:i], strings.TrimSpace(line[i+1:])
+		}
+		code = strings.TrimSpace(code)
+		switch {
+		case strings.HasSuffix(code, ":"):
+			text = append(text, &Instr{Label: strings.TrimSuffix(code, ":")})
+		case code == "":
+			text = append(text, &Instr{Comment: comment})
+		default:
+			in := &Instr{Op: code, Comment: comment}
+			if i := strings.Index(code, " "); i >= 0 {
+				in.Op = code[:i]
+				in.Args = strings.Split(strings.TrimSpace(code[i+1:]), ", ")
+			}
+			text = append(text, in)
+		}
+	}
+	return text
+}
+
+// noLibcRuntime: As rotinas, seguindo a convenção System V (argumentos em
+// RDI, RSI, RDX..., decimais em XMM0..., RBX e R12-R15 preservados).
+const noLibcRuntime = `
+
+; --- Runtime Sigma sem LibC ---
+_start:
+    xor ebp, ebp                        ; Marca o fim da cadeia de molduras
+    and rsp, -16                        ; Alinha a pilha em 16 bytes
+    call main
+    mov edi, eax                        ; Codigo de saida = retorno do main
+    call sigma_exit
+
+; sigma_exit(codigo): esvazia a saida padrao e encerra o processo.
+sigma_exit:
+    push rdi
+    call sigma_flush
+    pop rdi
+    mov eax, 231                        ; exit_group
+    syscall
+
+; sigma_flush(): grava o buffer da saida padrao (fflush).
+sigma_flush:
+    mov rdx, [sigma_out_len]
+    cmp rdx, 0
+    je .vazio
+    sub rsp, 8                          ; Alinha a pilha em 16 bytes
+    mov edi, 1
+    lea rsi, [sigma_out_buf]
+    call sigma_write
+    add rsp, 8
+    mov qword [sigma_out_len], 0
+.vazio:
+    xor eax, eax
+    ret
+
+; sigma_write(fd, endereco, tamanho): write em laco ate gravar tudo.
+sigma_write:
+    cmp rdx, 0
+    jle .fim
+    mov eax, 1                          ; write
+    syscall
+    cmp rax, 0
+    jle .fim                            ; Erro: desiste
+    add rsi, rax
+    sub rdx, rax
+    jmp sigma_write
+.fim:
+    ret
+
+; sigma_putc(c): acrescenta um byte ao buffer da saida padrao.
+sigma_putc:
+    mov rax, [sigma_out_len]
+    cmp rax, BUFSIZE
+    jb .guarda
+    push rdi
+    call sigma_flush                    ; Buffer cheio
+    pop rdi
+    xor eax, eax
+.guarda:
+    lea rcx, [sigma_out_buf]
+    mov edx, edi
+    mov byte [rcx + rax*1], dl
+    add rax, 1
+    mov [sigma_out_len], rax
+    ret
+
+; sigma_printf(formato, ...): printf com %ld, %g, %s e %%. Os argumentos
+; ficam lado a lado na pilha para sigma_format percorre-los em ordem. Os
+; que nao couberam nos registradores ja estao na pilha, em [rbp + 16].
+sigma_printf:
+    push rbp
+    mov rbp, rsp
+    sub rsp, 112
+    mov [rbp - 40], rsi                 ; Inteiros e ponteiros: [rbp - 40] a [rbp - 8]
+    mov [rbp - 32], rdx
+    mov [rbp - 24], rcx
+    mov [rbp - 16], r8
+    mov [rbp - 8], r9
+    lea rdx, [rbp - 40]
+    mov rsi, rdi                        ; Formato
+    mov edi, 1                          ; stdout
+    jmp sigma_printf_xmm
+
+; sigma_dprintf(fd, formato, ...): como o printf, no descritor fd.
+sigma_dprintf:
+    push rbp
+    mov rbp, rsp
+    sub rsp, 112
+    mov [rbp - 32], rdx
+    mov [rbp - 24], rcx
+    mov [rbp - 16], r8
+    mov [rbp - 8], r9
+    lea rdx, [rbp - 32]                 ; RDI ja e o fd
+sigma_printf_xmm:
+    movsd [rbp - 104], xmm0             ; Decimais: [rbp - 104] a [rbp - 48]
+    movsd [rbp - 96], xmm1
+    movsd [rbp - 88], xmm2
+    movsd [rbp - 80], xmm3
+    movsd [rbp - 72], xmm4
+    movsd [rbp - 64], xmm5
+    movsd [rbp - 56], xmm6
+    movsd [rbp - 48], xmm7
+    lea rax, [rbp + 16]
+    mov [rbp - 112], rax                ; Proximo argumento da pilha
+    lea rcx, [rbp - 104]
+    call sigma_format
+    mov rsp, rbp
+    pop rbp
+    ret
+
+; sigma_format(fd, formato, inteiros, decimais): percorre o formato e
+; escreve no buffer. Fora da saida padrao, o trecho escrito e gravado
+; em seguida no fd e retirado do buffer. RBP e a moldura do sigma_printf:
+; os inteiros terminam em [rbp], os decimais em [rbp - 40], e depois deles
+; os argumentos seguem em [rbp - 112], que aponta para a pilha.
+sigma_format:
+    push rbx
+    push r12
+    push r13
+    push r14
+    push r15
+    mov rbx, rsi                        ; RBX = Proximo caractere do formato
+    mov r12, rdx                        ; R12 = Proximo inteiro
+    mov r13, rcx                        ; R13 = Proximo decimal
+    mov r14, rdi                        ; R14 = fd
+    cmp r14, 1
+    je .inicio
+    mov rax, [sigma_out_len]
+    cmp rax, 2048
+    jb .inicio
+    call sigma_flush                    ; Garante espaco para a mensagem
+.inicio:
+    mov r15, [sigma_out_len]            ; R15 = Inicio do trecho escrito
+.laco:
+    movzx eax, byte [rbx]
+    add rbx, 1
+    cmp eax, 0
+    je .fim
+    cmp eax, 37                         ; '%'
+    je .conversao
+    mov edi, eax
+    call sigma_putc
+    jmp .laco
+.conversao:
+    movzx eax, byte [rbx]
+    add rbx, 1
+    cmp eax, 115                        ; 's'
+    je .texto
+    cmp eax, 103                        ; 'g'
+    je .decimal
+    cmp eax, 108                        ; 'l' de %ld
+    je .inteiro
+    mov edi, eax                        ; '%%'
+    call sigma_putc
+    jmp .laco
+.inteiro:
+    add rbx, 1                          ; Pula o 'd'
+.texto:
+    mov rdx, r12
+    add r12, 8
+    cmp rdx, rbp
+    jb .le_inteiro
+    mov rdx, [rbp - 112]                ; Acabaram os registradores
+    add qword [rbp - 112], 8
+.le_inteiro:
+    mov rdi, [rdx]
+    movzx eax, byte [rbx - 1]
+    cmp eax, 115                        ; 's'
+    je .escreve_texto
+    call sigma_put_int
+    jmp .laco
+.escreve_texto:
+    call sigma_put_str
+    jmp .laco
+.decimal:
+    mov rdx, r13
+    add r13, 8
+    lea rax, [rbp - 40]
+    cmp rdx, rax
+    jb .le_decimal
+    mov rdx, [rbp - 112]                ; Acabaram os registradores
+    add qword [rbp - 112], 8
+.le_decimal:
+    movsd xmm0, [rdx]
+    call sigma_put_float
+    jmp .laco
+.fim:
+    cmp r14, 1
+    je .saida
+    mov rdi, r14
+    lea rsi, [sigma_out_buf]
+    add rsi, r15
+    mov rdx, [sigma_out_len]
+    sub rdx, r15
+    call sigma_write
+    mov [sigma_out_len], r15
+.saida:
+    pop r15
+    pop r14
+    pop r13
+    pop r12
+    pop rbx
+    ret
+
+; sigma_put_str(texto): copia um texto terminado em zero.
+sigma_put_str:
+    push rbx
+    mov rbx, rdi
+.laco:
+    movzx edi, byte [rbx]
+    cmp edi, 0
+    je .fim
+    call sigma_putc
+    add rbx, 1
+    jmp .laco
+.fim:
+    pop rbx
+    ret
+
+; sigma_put_int(n): %ld. Os digitos saem do menos significativo para o
+; mais, em um buffer na pilha, e sao escritos de tras para a frente.
+sigma_put_int:
+    push rbx
+    push r12
+    sub rsp, 40
+    mov rbx, rdi
+    cmp rbx, 0
+    jge .positivo
+    mov edi, 45                         ; '-'
+    call sigma_putc
+    neg rbx                             ; Sem sinal, -INT64_MIN tambem serve
+.positivo:
+    xor r12, r12                        ; R12 = Quantidade de digitos
+    mov rax, rbx
+    mov ecx, 10
+.digito:
+    xor edx, edx
+    div rcx                             ; Divisao sem sinal
+    add edx, 48
+    mov byte [rsp + r12*1], dl
+    add r12, 1
+    cmp rax, 0
+    jne .digito
+.escreve:
+    sub r12, 1
+    movzx edi, byte [rsp + r12*1]
+    call sigma_putc
+    cmp r12, 0
+    jne .escreve
+    add rsp, 40
+    pop r12
+    pop rbx
+    ret
+
+; sigma_put_float(x): %g (6 algarismos significativos, sem zeros a direita;
+; notacao cientifica se o expoente for menor que -4 ou maior que 5).
+; X = expoente decimal; M = round(|x| * 10^(5 - X)) tem 6 digitos.
+sigma_put_float:
+    push rbx
+    push r12
+    push r13
+    sub rsp, 32                         ; [rsp] = |x|, [rsp + 8] = digitos, [rsp + 16] = 10^(5 - X)
+    movq rbx, xmm0
+    cmp rbx, 0
+    jge .positivo
+    mov edi, 45                         ; '-' (inclusive -0 e -nan)
+    call sigma_putc
+    shl rbx, 1                          ; Apaga o bit de sinal
+    shr rbx, 1
+.positivo:
+    mov rax, 9218868437227405312        ; Bits de +inf
+    cmp rbx, rax
+    jae .especial
+    cmp rbx, 0
+    je .zero
+    mov [rsp], rbx
+    mov rax, rbx                        ; Estimativa: X = (expoente binario) * log10(2)
+    shr rax, 52
+    sub rax, 1023
+    imul rax, 78913                     ; 78913 / 2^18 = 0.30103
+    sar rax, 18
+    mov r12, rax
+.sobe:
+    lea rdi, [r12 + 1]                  ; Enquanto |x| >= 10^(X+1): X++
+    call sigma_pow10
+    movsd xmm1, [rsp]
+    ucomisd xmm1, xmm0
+    jb .desce
+    add r12, 1
+    jmp .sobe
+.desce:
+    mov rdi, r12                        ; Enquanto |x| < 10^X: X--
+    call sigma_pow10
+    movsd xmm1, [rsp]
+    ucomisd xmm1, xmm0
+    jae .escala
+    sub r12, 1
+    jmp .desce
+.escala:
+    mov rdi, 5
+    sub rdi, r12
+    cmp rdi, 0
+    jl .divide
+    cmp rdi, 300
+    jle .multiplica
+    lea r13, [rdi - 300]                ; Perto de 1e-308, 10^(5 - X) estoura:
+    mov rdi, 300                        ; multiplica antes por 10^300
+    call sigma_pow10
+    mulsd xmm0, [rsp]
+    movsd [rsp], xmm0
+    mov rdi, r13
+.multiplica:
+    call sigma_pow10
+    movsd [rsp + 16], xmm0
+    mulsd xmm0, [rsp]
+    cvtsd2si rbx, xmm0                  ; Arredonda para o par mais proximo
+    cvtsi2sd xmm1, rbx
+    subsd xmm1, xmm0                    ; M - produto
+    mov rax, 4602678819172646912        ; Bits de 0.5
+    movq xmm2, rax
+    ucomisd xmm1, xmm2
+    je .empate_acima
+    movsd xmm3, xmm0
+    cvtsi2sd xmm1, rbx
+    subsd xmm3, xmm1                    ; produto - M
+    ucomisd xmm3, xmm2
+    jne .confere
+    jmp .empate
+.empate_acima:
+    sub rbx, 1                          ; RBX = Parte inteira do produto
+.empate:
+    ; O produto caiu exatamente em d.5, mas o valor exato |x| * 10^(5 - X)
+    ; pode estar um pouco acima ou abaixo: o erro do produto e calculado
+    ; sem arredondamento (Dekker), partindo os fatores em metades de 26 bits.
+    mov rax, 4728779608772575232        ; Bits de 2^27 + 1
+    movq xmm6, rax
+    movsd xmm2, [rsp]                   ; a = |x|
+    movsd xmm3, xmm2
+    mulsd xmm3, xmm6
+    movsd xmm4, xmm3
+    subsd xmm4, xmm2
+    subsd xmm3, xmm4                    ; Metade alta de a
+    subsd xmm2, xmm3                    ; Metade baixa de a
+    movsd xmm4, [rsp + 16]              ; b = 10^(5 - X)
+    movsd xmm5, xmm4
+    mulsd xmm5, xmm6
+    movsd xmm7, xmm5
+    subsd xmm7, xmm4
+    subsd xmm5, xmm7                    ; Metade alta de b
+    subsd xmm4, xmm5                    ; Metade baixa de b
+    movsd xmm6, xmm3
+    mulsd xmm6, xmm5
+    subsd xmm6, xmm0
+    movsd xmm7, xmm3
+    mulsd xmm7, xmm4
+    addsd xmm6, xmm7
+    movsd xmm7, xmm2
+    mulsd xmm7, xmm5
+    addsd xmm6, xmm7
+    mulsd xmm2, xmm4
+    addsd xmm6, xmm2                    ; Erro = a * b - produto
+    xor eax, eax
+    movq xmm7, rax
+    ucomisd xmm6, xmm7
+    jb .confere                         ; Abaixo do empate: fica a parte inteira
+    ja .empate_sobe
+    mov rax, rbx                        ; Empate de verdade: fica o par
+    and rax, 1
+    cmp rax, 0
+    je .confere
+.empate_sobe:
+    add rbx, 1
+    jmp .confere
+.divide:
+    neg rdi                             ; Divide por 10^(X-5): exato quando possivel
+    call sigma_pow10
+    movsd xmm1, [rsp]
+    divsd xmm1, xmm0
+    movsd xmm0, xmm1
+    cvtsd2si rbx, xmm0                  ; Arredonda para o par mais proximo
+.confere:
+    cmp rbx, 1000000
+    jl .digitos
+    mov rbx, 100000                     ; 999999.5 virou 1000000
+    add r12, 1
+.digitos:
+    mov rax, rbx
+    mov ecx, 10
+    mov r13, 5
+.digito:
+    xor edx, edx
+    div rcx
+    add edx, 48
+    mov byte [rsp + r13*1 + 8], dl
+    sub r13, 1
+    cmp r13, 0
+    jge .digito
+    mov r13, 6                          ; R13 = Digitos significativos
+.apara:
+    cmp r13, 1
+    je .estilo
+    movzx eax, byte [rsp + r13*1 + 7]
+    cmp eax, 48
+    jne .estilo
+    sub r13, 1
+    jmp .apara
+.estilo:
+    cmp r12, -4
+    jl .cientifica
+    cmp r12, 6
+    jge .cientifica
+    cmp r12, 0
+    jl .pequeno
+    xor ebx, ebx                        ; Parte inteira: digitos 0..X
+.inteira:
+    cmp rbx, r12
+    jg .fracao
+    mov edi, 48
+    cmp rbx, r13
+    jge .zero_inteira
+    movzx edi, byte [rsp + rbx*1 + 8]
+.zero_inteira:
+    call sigma_putc
+    add rbx, 1
+    jmp .inteira
+.fracao:
+    cmp rbx, r13
+    jge .fim
+    mov edi, 46                         ; '.'
+    call sigma_putc
+.fracao_digito:
+    cmp rbx, r13
+    jge .fim
+    movzx edi, byte [rsp + rbx*1 + 8]
+    call sigma_putc
+    add rbx, 1
+    jmp .fracao_digito
+.pequeno:
+    mov edi, 48                         ; 0.000ddd
+    call sigma_putc
+    mov edi, 46
+    call sigma_putc
+    mov rbx, r12
+.zeros:
+    add rbx, 1
+    cmp rbx, 0
+    jge .pequeno_digitos
+    mov edi, 48
+    call sigma_putc
+    jmp .zeros
+.pequeno_digitos:
+    xor ebx, ebx
+    jmp .fracao_digito
+.cientifica:
+    movzx edi, byte [rsp + 8]           ; d.ddddde+XX
+    call sigma_putc
+    mov ebx, 1
+    cmp r13, 1
+    je .expoente
+    mov edi, 46
+    call sigma_putc
+.cientifica_digito:
+    cmp rbx, r13
+    jge .expoente
+    movzx edi, byte [rsp + rbx*1 + 8]
+    call sigma_putc
+    add rbx, 1
+    jmp .cientifica_digito
+.expoente:
+    mov edi, 101                        ; 'e'
+    call sigma_putc
+    mov edi, 43                         ; '+'
+    cmp r12, 0
+    jge .sinal
+    mov edi, 45                         ; '-'
+    neg r12
+.sinal:
+    call sigma_putc
+    cmp r12, 10
+    jge .expoente_digitos
+    mov edi, 48                         ; Ao menos dois digitos
+    call sigma_putc
+.expoente_digitos:
+    mov rdi, r12
+    call sigma_put_int
+    jmp .fim
+.zero:
+    mov edi, 48
+    call sigma_putc
+    jmp .fim
+.especial:
+    lea rdi, [sigma_str_inf]
+    mov rax, 9218868437227405312
+    cmp rbx, rax
+    je .especial_texto
+    lea rdi, [sigma_str_nan]
+.especial_texto:
+    call sigma_put_str
+.fim:
+    add rsp, 32
+    pop r13
+    pop r12
+    pop rbx
+    ret
+
+; sigma_pow10(n): 10^n em XMM0, pelo produto das potencias da tabela
+; (10^1, 10^2, 10^4...) escolhidas pelos bits de |n|. Expoentes negativos
+; dividem 1 pelo resultado.
+sigma_pow10:
+    mov rax, rdi
+    cmp rax, 0
+    jge .positivo
+    neg rax
+    cmp rax, 300
+    jle .positivo
+    sub rax, 300                        ; 10^-n = 10^-300 / 10^(n - 300): o 10^n estouraria
+.positivo:
+    cmp rax, 400
+    jl .limite
+    mov rax, 400                        ; 10^400 ja e infinito
+.limite:
+    mov rcx, 4607182418800017408        ; Bits de 1.0
+    movq xmm0, rcx
+    lea rdx, [sigma_pow10_0]
+.bit:
+    cmp rax, 0
+    je .sinal
+    mov rcx, rax
+    and rcx, 1
+    cmp rcx, 0
+    je .proximo
+    mulsd xmm0, [rdx]
+.proximo:
+    shr rax, 1
+    add rdx, 8
+    jmp .bit
+.sinal:
+    cmp rdi, 0
+    jge .fim
+    mov rcx, 4607182418800017408
+    cmp rdi, -300
+    jge .inverte
+    mov rcx, 118622047889322841         ; Bits de 1e-300
+.inverte:
+    movq xmm1, rcx
+    divsd xmm1, xmm0
+    movsd xmm0, xmm1
+.fim:
+    ret
+
+; sigma_getchar(): proximo byte da entrada padrao, ou -1 no fim.
+sigma_getchar:
+    mov rax, [sigma_in_pos]
+    cmp rax, [sigma_in_len]
+    jb .tem
+    xor eax, eax                        ; read
+    xor edi, edi                        ; stdin
+    lea rsi, [sigma_in_buf]
+    mov edx, BUFSIZE
+    syscall
+    cmp rax, 0
+    jle .eof
+    mov [sigma_in_len], rax
+    xor eax, eax
+.tem:
+    lea rcx, [sigma_in_buf]
+    movzx edx, byte [rcx + rax*1]
+    add rax, 1
+    mov [sigma_in_pos], rax
+    mov eax, edx
+    ret
+.eof:
+    mov qword [sigma_in_pos], 0
+    mov qword [sigma_in_len], 0
+    mov eax, -1
+    ret
+
+; sigma_ungetc(c): devolve o ultimo byte lido (o scanf para antes dele).
+sigma_ungetc:
+    cmp edi, -1
+    je .fim
+    sub qword [sigma_in_pos], 1
+.fim:
+    ret
+
+; sigma_scanf(formato, endereco): scanf de um unico %ld ou %lf. Devolve 1
+; (lido), 0 (o texto nao comeca com um numero) ou -1 (fim da entrada).
+sigma_scanf:
+    push rbx
+    push r12
+    push r13
+    push r14
+    push r15
+    sub rsp, 16                         ; [rsp] e [rsp + 8]: variaveis locais
+    mov rbx, rsi                        ; RBX = Destino
+    movzx r12d, byte [rdi + 2]          ; R12 = 'd' ou 'f'
+.espacos:
+    call sigma_getchar
+    cmp eax, -1
+    je .eof
+    cmp eax, 32
+    je .espacos
+    cmp eax, 9
+    jb .sinal
+    cmp eax, 13                         ; \t \n \v \f \r
+    jbe .espacos
+.sinal:
+    xor r13, r13                        ; R13 = 1 se negativo
+    cmp eax, 45
+    jne .mais
+    mov r13, 1
+    jmp .depois_sinal
+.mais:
+    cmp eax, 43
+    jne .numero
+.depois_sinal:
+    call sigma_getchar
+.numero:
+    xor r14, r14                        ; R14 = Digitos (mantissa)
+    xor r15, r15                        ; R15 = Expoente decimal
+    mov qword [rsp + 8], 0              ; Algum digito lido?
+.inteira:
+    mov rcx, rax
+    sub rcx, 48
+    cmp rcx, 9
+    ja .fim_inteira
+    mov qword [rsp + 8], 1
+    cmp r12, 100
+    jne .limite_decimal
+    mov rdx, 922337203685477580         ; %ld: todos os digitos contam, ate o
+    cmp r14, rdx                        ; limite do long
+    jb .acumula
+    ja .estoura
+    cmp rcx, 8
+    jb .acumula
+.estoura:
+    mov r14, -9223372036854775808       ; Satura em 2^63 (como o strtol)
+    jmp .proximo_inteira
+.limite_decimal:
+    mov rdx, 100000000000000000
+    cmp r14, rdx
+    jb .acumula
+    add r15, 1                          ; %lf: digitos alem de 17 so escalam
+    jmp .proximo_inteira
+.acumula:
+    imul r14, 10
+    add r14, rcx
+.proximo_inteira:
+    call sigma_getchar
+    jmp .inteira
+.fim_inteira:
+    cmp r12, 100
+    je .guarda_inteiro
+    cmp eax, 46                         ; '.'
+    jne .expoente
+.fracao:
+    call sigma_getchar
+    mov rcx, rax
+    sub rcx, 48
+    cmp rcx, 9
+    ja .expoente
+    mov qword [rsp + 8], 1
+    mov rdx, 100000000000000000
+    cmp r14, rdx
+    jae .fracao
+    imul r14, 10
+    add r14, rcx
+    sub r15, 1
+    jmp .fracao
+.expoente:
+    cmp qword [rsp + 8], 0
+    je .invalido
+    mov ecx, eax
+    or ecx, 32                          ; 'E' -> 'e'
+    cmp ecx, 101
+    jne .devolve_decimal
+    mov qword [rsp], 0                  ; Sinal do expoente
+    mov qword [rsp + 8], 0              ; Valor do expoente
+    call sigma_getchar
+    cmp eax, 45
+    jne .expoente_mais
+    mov qword [rsp], 1
+    jmp .expoente_proximo
+.expoente_mais:
+    cmp eax, 43
+    jne .expoente_digito
+.expoente_proximo:
+    call sigma_getchar
+.expoente_digito:
+    mov rcx, rax
+    sub rcx, 48
+    cmp rcx, 9
+    ja .expoente_fim
+    mov rax, [rsp + 8]
+    cmp rax, 100000
+    jae .expoente_proximo               ; Expoentes absurdos: basta saturar
+    imul rax, 10
+    add rax, rcx
+    mov [rsp + 8], rax
+    jmp .expoente_proximo
+.expoente_fim:
+    mov edi, eax
+    call sigma_ungetc
+    mov rax, [rsp + 8]
+    cmp qword [rsp], 0
+    je .aplica_expoente
+    neg rax
+.aplica_expoente:
+    add r15, rax
+    jmp .decimal
+.devolve_decimal:
+    mov edi, eax
+    call sigma_ungetc
+.decimal:
+    cvtsi2sd xmm0, r14
+    movsd [rsp], xmm0
+    mov rdi, r15
+    cmp rdi, 0
+    jl .divide
+    call sigma_pow10
+    mulsd xmm0, [rsp]
+    jmp .sinal_decimal
+.divide:
+    neg rdi
+    cmp rdi, 300
+    jle .divide_uma
+    sub rdi, 300                        ; 10^n estouraria: divide em duas etapas
+    call sigma_pow10
+    movsd xmm1, [rsp]
+    divsd xmm1, xmm0
+    movsd [rsp], xmm1
+    mov rdi, 300
+.divide_uma:
+    call sigma_pow10
+    movsd xmm1, [rsp]
+    divsd xmm1, xmm0
+    movsd xmm0, xmm1
+.sinal_decimal:
+    cmp r13, 0
+    je .guarda_decimal
+    movq rax, xmm0
+    mov rcx, -9223372036854775808       ; Bit de sinal
+    xor rax, rcx
+    movq xmm0, rax
+.guarda_decimal:
+    movsd [rbx], xmm0
+    mov eax, 1
+    jmp .fim
+.guarda_inteiro:
+    cmp qword [rsp + 8], 0
+    je .invalido
+    mov edi, eax
+    call sigma_ungetc
+    mov rax, r14
+    cmp r13, 0
+    je .positivo
+    neg rax                             ; -2^63 cabe no long
+    jmp .guarda
+.positivo:
+    cmp rax, 0
+    jge .guarda
+    mov rax, 9223372036854775807        ; 2^63 nao cabe: maior long
+.guarda:
+    mov [rbx], rax
+    mov eax, 1
+    jmp .fim
+.invalido:
+    mov edi, eax
+    call sigma_ungetc
+    xor eax, eax
+    jmp .fim
+.eof:
+    mov eax, -1
+.fim:
+    add rsp, 16
+    pop r15
+    pop r14
+    pop r13
+    pop r12
+    pop rbx
+    ret
+`
@@ -0,0 +1,43 @@
+package codegen
+
+import (
+	"csigma/ir"
+	"errors"
+)
+
+// Object: Monta o código de máquina sem programa externo (montador interno) e
+// grava o arquivo objeto ELF64 direto. O Assembly em sintaxe NASM continua
+// sendo gerado, mas só para o relatório: nada é chamado para montá-lo.
+type Object struct{}
+
+func (Object) Name() string      { return "elf" }
+func (Object) Extension() string { return ".asm" }
+
+// AssembleCommand: Nenhum. A montagem acontece em Assemble.
+func (Object) AssembleCommand(src, obj string) []string { return nil }
+
+func (Object) Generate(prog *ir.Program, opts Options) string {
+	return NASM{}.Generate(prog, opts)
+}
+
+// Assemble: O arquivo .o, pronto para o linker.
+func (Object) Assemble(prog *ir.Program, opts Options) ([]byte, error) {
+	obj, err := assemble(generate(prog, opts))
+	if err != nil {
+		return nil, err
+	}
+	return obj.Bytes(), nil
+}
+
+// Link: O executável, sem linker externo, a partir de _start. Só serve sem
+// LibC (opts.NoLibc): com ela, quem liga é o gcc.
+func (Object) Link(prog *ir.Program, opts Options, pie bool) ([]byte, error) {
+	if !opts.NoLibc {
+		return nil, errors.New("o linker interno só liga programas sem LibC (--nolibc)")
+	}
+	obj, err := assemble(generate(prog, opts))
+	if err != nil {
+		return nil, err
+	}
+	return obj.Executable("_start", pie)
+}
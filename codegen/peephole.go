@@ -0,0 +1,317 @@
+package codegen
+
+import "strings"
+
+// Peephole: Otimização "olho mágico". Olha duas ou três instruções vizinhas por
+// vez e troca padrões conhecidos por versões menores, repetindo até nada mudar:
+//
+//	mov rax, rax                     => (some)
+//	jmp .L / .L:                     => .L:
+//	jb .ok / jmp .fail / .ok:        => jae .fail / .ok:
+//	mov [m], rax / mov rsi, [m]      => mov [m], rax / mov rsi, rax
+//	mov rax, [x] / mov rsi, rax      => mov rsi, [x]        (se RAX morre ali)
+//	mov rax, 5 (RAX nunca é lido)    => (some)
+//	mov [rbp - 8], rax (nunca lido)  => (some)
+//
+// Rótulos são barreiras: um salto pode chegar neles vindo de qualquer lugar,
+// então nenhum padrão atravessa um rótulo.
+//
+// keep: posições da pilha cujas gravações ficam mesmo sem leitura (com -g, as
+// variáveis que o depurador mostra). Pode ser nil.
+func Peephole(text []*Instr, keep map[string]bool) []*Instr {
+	for changed := true; changed; {
+		changed = false
+		code := codeIndexes(text)
+		dead := map[int]bool{}
+		slotReads := stackSlotReads(text)
+		for slot := range keep {
+			slotReads[slot] = true
+		}
+
+		for k := 0; k < len(code); k++ {
+			i := code[k]
+			a := text[i]
+			if dead[i] || a.Label != "" {
+				continue
+			}
+
+			// Gravação em uma posição da pilha que ninguém lê.
+			if isMove(a.Op) && isStackSlot(a.Args[0]) && !slotReads[mem(a.Args[0])] {
+				dead[i], changed = true, true
+				continue
+			}
+			// Cópia de um valor para ele mesmo.
+			if isMove(a.Op) && mem(a.Args[0]) == mem(a.Args[1]) {
+				dead[i], changed = true, true
+				continue
+			}
+			// Registrador escrito e nunca lido.
+			if (a.Op == "mov" || a.Op == "movq" || a.Op == "lea") && isGPR(a.Args[0]) && deadAfter(text, i, a.Args[0]) {
+				dead[i], changed = true, true
+				continue
+			}
+
+			// Temporário na pilha relido mais adiante, com o registrador intacto.
+			if isMove(a.Op) && isStackSlot(a.Args[0]) && isReg(a.Args[1]) && forward(text, i) {
+				changed = true
+			}
+
+			if k+1 >= len(code) {
+				continue
+			}
+			j := code[k+1]
+			b := text[j]
+
+			switch {
+			// Salto para a linha seguinte.
+			case a.Op == "jmp" && b.Label == a.Args[0]:
+				dead[i], changed = true, true
+
+			// Salto condicional por cima de um jmp: inverte a condição.
+			case inverse[a.Op] != "" && b.Op == "jmp" && k+2 < len(code) && text[code[k+2]].Label == a.Args[0]:
+				a.Op, a.Args = inverse[a.Op], b.Args
+				dead[j], changed = true, true
+
+			case b.Label != "" || !isMove(a.Op) || b.Op != a.Op:
+
+			// Ida e volta: o segundo mov copia de volta o que o primeiro copiou.
+			case mem(b.Args[0]) == mem(a.Args[1]) && mem(b.Args[1]) == mem(a.Args[0]) && !mentions(a.Args[1], a.Args[0]):
+				dead[j], changed = true, true
+
+			// Releitura do que acabou de ser gravado na memória.
+			case !isReg(a.Args[0]) && mem(b.Args[1]) == mem(a.Args[0]) && (isReg(a.Args[1]) || isImm(a.Args[1]) && a.Op == "mov"):
+				b.Args = []string{b.Args[0], a.Args[1]}
+				changed = true
+
+			// Cópia intermediária por um registrador que morre logo em seguida.
+			case a.Op == "mov" && isGPR(a.Args[0]) && b.Args[1] == a.Args[0] && !mentions(b.Args[0], a.Args[0]) &&
+				canMove(b.Args[0], a.Args[1]) && deadAfter(text, j, a.Args[0]):
+				src := a.Args[1]
+				dst := b.Args[0]
+				if !isReg(dst) && isImm(src) {
+					dst = "qword " + mem(dst)
+				}
+				b.Args = []string{dst, src}
+				dead[i], changed = true, true
+			}
+		}
+
+		if changed {
+			kept := text[:0]
+			for i, in := range text {
+				if !dead[i] {
+					kept = append(kept, in)
+				}
+			}
+			text = kept
+		}
+	}
+	return text
+}
+
+// forward: Depois de 'mov [rbp - k], R', troca as releituras de [rbp - k] por R
+// enquanto nem R nem a posição da pilha mudam, até o fim do bloco. Uma chamada
+// encerra a busca: o 'input' passa o endereço da variável para o scanf.
+func forward(text []*Instr, i int) bool {
+	slot, reg := mem(text[i].Args[0]), text[i].Args[1]
+	changed := false
+	for _, in := range text[i+1:] {
+		if in.Label != "" || strings.HasPrefix(in.Op, "j") || in.Op == "ret" {
+			break
+		}
+		if in.Op == "" {
+			continue
+		}
+		if in.Op == text[i].Op && len(in.Args) == 2 && mem(in.Args[1]) == slot && isReg(in.Args[0]) {
+			in.Args = []string{in.Args[0], reg}
+			changed = true
+		}
+		if in.Op == "call" || writes(in, reg) || len(in.Args) > 0 && mem(in.Args[0]) == slot {
+			break
+		}
+	}
+	return changed
+}
+
+// inverse: Condição oposta de cada salto condicional.
+var inverse = map[string]string{
+	"je": "jne", "jne": "je", "jl": "jge", "jge": "jl", "jg": "jle", "jle": "jg", "jb": "jae", "jae": "jb",
+}
+
+// codeIndexes: Posições das instruções e rótulos (comentários e linhas em branco
+// não interrompem um padrão).
+func codeIndexes(text []*Instr) []int {
+	var idx []int
+	for i, in := range text {
+		if in.Label != "" || in.Op != "" {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+func isMove(op string) bool {
+	return op == "mov" || op == "movq" || op == "movsd"
+}
+
+// mem: Remove o prefixo de tamanho ("qword [x]" e "[x]" são o mesmo endereço).
+func mem(s string) string {
+	return strings.TrimPrefix(s, "qword ")
+}
+
+// isStackSlot: Variável simples ou temporário na pilha ([rbp - 16]). Endereços
+// com índice ([r10 + rcx*8], [vet_v + 16]) ficam de fora: são os vetores, na
+// .bss, e o índice pode apontar para qualquer posição deles.
+func isStackSlot(s string) bool {
+	return strings.HasPrefix(mem(s), "[rbp - ") && !strings.Contains(s, "+")
+}
+
+// stackSlotReads: Posições da pilha lidas em algum ponto da função. As demais
+// só são gravadas, e essas gravações podem sumir.
+func stackSlotReads(text []*Instr) map[string]bool {
+	reads := map[string]bool{}
+	for _, in := range text {
+		for n, arg := range in.Args {
+			if isStackSlot(arg) && (n > 0 || !isMove(in.Op)) {
+				reads[mem(arg)] = true
+			}
+		}
+	}
+	return reads
+}
+
+// canMove: Combinações aceitas por um único 'mov dst, src' de 64 bits.
+func canMove(dst, src string) bool {
+	if isReg(dst) {
+		return true
+	}
+	return isReg(src) || isImm(src) && !isWideImm(src)
+}
+
+// --- REGISTRADORES ---
+
+var (
+	argRegs     = []string{"rdi", "rsi", "rdx", "rcx", "r8", "r9", "rax"} // Lidos por um call (RAX = AL das variádicas)
+	calleeSaved = []string{"rbx", "rbp", "r12", "r13", "r14", "r15"}
+)
+
+// family: "eax" e "rax" são partes do mesmo registrador.
+func family(r string) string {
+	if len(r) == 3 && r[0] == 'e' {
+		return "r" + r[1:]
+	}
+	return strings.TrimSuffix(r, "d")
+}
+
+// isGPR: Registrador de uso geral (não XMM).
+func isGPR(s string) bool {
+	return isReg(s) && !strings.HasPrefix(s, "xmm")
+}
+
+// mentions: O operando usa o registrador (diretamente ou no endereço)?
+func mentions(operand, reg string) bool {
+	words := strings.FieldsFunc(operand, func(c rune) bool {
+		return !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_')
+	})
+	for _, w := range words {
+		if family(w) == family(reg) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, reg string) bool {
+	for _, r := range list {
+		if r == family(reg) {
+			return true
+		}
+	}
+	return false
+}
+
+// deadAfter: O valor do registrador depois da instrução i nunca é lido? Segue
+// adiante até alguém sobrescrevê-lo (morto) ou lê-lo (vivo). Ao chegar a um
+// rótulo ou salto, não dá para saber: considera vivo.
+func deadAfter(text []*Instr, i int, reg string) bool {
+	if family(reg) == "rsp" || family(reg) == "rbp" {
+		return false // A moldura da pilha é lida pela saída da função
+	}
+	for _, in := range text[i+1:] {
+		switch {
+		case in.Label != "":
+			return false
+		case in.Op == "":
+			continue
+		case in.Op == "ret":
+			return family(reg) != "rax" && !contains(calleeSaved, reg)
+		case in.Op == "call":
+			if contains(argRegs, reg) {
+				return false
+			}
+			if !contains(calleeSaved, reg) {
+				return true // Destruído pela chamada
+			}
+			continue
+		case strings.HasPrefix(in.Op, "j"):
+			return false
+		}
+
+		if reads(in, reg) {
+			return false
+		}
+		if writes(in, reg) {
+			return true
+		}
+	}
+	return false
+}
+
+// reads: A instrução lê o registrador?
+func reads(in *Instr, reg string) bool {
+	switch in.Op {
+	case "cqo":
+		return family(reg) == "rax"
+	case "rep stosq":
+		return family(reg) == "rax" || family(reg) == "rcx" || family(reg) == "rdi"
+	case "idiv":
+		return family(reg) == "rax" || family(reg) == "rdx" || mentions(in.Args[0], reg)
+	case "pop":
+		return false
+	case "xor":
+		if in.Args[0] == in.Args[1] {
+			return false // Zerar não depende do valor anterior
+		}
+	}
+	for n, arg := range in.Args {
+		if n > 0 || !isReg(arg) {
+			if mentions(arg, reg) {
+				return true
+			}
+			continue
+		}
+		// Primeiro operando em registrador: só é lido se a instrução o combina
+		// com o segundo (add, sub, cmp...), e não apenas sobrescreve.
+		pureWrite := in.Op == "mov" || in.Op == "movq" || in.Op == "movsxd" || in.Op == "lea" ||
+			in.Op == "movsd" && !isReg(in.Args[1])
+		if !pureWrite && mentions(arg, reg) {
+			return true
+		}
+	}
+	return false
+}
+
+// writes: A instrução sobrescreve o registrador?
+func writes(in *Instr, reg string) bool {
+	switch in.Op {
+	case "cqo":
+		return family(reg) == "rdx"
+	case "idiv":
+		return family(reg) == "rax" || family(reg) == "rdx"
+	case "rep stosq":
+		return family(reg) == "rcx" || family(reg) == "rdi"
+	case "cmp", "push":
+		return false
+	}
+	return len(in.Args) > 0 && isReg(in.Args[0]) && family(in.Args[0]) == family(reg)
+}
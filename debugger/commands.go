@@ -0,0 +1,185 @@
+package debugger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const help = `Comandos (entre parênteses, as abreviações):
+  step (s) [n]       executa o próximo comando (ou n comandos)
+  next (n) [n]       executa a linha atual inteira (ou n linhas)
+  continue (c)       executa até o próximo ponto de parada ou o fim
+  break (b) [linha]  põe um ponto de parada na linha; sem linha, lista os pontos
+  clear [linha]      tira o ponto de parada da linha; sem linha, tira todos
+  watch (w) expr     observa a expressão (ou um vetor pelo nome) a cada parada
+  unwatch n          para de observar a expressão n
+  print (p) expr     mostra o valor de uma expressão
+  vars (v)           tabela das variáveis ('*': mudou desde a parada anterior)
+  list (l) [linha]   mostra o fonte em volta da linha atual (ou da linha dada)
+  help (h)           esta ajuda
+  quit (q)           sai
+`
+
+// Command: Executa um comando do depurador. Devolve false para sair.
+func (d *Debugger) Command(line string) bool {
+	cmd, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+	arg = strings.TrimSpace(arg)
+	var err error
+	switch cmd {
+	case "":
+	case "step", "s":
+		if d.running() {
+			var n int
+			if n, err = count(arg); err == nil {
+				d.Step(n)
+			}
+		}
+	case "next", "n":
+		if d.running() {
+			var n int
+			if n, err = count(arg); err == nil {
+				d.Next(n)
+			}
+		}
+	case "continue", "c":
+		if d.running() {
+			d.Continue()
+		}
+	case "break", "b":
+		if arg == "" {
+			d.listBreakpoints()
+			break
+		}
+		var n int
+		if n, err = lineNumber(arg); err == nil {
+			if n, err = d.Break(n); err == nil {
+				fmt.Fprintf(d.out, "Ponto de parada na linha %d.\n", n)
+			}
+		}
+	case "clear":
+		n := 0
+		if arg != "" {
+			n, err = lineNumber(arg)
+		}
+		if err == nil {
+			err = d.Clear(n)
+		}
+	case "watch", "w":
+		err = d.Watch(arg)
+	case "unwatch":
+		var n int
+		if n, err = strconv.Atoi(arg); err == nil {
+			err = d.Unwatch(n)
+		} else {
+			err = fmt.Errorf("uso: unwatch n")
+		}
+	case "print", "p":
+		err = d.Print(arg)
+	case "vars", "v":
+		d.Variables()
+	case "list", "l":
+		n := 0
+		if arg != "" {
+			n, err = lineNumber(arg)
+		}
+		if err == nil {
+			d.List(n)
+		}
+	case "help", "h":
+		io.WriteString(d.out, help)
+	case "quit", "q":
+		return false
+	default:
+		err = fmt.Errorf("comando desconhecido: %s (help lista os comandos)", cmd)
+	}
+	if err != nil {
+		fmt.Fprintf(d.out, "Erro: %v\n", err)
+	}
+	return true
+}
+
+// running: O programa ainda não terminou (senão avisa).
+func (d *Debugger) running() bool {
+	if d.finished {
+		fmt.Fprintln(d.out, "O programa já terminou.")
+	}
+	return !d.finished
+}
+
+func (d *Debugger) listBreakpoints() {
+	found := false
+	for n := range d.lines {
+		if d.breakpoints[n+1] {
+			fmt.Fprintf(d.out, "  linha %4d | %s\n", n+1, d.source(n+1))
+			found = true
+		}
+	}
+	if !found {
+		fmt.Fprintln(d.out, "Nenhum ponto de parada.")
+	}
+}
+
+func count(arg string) (int, error) {
+	if arg == "" {
+		return 1, nil
+	}
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("quantidade inválida: %s", arg)
+	}
+	return n, nil
+}
+
+func lineNumber(arg string) (int, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("linha inválida: %s", arg)
+	}
+	return n, nil
+}
+
+// --- SESSÃO ---
+
+// Run: Lê e executa os comandos até 'quit' ou o fim de cmds.
+//
+//   - interativo (prompt): mostra o prompt, e uma linha vazia repete o último
+//     comando, como no gdb;
+//   - roteiro (--commands): cada comando aparece na saída depois do prompt,
+//     para que a sessão inteira possa ser comparada com a esperada; linhas
+//     vazias e comentários ('#') são ignorados.
+//
+// shared diz se cmds também é a entrada do programa.
+func (d *Debugger) Run(cmds *bufio.Reader, prompt, shared bool) {
+	d.shared = shared
+	d.Start()
+	script := !prompt
+	last := ""
+	for {
+		if prompt {
+			fmt.Fprint(d.out, "(sigma) ")
+		}
+		line, err := cmds.ReadString('\n')
+		if err != nil && line == "" {
+			if prompt {
+				fmt.Fprintln(d.out)
+			}
+			return
+		}
+		line = strings.TrimSpace(line)
+		if script {
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			fmt.Fprintf(d.out, "(sigma) %s\n", line)
+		} else if line == "" {
+			line = last
+		}
+		last = line
+		if !d.Command(line) {
+			return
+		}
+	}
+}
@@ -0,0 +1,446 @@
+package debugger
+
+import (
+	"bufio"
+	"csigma/interp"
+	"csigma/lexer"
+	"csigma/parser"
+	"csigma/semantic"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Depurador (csigma debug): executa o programa no interpretador (pacote
+// interp) um comando por vez, para acompanhar como as variáveis mudam.
+//
+//   - pontos de parada por linha; o programa para antes de executar a linha;
+//   - step executa um comando, next executa a linha inteira, continue vai até
+//     o próximo ponto de parada (ou o fim);
+//   - expressões observadas (watch) são recalculadas e mostradas a cada parada;
+//   - a tabela de variáveis vem da Tabela de Símbolos do Analisador Semântico,
+//     com o valor atual e uma marca nas que mudaram desde a parada anterior.
+//
+// O Sigma não tem funções nem laços: o programa é a lista de comandos na
+// ordem do fonte, e a posição da execução é o índice do próximo comando.
+
+// Debugger: O programa carregado e o estado da depuração.
+type Debugger struct {
+	statements []parser.Statement
+	lines      []string                    // Linhas do fonte, para mostrar
+	symbols    map[string]semantic.Simbolo // Tabela do programa inteiro
+	names      []string                    // Nomes na ordem das declarações
+	machine    *interp.Machine
+	in         *bufio.Reader
+	out        io.Writer
+	shared     bool // A entrada do programa é a mesma dos comandos
+
+	pc          int // Próximo comando a executar
+	finished    bool
+	breakpoints map[int]bool
+	watches     []watch
+	previous    map[string]string // Valores na parada anterior
+	current     map[string]string // Valores na parada atual
+}
+
+// New: Carrega o programa: análise léxica, sintática e semântica. Devolve os
+// erros de compilação, se houver. in é a entrada do programa ('input').
+func New(src string, in *bufio.Reader, out io.Writer, opts interp.Options) (*Debugger, error) {
+	statements, err := parse(src)
+	if err != nil {
+		return nil, err
+	}
+	analyzer := semantic.NewAnalyzer()
+	analyzer.Validar(statements)
+	if len(analyzer.Erros) > 0 {
+		return nil, errors.New(strings.Join(analyzer.Erros, "\n"))
+	}
+
+	d := &Debugger{
+		statements:  statements,
+		lines:       strings.Split(strings.TrimRight(src, "\n"), "\n"),
+		symbols:     analyzer.TabelaSimbolos,
+		machine:     interp.New(analyzer.TabelaSimbolos, in, out, opts),
+		in:          in,
+		out:         out,
+		breakpoints: map[int]bool{},
+	}
+	for _, stmt := range statements {
+		switch s := stmt.(type) {
+		case *parser.VarDeclNode:
+			d.names = append(d.names, s.Name)
+		case *parser.ConstDeclNode:
+			d.names = append(d.names, s.Name)
+		}
+	}
+	d.current = d.values()
+	return d, nil
+}
+
+func parse(src string) ([]parser.Statement, error) {
+	return parser.NewParser(lexer.Tokenize(src)).ParseProgram()
+}
+
+// --- EXECUÇÃO ---
+
+// Start: Mostra onde o programa está parado antes do primeiro comando.
+func (d *Debugger) Start() {
+	if len(d.statements) == 0 {
+		d.finished = true
+		fmt.Fprintln(d.out, "O programa não tem comandos.")
+		return
+	}
+	fmt.Fprintf(d.out, "Programa carregado: %d comando(s). Parado antes da linha %d.\n", len(d.statements), d.line(d.pc))
+	d.stop()
+}
+
+// Step: Executa n comandos (para antes, num ponto de parada).
+func (d *Debugger) Step(n int) {
+	for i := 0; i < n && d.exec(); i++ {
+		if i < n-1 && d.atBreakpoint() {
+			break
+		}
+	}
+	d.stopped()
+}
+
+// Next: Executa n linhas: todos os comandos da linha atual, de uma vez.
+func (d *Debugger) Next(n int) {
+	for i := 0; i < n && !d.finished; i++ {
+		line := d.line(d.pc)
+		for d.exec() && d.line(d.pc) == line {
+		}
+		if i < n-1 && d.atBreakpoint() {
+			break
+		}
+	}
+	d.stopped()
+}
+
+// Continue: Executa até o próximo ponto de parada ou o fim do programa.
+func (d *Debugger) Continue() {
+	for d.exec() && !d.atBreakpoint() {
+	}
+	d.stopped()
+}
+
+// exec: Executa o próximo comando. Devolve false se o programa terminou
+// (antes ou por causa dele).
+func (d *Debugger) exec() bool {
+	if d.finished {
+		return false
+	}
+	stmt := d.statements[d.pc]
+	err := d.machine.Exec(stmt)
+	if _, ok := stmt.(*parser.InputNode); ok && d.shared {
+		d.skipNewline()
+	}
+	d.pc++
+	if err != nil {
+		fmt.Fprintln(d.out, err)
+		fmt.Fprintln(d.out, "O programa terminou com erro de execução.")
+		d.finished = true
+		return false
+	}
+	if d.pc == len(d.statements) {
+		fmt.Fprintln(d.out, "O programa terminou.")
+		d.finished = true
+		return false
+	}
+	return true
+}
+
+// skipNewline: O 'input' deixa o fim da linha digitada na entrada, que seria
+// lido como um comando vazio do depurador.
+func (d *Debugger) skipNewline() {
+	for d.in.Buffered() > 0 {
+		c, _ := d.in.ReadByte()
+		if c == '\n' {
+			return
+		}
+		if c != ' ' && c != '\t' && c != '\r' {
+			d.in.UnreadByte()
+			return
+		}
+	}
+}
+
+// atBreakpoint: O próximo comando é o primeiro de uma linha com ponto de parada.
+func (d *Debugger) atBreakpoint() bool {
+	if d.finished || !d.breakpoints[d.line(d.pc)] {
+		return false
+	}
+	return d.pc == 0 || d.line(d.pc-1) != d.line(d.pc)
+}
+
+// stopped: Depois de executar: a linha em que parou e as expressões observadas.
+func (d *Debugger) stopped() {
+	if d.finished {
+		d.previous, d.current = d.current, d.values()
+		d.showWatches()
+		return
+	}
+	if d.atBreakpoint() {
+		fmt.Fprintf(d.out, "Ponto de parada na linha %d.\n", d.line(d.pc))
+	}
+	d.stop()
+}
+
+func (d *Debugger) stop() {
+	d.previous, d.current = d.current, d.values()
+	line := d.line(d.pc)
+	fmt.Fprintf(d.out, "-> %4d | %s\n", line, d.source(line))
+	d.showWatches()
+}
+
+func (d *Debugger) showWatches() {
+	for i, w := range d.watches {
+		fmt.Fprintf(d.out, "   [%d] %s = %s\n", i+1, w.text, d.evalWatch(w))
+	}
+}
+
+// line: A linha do fonte do comando i.
+func (d *Debugger) line(i int) int {
+	if i >= len(d.statements) {
+		return 0
+	}
+	return parser.SpanOf(d.statements[i]).Line
+}
+
+func (d *Debugger) source(line int) string {
+	if line < 1 || line > len(d.lines) {
+		return ""
+	}
+	return strings.TrimRight(d.lines[line-1], "\r")
+}
+
+// --- PONTOS DE PARADA ---
+
+// Break: Põe um ponto de parada na primeira linha com comando a partir de
+// line. Devolve a linha escolhida.
+func (d *Debugger) Break(line int) (int, error) {
+	for _, stmt := range d.statements {
+		if l := parser.SpanOf(stmt).Line; l >= line {
+			d.breakpoints[l] = true
+			return l, nil
+		}
+	}
+	return 0, fmt.Errorf("nenhum comando na linha %d ou depois", line)
+}
+
+// Clear: Tira o ponto de parada da linha (0 tira todos).
+func (d *Debugger) Clear(line int) error {
+	if line == 0 {
+		d.breakpoints = map[int]bool{}
+		return nil
+	}
+	if !d.breakpoints[line] {
+		return fmt.Errorf("não há ponto de parada na linha %d", line)
+	}
+	delete(d.breakpoints, line)
+	return nil
+}
+
+// --- EXPRESSÕES OBSERVADAS ---
+
+// watch: Uma expressão observada (ou um vetor inteiro, pelo nome).
+type watch struct {
+	text  string
+	expr  *parser.ExprNode
+	array string
+}
+
+// compile: Lê e valida uma expressão como o 'print' do programa, numa cópia
+// da Tabela de Símbolos (a expressão não declara nada).
+func (d *Debugger) compile(text string) (watch, error) {
+	text = strings.TrimSpace(text)
+	if sym, ok := d.symbols[text]; ok && sym.Tamanho > 0 {
+		return watch{text: text, array: text}, nil
+	}
+	statements, err := parse("print " + text)
+	if err != nil {
+		return watch{}, err
+	}
+	var expr *parser.ExprNode
+	if len(statements) == 1 {
+		if p, ok := statements[0].(*parser.PrintNode); ok && !p.IsString && p.Span.EndColumn == len("print "+text)+1 {
+			expr = p.Parts[0].Expr
+		}
+	}
+	if expr == nil {
+		return watch{}, fmt.Errorf("expressão inválida: %s", text)
+	}
+	analyzer := semantic.NewAnalyzer()
+	for name, sym := range d.symbols {
+		analyzer.TabelaSimbolos[name] = sym
+	}
+	analyzer.Validar(statements)
+	if len(analyzer.Erros) > 0 {
+		return watch{}, errors.New(analyzer.Erros[0])
+	}
+	return watch{text: text, expr: expr}, nil
+}
+
+// Watch: Passa a observar a expressão e mostra o valor atual.
+func (d *Debugger) Watch(text string) error {
+	w, err := d.compile(text)
+	if err != nil {
+		return err
+	}
+	d.watches = append(d.watches, w)
+	fmt.Fprintf(d.out, "   [%d] %s = %s\n", len(d.watches), w.text, d.evalWatch(w))
+	return nil
+}
+
+// Unwatch: Para de observar a expressão de número n (começando em 1).
+func (d *Debugger) Unwatch(n int) error {
+	if n < 1 || n > len(d.watches) {
+		return fmt.Errorf("não há expressão observada de número %d", n)
+	}
+	d.watches = append(d.watches[:n-1], d.watches[n:]...)
+	return nil
+}
+
+// Print: Mostra o valor de uma expressão uma vez.
+func (d *Debugger) Print(text string) error {
+	w, err := d.compile(text)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(d.out, "%s = %s\n", w.text, d.evalWatch(w))
+	return nil
+}
+
+// evalWatch: O valor como texto; variáveis ainda não declaradas e erros de
+// execução (índice fora dos limites, divisão por zero) aparecem no lugar dele.
+func (d *Debugger) evalWatch(w watch) string {
+	if w.array != "" {
+		return d.show(w.array)
+	}
+	for _, name := range variables(w.expr) {
+		if !d.machine.Declared(name) {
+			return fmt.Sprintf("(%s ainda não declarada)", name)
+		}
+	}
+	line := d.line(d.pc)
+	if d.finished && len(d.statements) > 0 {
+		line = d.line(len(d.statements) - 1) // Depois do fim: a última linha executada
+	}
+	v, err := d.machine.Eval(w.expr, line)
+	if err != nil {
+		return "(" + err.Error() + ")"
+	}
+	if v.Tipo == "SIGMA_STR" {
+		return fmt.Sprintf("%q", v.Str)
+	}
+	return v.String()
+}
+
+// variables: Os nomes lidos pela expressão (os argumentos de len() não contam:
+// o tamanho vem da Tabela de Símbolos).
+func variables(e *parser.ExprNode) []string {
+	var names []string
+	add := func(o parser.Operand) {
+		if o.IsVar && !o.IsCall {
+			names = append(names, o.Value)
+		}
+		if o.Index != nil {
+			names = append(names, variables(o.Index)...)
+		}
+	}
+	add(e.First)
+	for _, op := range e.Ops {
+		add(op.Operand)
+	}
+	return names
+}
+
+// --- VARIÁVEIS ---
+
+// values: O valor de cada variável, como mostrado na tabela.
+func (d *Debugger) values() map[string]string {
+	values := map[string]string{}
+	for _, name := range d.names {
+		values[name] = d.show(name)
+	}
+	return values
+}
+
+// show: O valor atual de uma variável, vetor ou constante.
+func (d *Debugger) show(name string) string {
+	if !d.machine.Declared(name) {
+		return "(ainda não declarada)"
+	}
+	for _, v := range d.machine.Variables() {
+		if v.Name != name {
+			continue
+		}
+		if v.Elements != nil {
+			parts := make([]string, len(v.Elements))
+			for i, e := range v.Elements {
+				parts[i] = e.String()
+			}
+			return "[" + strings.Join(parts, ", ") + "]"
+		}
+		if v.Tipo == "SIGMA_STR" {
+			return fmt.Sprintf("%q", v.Value.Str)
+		}
+		return v.Value.String()
+	}
+	return ""
+}
+
+// Variables: A tabela das variáveis da Tabela de Símbolos, na ordem das
+// declarações. '*' marca as que mudaram desde a parada anterior.
+func (d *Debugger) Variables() {
+	if len(d.names) == 0 {
+		fmt.Fprintln(d.out, "O programa não declara variáveis.")
+		return
+	}
+	values := d.values()
+	fmt.Fprintf(d.out, "   %-6s %-14s %-16s %s\n", "", "nome", "tipo", "valor")
+	for _, name := range d.names {
+		sym := d.symbols[name]
+		kind, tipo := "var", sym.Tipo
+		if sym.Constante {
+			kind = "const"
+		}
+		if sym.Tamanho > 0 {
+			tipo = fmt.Sprintf("%s[%d]", sym.Tipo, sym.Tamanho)
+		}
+		mark := " "
+		if d.previous != nil && values[name] != d.previous[name] {
+			mark = "*"
+		}
+		fmt.Fprintf(d.out, " %s %-6s %-14s %-16s %s\n", mark, kind, name, tipo, values[name])
+	}
+}
+
+// List: O fonte em volta da linha (0: a linha atual), com '->' na próxima a
+// executar e 'b' nos pontos de parada.
+func (d *Debugger) List(line int) {
+	if line == 0 {
+		line = d.line(d.pc)
+		if d.finished {
+			line = len(d.lines)
+		}
+	}
+	first, last := line-5, line+5
+	if first < 1 {
+		first = 1
+	}
+	if last > len(d.lines) {
+		last = len(d.lines)
+	}
+	for n := first; n <= last; n++ {
+		bp, cur := " ", "  "
+		if d.breakpoints[n] {
+			bp = "b"
+		}
+		if !d.finished && n == d.line(d.pc) {
+			cur = "->"
+		}
+		fmt.Fprintf(d.out, "%s%s %4d | %s\n", bp, cur, n, d.source(n))
+	}
+}
@@ -0,0 +1,86 @@
+package debugger
+
+import (
+	"bufio"
+	"bytes"
+	"csigma/interp"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "regrava os arquivos .golden")
+
+// Cada testdata/X.sig é depurado com o roteiro X.cmds (e a entrada X.input,
+// se houver); a sessão inteira tem de ser igual a X.golden.
+func TestTranscripts(t *testing.T) {
+	programs, err := filepath.Glob("testdata/*.sig")
+	if err != nil || len(programs) == 0 {
+		t.Fatalf("nenhum programa em testdata (%v)", err)
+	}
+	for _, program := range programs {
+		base := strings.TrimSuffix(program, ".sig")
+		t.Run(filepath.Base(base), func(t *testing.T) {
+			src := read(t, program)
+			cmds := read(t, base+".cmds")
+			input := ""
+			if _, err := os.Stat(base + ".input"); err == nil {
+				input = read(t, base+".input")
+			}
+
+			var out bytes.Buffer
+			d, err := New(src, bufio.NewReader(strings.NewReader(input)), &out, interp.Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			d.Run(bufio.NewReader(strings.NewReader(cmds)), false, false)
+
+			golden := base + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, out.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if want := read(t, golden); out.String() != want {
+				t.Errorf("sessão diferente de %s:\n%s", golden, diff(want, out.String()))
+			}
+		})
+	}
+}
+
+func read(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// diff: A primeira linha diferente, com as vizinhas.
+func diff(want, got string) string {
+	w, g := strings.Split(want, "\n"), strings.Split(got, "\n")
+	for i := 0; i < len(w) || i < len(g); i++ {
+		if i < len(w) && i < len(g) && w[i] == g[i] {
+			continue
+		}
+		from := i - 2
+		if from < 0 {
+			from = 0
+		}
+		var sb strings.Builder
+		for j := from; j <= i+2; j++ {
+			if j < len(w) {
+				sb.WriteString("  quer: " + w[j] + "\n")
+			}
+			if j < len(g) {
+				sb.WriteString("  veio: " + g[j] + "\n")
+			}
+		}
+		return sb.String()
+	}
+	return ""
+}
@@ -0,0 +1,358 @@
+package dwarf
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Informação de depuração DWARF (versão 4) para o gdb: a tabela de linhas
+// (.debug_line), que liga cada endereço do código a uma linha do fonte Sigma,
+// e a descrição do programa (.debug_info, com o vocabulário em .debug_abbrev):
+// o arquivo, o 'main' e as variáveis, com o tipo e onde cada uma mora.
+//
+// Os endereços só são conhecidos depois da montagem e da linkagem, então as
+// seções saem como bytes com referências a rótulos (Ref), que o montador
+// completa: 'dq main.dbg_linha_3' no NASM, '.quad .Lmain_dbg_linha_3' no GNU
+// as, ou uma relocação no montador interno.
+
+// Ref: Size bytes em Offset que recebem o endereço de Symbol (8 bytes) ou,
+// com Size 4, o deslocamento dele dentro da sua seção (o início de outra
+// seção de depuração).
+type Ref struct {
+	Offset int
+	Size   int
+	Symbol string
+}
+
+// Section: Uma seção de depuração. Label marca o seu início, para as
+// referências vindas das outras seções.
+type Section struct {
+	Name  string
+	Label string
+	Data  []byte
+	Refs  []Ref
+}
+
+// Type: O tipo de uma variável Sigma.
+type Type int
+
+const (
+	Int Type = iota
+	Float
+	String
+)
+
+// Var: Uma variável do 'main'. Location é a expressão DWARF que diz onde ela
+// mora (Frame ou Register); vazia se a variável foi eliminada (-O1). Os
+// vetores, na memória estática, usam Static no lugar dela.
+type Var struct {
+	Name     string
+	Line     int
+	Type     Type
+	Count    int // Vetor: número de elementos (0 nas variáveis simples)
+	Location []byte
+	Static   string // Rótulo da variável na .bss (DW_OP_addr)
+}
+
+// Row: A partir do endereço de Label, o código vem da linha Line do fonte.
+type Row struct {
+	Label string
+	Line  int
+}
+
+// Unit: O programa compilado, como o gdb o enxerga.
+type Unit struct {
+	Producer string
+	File     string // Caminho do fonte .sig
+	Low      string // Rótulo do início do 'main'
+	High     string // Rótulo logo depois do fim do 'main'
+	Rows     []Row
+	Vars     []Var
+}
+
+// Registradores na numeração do DWARF para x86_64 (diferente da do ModRM).
+var registers = map[string]byte{
+	"rax": 0, "rdx": 1, "rcx": 2, "rbx": 3, "rsi": 4, "rdi": 5, "rbp": 6, "rsp": 7,
+	"r8": 8, "r9": 9, "r10": 10, "r11": 11, "r12": 12, "r13": 13, "r14": 14, "r15": 15,
+}
+
+// Frame: A variável está na pilha, em [rbp + offset] (DW_OP_breg6).
+func Frame(offset int) []byte {
+	return append([]byte{0x76}, sleb(int64(offset))...)
+}
+
+// Register: A variável mora no registrador (DW_OP_reg0 + n).
+func Register(name string) []byte {
+	return []byte{0x50 + registers[name]}
+}
+
+// Constantes do formato (DWARF 4, seção 7).
+const (
+	tagArrayType    = 0x01
+	tagPointerType  = 0x0f
+	tagCompileUnit  = 0x11
+	tagSubrangeType = 0x21
+	tagBaseType     = 0x24
+	tagSubprogram   = 0x2e
+	tagVariable     = 0x34
+
+	atLocation   = 0x02
+	atName       = 0x03
+	atByteSize   = 0x0b
+	atStmtList   = 0x10
+	atLowPC      = 0x11
+	atHighPC     = 0x12
+	atLanguage   = 0x13
+	atProducer   = 0x25
+	atUpperBound = 0x2f
+	atDeclFile   = 0x3a
+	atDeclLine   = 0x3b
+	atEncoding   = 0x3e
+	atExternal   = 0x3f
+	atFrameBase  = 0x40
+	atType       = 0x49
+
+	formAddr        = 0x01
+	formData1       = 0x0b
+	formString      = 0x08
+	formUdata       = 0x0f
+	formRef4        = 0x13
+	formSecOffset   = 0x17
+	formExprloc     = 0x18
+	formFlagPresent = 0x19
+
+	ateFloat      = 0x04
+	ateSigned     = 0x05
+	ateSignedChar = 0x06
+
+	langC99 = 0x0c // Não há código para o Sigma: o gdb usa a sintaxe do C nas expressões
+)
+
+// Códigos das abreviações (cada tipo de entrada de .debug_info).
+const (
+	abbrevUnit = iota + 1
+	abbrevBase
+	abbrevPointer
+	abbrevArray
+	abbrevSubrange
+	abbrevMain
+	abbrevVar
+	abbrevVarGone // Sem DW_AT_location: o gdb mostra <optimized out>
+)
+
+// Sections: .debug_abbrev, .debug_info e .debug_line.
+func (u *Unit) Sections() []Section {
+	abbrev := &Section{Name: ".debug_abbrev", Label: "sigma_debug_abbrev"}
+	info := &Section{Name: ".debug_info", Label: "sigma_debug_info"}
+	line := &Section{Name: ".debug_line", Label: "sigma_debug_line"}
+	u.abbrevs(abbrev)
+	u.info(info, abbrev.Label, line.Label)
+	u.lines(line)
+	return []Section{*abbrev, *info, *line}
+}
+
+// abbrevs: Código, tag, se tem filhos e os pares (atributo, formato).
+func (u *Unit) abbrevs(s *Section) {
+	table := []struct {
+		code, tag byte
+		children  bool
+		attrs     []byte
+	}{
+		{abbrevUnit, tagCompileUnit, true, []byte{atProducer, formString, atLanguage, formData1, atName, formString, atLowPC, formAddr, atHighPC, formAddr, atStmtList, formSecOffset}},
+		{abbrevBase, tagBaseType, false, []byte{atName, formString, atEncoding, formData1, atByteSize, formData1}},
+		{abbrevPointer, tagPointerType, false, []byte{atByteSize, formData1, atType, formRef4}},
+		{abbrevArray, tagArrayType, true, []byte{atType, formRef4}},
+		{abbrevSubrange, tagSubrangeType, false, []byte{atUpperBound, formUdata}},
+		{abbrevMain, tagSubprogram, true, []byte{atExternal, formFlagPresent, atName, formString, atDeclFile, formData1, atDeclLine, formUdata, atType, formRef4, atLowPC, formAddr, atHighPC, formAddr, atFrameBase, formExprloc}},
+		{abbrevVar, tagVariable, false, []byte{atName, formString, atDeclFile, formData1, atDeclLine, formUdata, atType, formRef4, atLocation, formExprloc}},
+		{abbrevVarGone, tagVariable, false, []byte{atName, formString, atDeclFile, formData1, atDeclLine, formUdata, atType, formRef4}},
+	}
+	var b bytes.Buffer
+	for _, a := range table {
+		b.Write(uleb(uint64(a.code)))
+		b.Write(uleb(uint64(a.tag)))
+		if a.children {
+			b.WriteByte(1)
+		} else {
+			b.WriteByte(0)
+		}
+		b.Write(a.attrs)
+		b.Write([]byte{0, 0})
+	}
+	b.WriteByte(0)
+	s.Data = b.Bytes()
+}
+
+// info: A unidade de compilação, os tipos, o 'main' e as suas variáveis.
+func (u *Unit) info(s *Section, abbrev, line string) {
+	w := &writer{s: s}
+	w.u32(0) // Tamanho da unidade, preenchido no fim
+	w.u16(4) // DWARF 4
+	w.ref(4, abbrev)
+	w.u8(8) // Endereços de 8 bytes
+
+	w.uleb(abbrevUnit)
+	w.str(u.Producer)
+	w.u8(langC99)
+	w.str(u.File)
+	w.ref(8, u.Low)
+	w.ref(8, u.High)
+	w.ref(4, line)
+
+	// Tipos: long, double, char e char * (os textos).
+	long := w.len()
+	w.uleb(abbrevBase)
+	w.str("long")
+	w.u8(ateSigned)
+	w.u8(8)
+	double := w.len()
+	w.uleb(abbrevBase)
+	w.str("double")
+	w.u8(ateFloat)
+	w.u8(8)
+	char := w.len()
+	w.uleb(abbrevBase)
+	w.str("char")
+	w.u8(ateSignedChar)
+	w.u8(1)
+	text := w.len()
+	w.uleb(abbrevPointer)
+	w.u8(8)
+	w.u32(uint32(char))
+	types := map[Type]int{Int: long, Float: double, String: text}
+
+	// Vetores: um tipo 'array' para cada tamanho usado.
+	arrays := map[[2]int]int{}
+	for _, v := range u.Vars {
+		key := [2]int{int(v.Type), v.Count}
+		if v.Count == 0 || arrays[key] != 0 {
+			continue
+		}
+		arrays[key] = w.len()
+		w.uleb(abbrevArray)
+		w.u32(uint32(types[v.Type]))
+		w.uleb(abbrevSubrange)
+		w.uleb(uint64(v.Count - 1))
+		w.u8(0) // Fim dos filhos
+	}
+
+	w.uleb(abbrevMain)
+	w.str("main")
+	w.u8(1)
+	w.uleb(1)
+	w.u32(uint32(long))
+	w.ref(8, u.Low)
+	w.ref(8, u.High)
+	w.expr([]byte{0x76, 0}) // Base da moldura: RBP (DW_OP_breg6 0)
+	for _, v := range u.Vars {
+		typ := types[v.Type]
+		if v.Count > 0 {
+			typ = arrays[[2]int{int(v.Type), v.Count}]
+		}
+		if len(v.Location) > 0 || v.Static != "" {
+			w.uleb(abbrevVar)
+		} else {
+			w.uleb(abbrevVarGone)
+		}
+		w.str(v.Name)
+		w.u8(1)
+		w.uleb(uint64(v.Line))
+		w.u32(uint32(typ))
+		switch {
+		case v.Static != "":
+			w.uleb(9)
+			w.u8(0x03) // DW_OP_addr: o endereço vem de uma relocação
+			w.ref(8, v.Static)
+		case len(v.Location) > 0:
+			w.expr(v.Location)
+		}
+	}
+	w.u8(0) // Fim das variáveis do main
+	w.u8(0) // Fim da unidade
+
+	binary.LittleEndian.PutUint32(s.Data, uint32(len(s.Data)-4))
+}
+
+// lines: O programa da tabela de linhas. Cada linha do fonte começa em um
+// rótulo do código: DW_LNE_set_address, DW_LNS_advance_line e DW_LNS_copy.
+func (u *Unit) lines(s *Section) {
+	w := &writer{s: s}
+	w.u32(0) // Tamanho da unidade
+	w.u16(4)
+	w.u32(0) // Tamanho do cabeçalho
+	start := w.len()
+	w.u8(1)                                     // Tamanho mínimo de instrução
+	w.u8(1)                                     // Operações por instrução
+	w.u8(1)                                     // default_is_stmt
+	w.u8(0xfb)                                  // line_base = -5
+	w.u8(14)                                    // line_range
+	w.u8(13)                                    // opcode_base
+	w.bytes(0, 1, 1, 1, 1, 0, 0, 0, 1, 0, 0, 1) // Operandos dos opcodes padrão
+	w.u8(0)                                     // Sem diretórios extras
+	w.str(u.File)                               // Arquivo 1
+	w.bytes(0, 0, 0)                            // Diretório, data e tamanho
+	w.u8(0)                                     // Fim dos arquivos
+	binary.LittleEndian.PutUint32(s.Data[6:], uint32(w.len()-start))
+
+	current := 1
+	for _, r := range u.Rows {
+		w.bytes(0, 9, 2) // DW_LNE_set_address
+		w.ref(8, r.Label)
+		if r.Line != current {
+			w.u8(3) // DW_LNS_advance_line
+			w.sleb(int64(r.Line - current))
+			current = r.Line
+		}
+		w.u8(1) // DW_LNS_copy: nova linha da tabela
+	}
+	w.bytes(0, 9, 2)
+	w.ref(8, u.High)
+	w.bytes(0, 1, 1) // DW_LNE_end_sequence
+
+	binary.LittleEndian.PutUint32(s.Data, uint32(len(s.Data)-4))
+}
+
+// writer: Acrescenta valores little-endian ao fim da seção.
+type writer struct {
+	s *Section
+}
+
+func (w *writer) len() int        { return len(w.s.Data) }
+func (w *writer) bytes(b ...byte) { w.s.Data = append(w.s.Data, b...) }
+func (w *writer) u8(v byte)       { w.bytes(v) }
+func (w *writer) uleb(v uint64)   { w.bytes(uleb(v)...) }
+func (w *writer) sleb(v int64)    { w.bytes(sleb(v)...) }
+func (w *writer) str(v string)    { w.bytes(append([]byte(v), 0)...) }
+func (w *writer) expr(e []byte)   { w.uleb(uint64(len(e))); w.bytes(e...) }
+func (w *writer) u16(v uint16)    { w.bytes(byte(v), byte(v>>8)) }
+func (w *writer) u32(v uint32)    { w.bytes(byte(v), byte(v>>8), byte(v>>16), byte(v>>24)) }
+func (w *writer) ref(n int, s string) {
+	w.s.Refs = append(w.s.Refs, Ref{Offset: w.len(), Size: n, Symbol: s})
+	w.bytes(make([]byte, n)...)
+}
+
+// uleb: LEB128 sem sinal (7 bits por byte, o bit alto indica que há mais).
+func uleb(v uint64) []byte {
+	var b []byte
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if v == 0 {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+// sleb: LEB128 com sinal.
+func sleb(v int64) []byte {
+	var b []byte
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if v == 0 && c&0x40 == 0 || v == -1 && c&0x40 != 0 {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
@@ -0,0 +1,310 @@
+package elf
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Escritor de arquivos objeto ELF64 (x86_64, Linux). Recebe o código de
+// máquina e os dados já montados e grava um '.o' relocável, que o gcc (ou o ld)
+// liga como se viesse do NASM:
+//
+//	cabeçalho ELF | .text | .data | (extras) | .symtab | .strtab | .rela.text | .shstrtab | cabeçalhos de seção
+//
+// A .bss (buffers zerados) não ocupa espaço no arquivo: só o seu tamanho é
+// gravado. As seções extras (a informação de depuração) não são carregadas na
+// memória; cada uma tem as suas próprias relocações. O executável, sem
+// linker externo, fica em exec.go.
+
+// Seções onde um símbolo pode estar definido.
+const (
+	SecUndef = 0 // Externo (printf, scanf...): resolvido pelo linker
+	SecText  = 1
+	SecData  = 2
+	SecBss   = 3
+	SecExtra = 4 // Extra[i] é a seção SecExtra + i
+)
+
+// Tipos de relocação do x86_64 usados pelo montador.
+const (
+	R_X86_64_64    = 1  // Endereço absoluto de 64 bits (na depuração)
+	R_X86_64_PC32  = 2  // Endereço relativo ao RIP (lea rdi, [fmt_0])
+	R_X86_64_PLT32 = 4  // call para uma função externa
+	R_X86_64_32    = 10 // Deslocamento de 32 bits (entre seções de depuração)
+)
+
+// Symbol: Um nome do programa. Value é o deslocamento dentro da seção.
+type Symbol struct {
+	Name    string
+	Section int
+	Value   uint64
+	Global  bool
+	Func    bool
+}
+
+// Reloc: Um pedaço de uma seção que o linker precisa completar com o endereço
+// de Symbol (mais Addend).
+type Reloc struct {
+	Offset uint64
+	Symbol string
+	Type   uint32
+	Addend int64
+}
+
+// Object: O conteúdo de um arquivo objeto relocável.
+type Object struct {
+	Text    []byte
+	Data    []byte
+	Bss     uint64 // Tamanho da .bss
+	Symbols []Symbol
+	Relocs  []Reloc // Do .text
+	Extra   []Section
+}
+
+// Section: Uma seção extra, sem alocação na memória (.debug_info...).
+type Section struct {
+	Name   string
+	Data   []byte
+	Relocs []Reloc
+}
+
+// Bytes: O arquivo .o completo.
+func (o *Object) Bytes() []byte {
+	// --- SEÇÕES ---
+	// Índices no arquivo (a 0 é sempre a seção nula): .text, .data, .bss, as
+	// extras (cada uma seguida da sua .rela, se houver) e as de controle.
+	const shText, shData, shBss = 1, 2, 3
+	extraIndex := make([]int, len(o.Extra))
+	n := 4
+	for i, x := range o.Extra {
+		extraIndex[i] = n
+		n++
+		if len(x.Relocs) > 0 {
+			n++
+		}
+	}
+	shSymtab, shStrtab, shRela, shNote, shShstrtab := n, n+1, n+2, n+3, n+4
+	shCount := n + 5
+
+	// --- TABELA DE SÍMBOLOS ---
+	symtab, strtab, index, firstGlobal := symbolTable(o.Symbols, func(s Symbol) (uint16, uint64) {
+		return sectionIndex(s.Section, extraIndex), s.Value
+	})
+
+	// --- RELOCAÇÕES ---
+	rela := func(relocs []Reloc) []byte {
+		var b bytes.Buffer
+		for _, r := range relocs {
+			binary.Write(&b, binary.LittleEndian, struct {
+				Offset uint64
+				Info   uint64
+				Addend int64
+			}{r.Offset, uint64(index[r.Symbol])<<32 | uint64(r.Type), r.Addend})
+		}
+		return b.Bytes()
+	}
+
+	// --- CABEÇALHOS DE SEÇÃO ---
+	t := newSectionTable(shCount)
+	t.set(shText, ".text", sectionHeader{Type: shtProgbits, Flags: shfAlloc | shfExec, Addralign: 16}, o.Text)
+	t.set(shData, ".data", sectionHeader{Type: shtProgbits, Flags: shfAlloc | shfWrite, Addralign: 8}, o.Data)
+	t.set(shBss, ".bss", sectionHeader{Type: shtNobits, Flags: shfAlloc | shfWrite, Addralign: 8}, nil)
+	t.headers[shBss].Size = o.Bss
+	for i, x := range o.Extra {
+		sh := extraIndex[i]
+		t.set(sh, x.Name, sectionHeader{Type: shtProgbits, Addralign: 1}, x.Data)
+		if len(x.Relocs) > 0 {
+			t.set(sh+1, ".rela"+x.Name, sectionHeader{Type: shtRela, Flags: shfInfoLink, Link: uint32(shSymtab), Info: uint32(sh), Addralign: 8, Entsize: 24}, rela(x.Relocs))
+		}
+	}
+	t.set(shSymtab, ".symtab", sectionHeader{Type: shtSymtab, Link: uint32(shStrtab), Info: uint32(firstGlobal), Addralign: 8, Entsize: 24}, symtab)
+	t.set(shStrtab, ".strtab", sectionHeader{Type: shtStrtab, Addralign: 1}, strtab)
+	t.set(shRela, ".rela.text", sectionHeader{Type: shtRela, Flags: shfInfoLink, Link: uint32(shSymtab), Info: shText, Addralign: 8, Entsize: 24}, rela(o.Relocs))
+	t.set(shNote, ".note.GNU-stack", sectionHeader{Type: shtProgbits, Addralign: 1}, nil) // Pilha não executável
+
+	// --- CONTEÚDO ---
+	var file bytes.Buffer
+	file.Write(make([]byte, 64)) // O cabeçalho é escrito por último
+	shoff := t.write(&file, shShstrtab)
+
+	// --- CABEÇALHO ELF ---
+	out := file.Bytes()
+	copy(out, fileHeader(etRel, 0, 0, shoff, shCount, shShstrtab))
+	return out
+}
+
+// --- PEÇAS COMUNS AO .o E AO EXECUTÁVEL ---
+
+// Tipos de arquivo ELF (e_type).
+const (
+	etRel  = 1 // Objeto relocável (.o)
+	etExec = 2 // Executável de endereço fixo
+	etDyn  = 3 // Executável independente de posição (PIE)
+)
+
+// sectionHeader: Uma entrada da tabela de seções (Elf64_Shdr).
+type sectionHeader struct {
+	Name      uint32
+	Type      uint32
+	Flags     uint64
+	Addr      uint64
+	Offset    uint64
+	Size      uint64
+	Link      uint32
+	Info      uint32
+	Addralign uint64
+	Entsize   uint64
+}
+
+const (
+	shtProgbits = 1
+	shtSymtab   = 2
+	shtStrtab   = 3
+	shtRela     = 4
+	shtNobits   = 8
+	shfWrite    = 1
+	shfAlloc    = 2
+	shfExec     = 4
+	shfInfoLink = 0x40
+)
+
+// sectionIndex: O índice, no arquivo, da seção de um símbolo (.text, .data e
+// .bss são sempre 1, 2 e 3; as extras ficam em extraIndex).
+func sectionIndex(section int, extraIndex []int) uint16 {
+	switch {
+	case section == SecText:
+		return 1
+	case section == SecData:
+		return 2
+	case section == SecBss:
+		return 3
+	case section >= SecExtra:
+		return uint16(extraIndex[section-SecExtra])
+	}
+	return 0
+}
+
+// symbolTable: A .symtab e a .strtab. O ELF exige os símbolos locais antes
+// dos globais; place dá a seção e o valor de cada símbolo no arquivo. Devolve
+// também o índice de cada nome e o do primeiro global.
+func symbolTable(symbols []Symbol, place func(Symbol) (uint16, uint64)) (symtab, strtab []byte, index map[string]int, firstGlobal int) {
+	var str bytes.Buffer
+	str.WriteByte(0)
+	var tab bytes.Buffer
+	index = map[string]int{}
+	tab.Write(make([]byte, 24)) // Símbolo nulo
+	count := 1
+	writeSym := func(s Symbol) {
+		name := uint32(str.Len())
+		str.WriteString(s.Name + "\x00")
+		info := byte(0) // STB_LOCAL, STT_NOTYPE
+		if s.Global {
+			info = 1 << 4
+		}
+		if s.Func {
+			info |= 2
+		}
+		section, value := place(s)
+		binary.Write(&tab, binary.LittleEndian, struct {
+			Name  uint32
+			Info  byte
+			Other byte
+			Shndx uint16
+			Value uint64
+			Size  uint64
+		}{name, info, 0, section, value, 0})
+		index[s.Name] = count
+		count++
+	}
+	for _, s := range symbols {
+		if !s.Global {
+			writeSym(s)
+		}
+	}
+	firstGlobal = count
+	for _, s := range symbols {
+		if s.Global {
+			writeSym(s)
+		}
+	}
+	return tab.Bytes(), str.Bytes(), index, firstGlobal
+}
+
+// sectionTable: Os cabeçalhos e o conteúdo das seções, pelo índice no arquivo.
+type sectionTable struct {
+	headers  []sectionHeader
+	contents [][]byte
+	names    []string
+}
+
+func newSectionTable(n int) *sectionTable {
+	return &sectionTable{headers: make([]sectionHeader, n), contents: make([][]byte, n), names: make([]string, n)}
+}
+
+func (t *sectionTable) set(sh int, name string, h sectionHeader, content []byte) {
+	t.names[sh], t.headers[sh], t.contents[sh] = name, h, content
+	t.headers[sh].Size = uint64(len(content))
+}
+
+// write: Monta a .shstrtab (no índice shstrtab), grava o conteúdo das seções
+// no fim de file, alinhado (ou a partir de um Offset já escolhido), e depois os
+// cabeçalhos. Devolve onde os cabeçalhos começam.
+func (t *sectionTable) write(file *bytes.Buffer, shstrtab int) uint64 {
+	var names bytes.Buffer
+	names.WriteByte(0)
+	for sh := 1; sh < len(t.headers); sh++ {
+		if sh == shstrtab {
+			continue
+		}
+		t.headers[sh].Name = uint32(names.Len())
+		names.WriteString(t.names[sh] + "\x00")
+	}
+	name := uint32(names.Len())
+	names.WriteString(".shstrtab\x00")
+	t.set(shstrtab, ".shstrtab", sectionHeader{Type: shtStrtab, Name: name, Addralign: 1}, names.Bytes())
+
+	for sh := 1; sh < len(t.headers); sh++ {
+		h := &t.headers[sh]
+		for file.Len()%int(h.Addralign) != 0 || uint64(file.Len()) < h.Offset {
+			file.WriteByte(0)
+		}
+		h.Offset = uint64(file.Len())
+		file.Write(t.contents[sh])
+	}
+	for file.Len()%8 != 0 {
+		file.WriteByte(0)
+	}
+	shoff := uint64(file.Len())
+	for _, h := range t.headers {
+		binary.Write(file, binary.LittleEndian, h)
+	}
+	return shoff
+}
+
+// fileHeader: O cabeçalho ELF64 (x86_64, Linux), com a tabela de programa logo
+// depois dele quando phnum > 0.
+func fileHeader(typ uint16, entry uint64, phnum int, shoff uint64, shnum, shstrndx int) []byte {
+	phoff, phentsize := uint64(0), uint16(0)
+	if phnum > 0 {
+		phoff, phentsize = 64, 56
+	}
+	var header bytes.Buffer
+	header.Write([]byte{0x7f, 'E', 'L', 'F', 2, 1, 1, 0}) // 64 bits, little-endian, versão 1, System V
+	header.Write(make([]byte, 8))
+	binary.Write(&header, binary.LittleEndian, struct {
+		Type      uint16
+		Machine   uint16
+		Version   uint32
+		Entry     uint64
+		Phoff     uint64
+		Shoff     uint64
+		Flags     uint32
+		Ehsize    uint16
+		Phentsize uint16
+		Phnum     uint16
+		Shentsize uint16
+		Shnum     uint16
+		Shstrndx  uint16
+	}{typ, 62, 1, entry, phoff, shoff, 0, 64, phentsize, uint16(phnum), 64, uint16(shnum), uint16(shstrndx)}) // EM_X86_64
+	return header.Bytes()
+}
@@ -0,0 +1,173 @@
+package elf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Escritor de executáveis ELF64: o papel do linker, para um objeto que não
+// depende de nada de fora (o runtime sem LibC). Os endereços de todos os
+// símbolos são escolhidos aqui e as relocações são resolvidas na hora:
+//
+//	cabeçalho ELF | cabeçalhos de programa | .text | (até a página seguinte) | .data | (extras) | .symtab | .strtab | .shstrtab | cabeçalhos de seção
+//
+// São dois segmentos PT_LOAD: o primeiro (leitura e execução) vai do início
+// do arquivo até o fim da .text; o segundo (leitura e escrita) começa na
+// página seguinte, com a .data, e se estende na memória pela .bss. O
+// PT_GNU_STACK pede a pilha sem execução. As seções extras (depuração) e a
+// tabela de símbolos continuam no arquivo para o gdb, fora dos segmentos.
+//
+// Com pie, o arquivo é ET_DYN com endereços a partir de 0: o kernel escolhe
+// onde carregá-lo. Isso só funciona porque o código usa apenas endereços
+// relativos ao RIP: não sobra nenhuma relocação para o momento da carga.
+
+const (
+	pageSize  = 0x1000
+	fixedBase = 0x400000 // Endereço de carga sem PIE, o mesmo do ld
+)
+
+// Executable: O executável completo, com a entrada no símbolo entry.
+func (o *Object) Executable(entry string, pie bool) ([]byte, error) {
+	// --- ENDEREÇOS ---
+	base := uint64(fixedBase)
+	typ := uint16(etExec)
+	if pie {
+		base, typ = 0, etDyn
+	}
+	const phnum = 3
+	textOff := align(64+56*phnum, 16)
+	textAddr := base + textOff
+	dataOff := align(textOff+uint64(len(o.Text)), pageSize)
+	dataAddr := base + dataOff
+	bssAddr := align(dataAddr+uint64(len(o.Data)), 8)
+
+	addr := map[string]uint64{}
+	for _, s := range o.Symbols {
+		switch {
+		case s.Section == SecText:
+			addr[s.Name] = textAddr + s.Value
+		case s.Section == SecData:
+			addr[s.Name] = dataAddr + s.Value
+		case s.Section == SecBss:
+			addr[s.Name] = bssAddr + s.Value
+		case s.Section >= SecExtra:
+			addr[s.Name] = s.Value // Fora da memória: o endereço é o deslocamento na seção
+		}
+	}
+	start, ok := addr[entry]
+	if !ok {
+		return nil, fmt.Errorf("ponto de entrada '%s' não definido", entry)
+	}
+
+	// --- RELOCAÇÕES ---
+	text, err := relocate(o.Text, o.Relocs, textAddr, addr)
+	if err != nil {
+		return nil, err
+	}
+	extra := make([][]byte, len(o.Extra))
+	for i, x := range o.Extra {
+		if extra[i], err = relocate(x.Data, x.Relocs, 0, addr); err != nil {
+			return nil, err
+		}
+	}
+
+	// --- SEÇÕES ---
+	// Índices no arquivo: .text, .data, .bss, as extras e as de controle.
+	const shText, shData, shBss = 1, 2, 3
+	extraIndex := make([]int, len(o.Extra))
+	for i := range o.Extra {
+		extraIndex[i] = 4 + i
+	}
+	shSymtab := 4 + len(o.Extra)
+	shStrtab, shShstrtab := shSymtab+1, shSymtab+2
+	shCount := shSymtab + 3
+
+	symtab, strtab, _, firstGlobal := symbolTable(o.Symbols, func(s Symbol) (uint16, uint64) {
+		return sectionIndex(s.Section, extraIndex), addr[s.Name]
+	})
+
+	t := newSectionTable(shCount)
+	t.set(shText, ".text", sectionHeader{Type: shtProgbits, Flags: shfAlloc | shfExec, Addr: textAddr, Offset: textOff, Addralign: 16}, text)
+	t.set(shData, ".data", sectionHeader{Type: shtProgbits, Flags: shfAlloc | shfWrite, Addr: dataAddr, Offset: dataOff, Addralign: 8}, o.Data)
+	t.set(shBss, ".bss", sectionHeader{Type: shtNobits, Flags: shfAlloc | shfWrite, Addr: bssAddr, Addralign: 8}, nil)
+	t.headers[shBss].Size = o.Bss
+	for i, x := range o.Extra {
+		t.set(extraIndex[i], x.Name, sectionHeader{Type: shtProgbits, Addralign: 1}, extra[i])
+	}
+	t.set(shSymtab, ".symtab", sectionHeader{Type: shtSymtab, Link: uint32(shStrtab), Info: uint32(firstGlobal), Addralign: 8, Entsize: 24}, symtab)
+	t.set(shStrtab, ".strtab", sectionHeader{Type: shtStrtab, Addralign: 1}, strtab)
+
+	// --- CONTEÚDO ---
+	var file bytes.Buffer
+	file.Write(make([]byte, textOff)) // Cabeçalhos escritos por último
+	shoff := t.write(&file, shShstrtab)
+
+	// --- CABEÇALHOS DE PROGRAMA ---
+	type programHeader struct {
+		Type   uint32
+		Flags  uint32
+		Offset uint64
+		Vaddr  uint64
+		Paddr  uint64
+		Filesz uint64
+		Memsz  uint64
+		Align  uint64
+	}
+	const (
+		ptLoad        = 1
+		ptGNUStack    = 0x6474e551
+		pfX, pfW, pfR = 1, 2, 4
+	)
+	textEnd := textOff + uint64(len(text))
+	dataEnd := bssAddr + o.Bss - dataAddr
+	phdrs := []programHeader{
+		{ptLoad, pfR | pfX, 0, base, base, textEnd, textEnd, pageSize},
+		{ptLoad, pfR | pfW, dataOff, dataAddr, dataAddr, uint64(len(o.Data)), dataEnd, pageSize},
+		{ptGNUStack, pfR | pfW, 0, 0, 0, 0, 0, 16},
+	}
+	var header bytes.Buffer
+	header.Write(fileHeader(typ, start, phnum, shoff, shCount, shShstrtab))
+	for _, ph := range phdrs {
+		binary.Write(&header, binary.LittleEndian, ph)
+	}
+	out := file.Bytes()
+	copy(out, header.Bytes())
+	return out, nil
+}
+
+// relocate: Uma cópia de code com as relocações resolvidas. at é o endereço
+// de carga do início de code (o P das fórmulas do ABI).
+func relocate(code []byte, relocs []Reloc, at uint64, addr map[string]uint64) ([]byte, error) {
+	out := append([]byte(nil), code...)
+	for _, r := range relocs {
+		s, ok := addr[r.Symbol]
+		if !ok {
+			return nil, fmt.Errorf("símbolo '%s' não definido (um executável sem LibC não tem nada de fora)", r.Symbol)
+		}
+		v := int64(s) + r.Addend
+		switch r.Type {
+		case R_X86_64_PC32, R_X86_64_PLT32:
+			v -= int64(at + r.Offset)
+			if v < math.MinInt32 || v > math.MaxInt32 {
+				return nil, fmt.Errorf("'%s' fora do alcance de um deslocamento de 32 bits", r.Symbol)
+			}
+			binary.LittleEndian.PutUint32(out[r.Offset:], uint32(int32(v)))
+		case R_X86_64_32:
+			if v < 0 || v > math.MaxUint32 {
+				return nil, fmt.Errorf("'%s' fora do alcance de um endereço de 32 bits", r.Symbol)
+			}
+			binary.LittleEndian.PutUint32(out[r.Offset:], uint32(v))
+		case R_X86_64_64:
+			binary.LittleEndian.PutUint64(out[r.Offset:], uint64(v))
+		default:
+			return nil, fmt.Errorf("relocação de tipo %d não suportada", r.Type)
+		}
+	}
+	return out, nil
+}
+
+func align(n, to uint64) uint64 {
+	return (n + to - 1) / to * to
+}
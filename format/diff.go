@@ -0,0 +1,130 @@
+package format
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Diff: As diferenças entre old e new no formato unificado do 'diff -u'
+// (csigma fmt -d), com 3 linhas de contexto. Vazio se forem iguais.
+func Diff(name, old, new string) string {
+	if old == new {
+		return ""
+	}
+	a, b := splitLines(old), splitLines(new)
+
+	// Maior subsequência comum (LCS), de trás para frente: lcs[i][j] é o
+	// tamanho da LCS de a[i:] e b[j:]. Os programas Sigma são pequenos.
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	// O roteiro de edição: ' ' (igual), '-' (só em old) e '+' (só em new).
+	type edit struct {
+		op   byte
+		text string
+		i, j int // Linhas (a partir de 0) em old e new antes da edição
+	}
+	var edits []edit
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			edits = append(edits, edit{' ', a[i], i, j})
+			i, j = i+1, j+1
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			edits = append(edits, edit{'-', a[i], i, j})
+			i++
+		default:
+			edits = append(edits, edit{'+', b[j], i, j})
+			j++
+		}
+	}
+
+	// Blocos (hunks): as mudanças com até 3 linhas iguais em volta; mudanças
+	// separadas por até 6 linhas iguais ficam no mesmo bloco.
+	const context = 3
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s.orig\n+++ %s\n", name, name)
+	for k := 0; k < len(edits); {
+		if edits[k].op == ' ' {
+			k++
+			continue
+		}
+		start := k - context
+		if start < 0 {
+			start = 0
+		}
+		end := k
+		for end < len(edits) {
+			if edits[end].op != ' ' {
+				end++
+				continue
+			}
+			same := end
+			for same < len(edits) && edits[same].op == ' ' {
+				same++
+			}
+			if same == len(edits) || same-end > 2*context {
+				break
+			}
+			end = same
+		}
+		stop := end + context
+		if stop > len(edits) {
+			stop = len(edits)
+		}
+		oldLen, newLen := 0, 0
+		for _, e := range edits[start:stop] {
+			if e.op != '+' {
+				oldLen++
+			}
+			if e.op != '-' {
+				newLen++
+			}
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(edits[start].i, oldLen), hunkRange(edits[start].j, newLen))
+		for _, e := range edits[start:stop] {
+			sb.WriteString(string(e.op) + e.text + "\n")
+		}
+		k = stop
+	}
+	return sb.String()
+}
+
+// hunkRange: 'início,tamanho' de um bloco (linhas a partir de 1; um bloco
+// vazio aponta a linha anterior, como no diff).
+func hunkRange(start, n int) string {
+	if n == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if n == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, n)
+}
+
+// splitLines: As linhas do texto, sem as quebras. Uma última linha sem a
+// quebra leva junto o aviso do diff, e assim também conta como diferente.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	if !strings.HasSuffix(s, "\n") {
+		lines[len(lines)-1] += "\n\\ No newline at end of file"
+	}
+	return lines
+}
@@ -0,0 +1,158 @@
+package format
+
+import (
+	"csigma/lexer"
+	"csigma/parser"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Formatador (csigma fmt): reescreve o programa a partir da AST em um estilo
+// único, como o gofmt faz com o Go:
+//
+//   - palavras-chave em minúsculas ('VAR', 'PRINT'... que o compilador nem
+//     aceita, viram 'var', 'print');
+//   - um espaço em volta de operadores e do '=', nenhum dentro de 'v[i]' e
+//     de 'len(v)', e um comando por linha;
+//   - no máximo uma linha em branco entre comandos, onde o fonte tinha uma
+//     ou mais, e nenhuma no início ou no fim do arquivo.
+//
+// Os comentários (que o lexer guarda como trivia) ficam onde estavam: em uma
+// linha própria antes do comando seguinte, ou no fim da linha do comando.
+
+// Source: O fonte formatado. Falha se o programa tiver erro de sintaxe.
+func Source(src string) (string, error) {
+	l := lexer.NewLexer(src)
+	tokens := l.Tokens()
+	fixKeywords(tokens)
+	statements, err := parser.NewParser(tokens).ParseProgram()
+	if err != nil {
+		return "", err
+	}
+
+	// Cada comando e cada comentário em linha própria é um item, na ordem do
+	// fonte; comentários na mesma linha do fim de um comando ficam nele.
+	type item struct {
+		line, endLine int
+		text          string
+	}
+	var items []item
+	trailing := map[int]string{} // Linha -> comentário depois do comando
+	ends := map[int]bool{}
+	for _, stmt := range statements {
+		sp := parser.SpanOf(stmt)
+		ends[sp.EndLine] = true
+		items = append(items, item{sp.Line, sp.EndLine, Statement(stmt)})
+	}
+	for _, c := range l.Comments() {
+		if ends[c.Line] && trailing[c.Line] == "" {
+			trailing[c.Line] = c.Text
+			continue
+		}
+		items = append(items, item{c.Line, c.Line, c.Text})
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].line < items[j].line })
+
+	var sb strings.Builder
+	for i, it := range items {
+		if i > 0 && it.line > items[i-1].endLine+1 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(it.text)
+		// Com dois comandos na mesma linha, o comentário vai depois do último.
+		if c := trailing[it.endLine]; c != "" && (i+1 == len(items) || items[i+1].endLine != it.endLine) {
+			sb.WriteString(" " + c)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String(), nil
+}
+
+// fixKeywords: Palavras-chave escritas em maiúsculas ('VAR', 'Print') chegam
+// como identificadores. No início de uma linha, sem '=' ou '[' depois (o que
+// faria delas o destino de uma atribuição), só podem ser o comando.
+func fixKeywords(tokens []lexer.Token) {
+	for i, tok := range tokens {
+		if tok.Type != lexer.TokenIdent || i+1 >= len(tokens) {
+			continue
+		}
+		lower := strings.ToLower(tok.Literal)
+		kw := lexer.NewLexer(lower).NextToken()
+		next := tokens[i+1].Type
+		if kw.Type == lexer.TokenIdent || kw.Literal != lower || (i > 0 && tokens[i-1].Line == tok.Line) ||
+			next == lexer.TokenAssign || next == lexer.TokenLBracket {
+			continue
+		}
+		tokens[i].Type, tokens[i].Literal = kw.Type, lower
+	}
+}
+
+// Statement: Um comando no estilo canônico, sem a quebra de linha.
+func Statement(stmt parser.Statement) string {
+	switch s := stmt.(type) {
+	case *parser.VarDeclNode:
+		if s.ElemType == "" {
+			if s.IsString {
+				return "var " + s.Name + " = \"" + s.Value + "\""
+			}
+			return "var " + s.Name + " = " + s.Value
+		}
+		size := s.SizeConst
+		if size == "" {
+			size = strconv.Itoa(s.Size)
+		}
+		if s.ElemType == "float" {
+			return "var " + s.Name + ": float[" + size + "]"
+		}
+		return "var " + s.Name + "[" + size + "]"
+	case *parser.ConstDeclNode:
+		return "const " + s.Name + " = " + s.ExprNode.String()
+	case *parser.PrintNode:
+		return output(s)
+	case *parser.InputNode:
+		cmd := "input "
+		if s.Prompt != nil {
+			cmd += text(s.Prompt) + " "
+		}
+		if s.Index != nil {
+			return cmd + s.VarName + "[" + s.Index.String() + "]"
+		}
+		return cmd + s.VarName
+	case *parser.AssignmentNode:
+		return s.DestString() + " = " + s.ExprNode.String()
+	}
+	return ""
+}
+
+// output: 'print' ou 'write' com o texto ou a expressão.
+func output(s *parser.PrintNode) string {
+	cmd := "print "
+	if s.NoNewline {
+		cmd = "write "
+	}
+	if !s.IsString {
+		return cmd + s.Parts[0].Expr.String()
+	}
+	return cmd + text(s)
+}
+
+// text: O literal de texto; na interpolação, cada '{expr}' é reescrito e as
+// chaves do texto fixo voltam a ser dobradas.
+func text(s *parser.PrintNode) string {
+	if s.Parts == nil {
+		return "\"" + s.Value + "\""
+	}
+	var sb strings.Builder
+	sb.WriteString("\"")
+	for _, part := range s.Parts {
+		if part.Expr != nil {
+			sb.WriteString("{" + part.Expr.String() + "}")
+			continue
+		}
+		t := strings.ReplaceAll(part.Text, "{", "{{")
+		sb.WriteString(strings.ReplaceAll(t, "}", "}}"))
+	}
+	sb.WriteString("\"")
+	return sb.String()
+}
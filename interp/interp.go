@@ -0,0 +1,348 @@
+package interp
+
+import (
+	"bufio"
+	"csigma/parser"
+	"csigma/semantic"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Interpretador: executa a AST já validada pelo Analisador Semântico, sem
+// gerar código, com o mesmo comportamento do programa compilado:
+//
+//   - inteiros de 64 bits com transbordo em complemento de dois, divisão que
+//     trunca em direção a zero e decimais IEEE 754;
+//   - saída no formato do printf (%ld, %g, %s);
+//   - 'input' como o scanf, repetindo o prompt com entrada inválida
+//     (ou falhando, com AbortOnInvalidInput) e ligando eof() no fim da entrada;
+//   - verificação dos índices dos vetores, com a mesma mensagem de erro.
+//
+// A execução é um comando por vez (Exec), o que serve ao REPL e ao depurador.
+
+// Options: Como no pacote ir, a política para entrada inválida.
+type Options struct {
+	AbortOnInvalidInput bool
+}
+
+// RuntimeError: Um erro de execução. Message é o que o programa compilado
+// escreveria no stderr antes de terminar.
+type RuntimeError struct {
+	Line    int
+	Message string
+}
+
+func (e *RuntimeError) Error() string { return e.Message }
+
+// Value: Um valor do Sigma. Tipo diz qual dos campos vale.
+type Value struct {
+	Tipo  string // SIGMA_INT, SIGMA_FLT ou SIGMA_STR
+	Int   int64
+	Float float64
+	Str   string
+}
+
+// String: O valor como o printf o escreveria.
+func (v Value) String() string {
+	switch v.Tipo {
+	case "SIGMA_FLT":
+		return FormatFloat(v.Float)
+	case "SIGMA_STR":
+		return v.Str
+	}
+	return strconv.FormatInt(v.Int, 10)
+}
+
+// FormatFloat: Um decimal no formato %g do printf: 6 algarismos
+// significativos, sem zeros à direita, com 'inf', 'nan' e '-0'.
+func FormatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		if math.Signbit(f) {
+			return "-nan"
+		}
+		return "nan"
+	}
+	return strconv.FormatFloat(f, 'g', 6, 64)
+}
+
+// Variable: Uma variável (ou constante) declarada, com o valor atual.
+type Variable struct {
+	Name     string
+	Tipo     string
+	Constant bool
+	Value    Value   // Variáveis simples e constantes
+	Elements []Value // Vetores
+}
+
+// Machine: O estado do programa em execução.
+type Machine struct {
+	Symbols map[string]semantic.Simbolo // Tabela do Analisador Semântico
+
+	in     *bufio.Reader
+	out    io.Writer
+	opts   Options
+	vars   map[string]Value
+	arrays map[string][]Value
+	order  []string // Nomes na ordem da declaração
+	eof    bool     // Um 'input' encontrou o fim da entrada
+}
+
+// New: Uma máquina vazia. symbols é a Tabela de Símbolos do Analisador
+// Semântico que validou os comandos; a máquina só a lê.
+func New(symbols map[string]semantic.Simbolo, in *bufio.Reader, out io.Writer, opts Options) *Machine {
+	return &Machine{Symbols: symbols, in: in, out: out, opts: opts,
+		vars: map[string]Value{}, arrays: map[string][]Value{}}
+}
+
+// Variables: As variáveis e constantes declaradas até agora, na ordem do fonte.
+func (m *Machine) Variables() []Variable {
+	var list []Variable
+	for _, name := range m.order {
+		sym := m.Symbols[name]
+		v := Variable{Name: name, Tipo: sym.Tipo, Constant: sym.Constante}
+		switch {
+		case sym.Constante:
+			v.Value = m.constant(sym)
+		case sym.Tamanho > 0:
+			v.Elements = append([]Value(nil), m.arrays[name]...)
+		default:
+			v.Value = m.vars[name]
+		}
+		list = append(list, v)
+	}
+	return list
+}
+
+// Declared: A variável (ou constante) já passou pela sua declaração.
+func (m *Machine) Declared(name string) bool {
+	for _, n := range m.order {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Exec: Executa um comando.
+func (m *Machine) Exec(stmt parser.Statement) error {
+	switch s := stmt.(type) {
+	case *parser.VarDeclNode:
+		sym := m.Symbols[s.Name]
+		if sym.Tamanho > 0 {
+			elems := make([]Value, sym.Tamanho)
+			for i := range elems {
+				elems[i] = Value{Tipo: sym.Tipo}
+			}
+			m.arrays[s.Name] = elems
+		} else {
+			m.vars[s.Name] = literal(s.Value, sym.Tipo)
+		}
+		m.order = append(m.order, s.Name)
+	case *parser.ConstDeclNode:
+		m.order = append(m.order, s.Name)
+	case *parser.AssignmentNode:
+		v, err := m.Eval(&s.ExprNode, s.Line)
+		if err != nil {
+			return err
+		}
+		return m.store(s.Dest, s.DestIndex, v, s.Line)
+	case *parser.PrintNode:
+		text, err := m.text(s, s.Line)
+		if err != nil {
+			return err
+		}
+		io.WriteString(m.out, text)
+	case *parser.InputNode:
+		return m.input(s)
+	}
+	return nil
+}
+
+// store: Grava o valor na variável ou no elemento v[i].
+func (m *Machine) store(name string, index *parser.ExprNode, v Value, line int) error {
+	if index == nil {
+		m.vars[name] = v
+		return nil
+	}
+	i, err := m.index(name, index, line)
+	if err != nil {
+		return err
+	}
+	m.arrays[name][i] = v
+	return nil
+}
+
+// text: O que um 'print' ou 'write' escreve. Como no printf, todos os
+// valores são calculados (e os índices verificados) antes de escrever.
+func (m *Machine) text(s *parser.PrintNode, line int) (string, error) {
+	var sb strings.Builder
+	if s.Parts == nil {
+		sb.WriteString(s.Value)
+	}
+	for _, part := range s.Parts {
+		if part.Expr == nil {
+			sb.WriteString(part.Text)
+			continue
+		}
+		v, err := m.Eval(part.Expr, line)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(v.String())
+	}
+	if !s.NoNewline {
+		sb.WriteString("\n")
+	}
+	return sb.String(), nil
+}
+
+// input: Repete prompt e leitura até vir um número; no fim da entrada a
+// variável recebe 0 e eof() passa a valer 1.
+func (m *Machine) input(s *parser.InputNode) error {
+	for {
+		if s.Prompt != nil {
+			text, err := m.text(s.Prompt, s.Line)
+			if err != nil {
+				return err
+			}
+			io.WriteString(m.out, text)
+		}
+		if s.Index != nil {
+			if _, err := m.index(s.VarName, s.Index, s.Line); err != nil {
+				return err
+			}
+		}
+		v, r := m.scan(s.Tipo)
+		if r < 0 {
+			m.eof = true
+			return m.store(s.VarName, s.Index, Value{Tipo: s.Tipo}, s.Line)
+		}
+		if r > 0 {
+			return m.store(s.VarName, s.Index, v, s.Line)
+		}
+		if m.opts.AbortOnInvalidInput {
+			return &RuntimeError{s.Line, fmt.Sprintf("Erro de execucao: entrada invalida para a variavel '%s'", s.VarName)}
+		}
+		io.WriteString(m.out, "Entrada invalida, tente novamente.\n")
+	}
+}
+
+// --- EXPRESSÕES ---
+
+// Eval: Calcula a expressão (já tipada) da esquerda para a direita. line é
+// a linha do comando, citada nos erros de execução.
+func (m *Machine) Eval(e *parser.ExprNode, line int) (Value, error) {
+	acc, err := m.operand(e.First, e.Tipo, line)
+	if err != nil {
+		return acc, err
+	}
+	for _, op := range e.Ops {
+		v, err := m.operand(op.Operand, e.Tipo, line)
+		if err != nil {
+			return acc, err
+		}
+		if e.Tipo == "SIGMA_FLT" {
+			acc.Float = arithFloat(acc.Float, op.Operator, v.Float)
+			continue
+		}
+		if op.Operator == "/" && (v.Int == 0 || (acc.Int == math.MinInt64 && v.Int == -1)) {
+			// O idiv derruba o programa compilado com SIGFPE.
+			return acc, &RuntimeError{line, fmt.Sprintf("Erro de execucao (linha %d): divisao inteira de %d por %d (o programa compilado termina com SIGFPE)", line, acc.Int, v.Int)}
+		}
+		acc.Int = arithInt(acc.Int, op.Operator, v.Int)
+	}
+	return acc, nil
+}
+
+func arithInt(a int64, op string, b int64) int64 {
+	switch op {
+	case "+":
+		return a + b
+	case "-":
+		return a - b
+	case "*":
+		return a * b
+	}
+	return a / b
+}
+
+func arithFloat(a float64, op string, b float64) float64 {
+	switch op {
+	case "+":
+		return a + b
+	case "-":
+		return a - b
+	case "*":
+		return a * b
+	}
+	return a / b
+}
+
+// operand: O valor de um literal, variável, v[i], constante ou chamada.
+// tipo é o da expressão: no Sigma os operandos nunca misturam tipos.
+func (m *Machine) operand(o parser.Operand, tipo string, line int) (Value, error) {
+	switch {
+	case o.IsString:
+		return Value{Tipo: "SIGMA_STR", Str: o.Value}, nil
+	case o.IsCall && o.Value == "len":
+		return Value{Tipo: "SIGMA_INT", Int: int64(m.Symbols[o.Args[0].Value].Tamanho)}, nil
+	case o.IsCall: // eof()
+		v := Value{Tipo: "SIGMA_INT"}
+		if m.eof {
+			v.Int = 1
+		}
+		return v, nil
+	case o.Index != nil:
+		i, err := m.index(o.Value, o.Index, line)
+		if err != nil {
+			return Value{}, err
+		}
+		return m.arrays[o.Value][i], nil
+	case o.IsVar:
+		if sym := m.Symbols[o.Value]; sym.Constante {
+			return m.constant(sym), nil
+		}
+		return m.vars[o.Value], nil
+	}
+	return literal(o.Value, tipo), nil
+}
+
+// index: Calcula e verifica o índice de v[i].
+func (m *Machine) index(name string, index *parser.ExprNode, line int) (int, error) {
+	v, err := m.Eval(index, line)
+	if err != nil {
+		return 0, err
+	}
+	size := int64(len(m.arrays[name]))
+	if uint64(v.Int) >= uint64(size) {
+		return 0, &RuntimeError{line, fmt.Sprintf("Erro de execucao (linha %d): indice %d fora dos limites de '%s' (0..%d)", line, v.Int, name, size-1)}
+	}
+	return int(v.Int), nil
+}
+
+// constant: O valor que o Analisador Semântico calculou para a constante.
+func (m *Machine) constant(sym semantic.Simbolo) Value {
+	return literal(sym.Valor, sym.Tipo)
+}
+
+// literal: O valor de um literal do fonte, no tipo dado.
+func literal(text, tipo string) Value {
+	v := Value{Tipo: tipo}
+	switch tipo {
+	case "SIGMA_FLT":
+		v.Float, _ = strconv.ParseFloat(text, 64)
+	case "SIGMA_STR":
+		v.Str = text
+	default:
+		v.Int, _ = strconv.ParseInt(text, 10, 64)
+	}
+	return v
+}
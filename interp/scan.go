@@ -0,0 +1,91 @@
+package interp
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// scan: Lê um número como o scanf("%ld") ou scanf("%lf") do programa
+// compilado: pula os espaços, lê o maior prefixo que forma um número e deixa
+// o resto na entrada. Devolve 1 (ok), 0 (texto inválido, com o resto da
+// linha descartado) ou -1 (fim da entrada).
+func (m *Machine) scan(tipo string) (Value, int) {
+	for {
+		c, err := m.in.ReadByte()
+		if err != nil {
+			return Value{}, -1
+		}
+		if !isSpace(c) {
+			m.in.UnreadByte()
+			break
+		}
+	}
+
+	var text strings.Builder
+	accept := func(ok func(byte) bool) bool {
+		c, err := m.in.ReadByte()
+		if err != nil {
+			return false
+		}
+		if !ok(c) {
+			m.in.UnreadByte()
+			return false
+		}
+		text.WriteByte(c)
+		return true
+	}
+	isSign := func(c byte) bool { return c == '+' || c == '-' }
+	digits := 0
+	accept(isSign)
+	for accept(isDigit) {
+		digits++
+	}
+	if tipo == "SIGMA_FLT" {
+		if accept(func(c byte) bool { return c == '.' }) {
+			for accept(isDigit) {
+				digits++
+			}
+		}
+		if digits > 0 && accept(func(c byte) bool { return c == 'e' || c == 'E' }) {
+			accept(isSign)
+			for accept(isDigit) {
+			}
+		}
+	}
+
+	if digits == 0 {
+		// O programa compilado descarta o resto da linha e tenta de novo.
+		for {
+			c, err := m.in.ReadByte()
+			if err != nil {
+				return Value{}, -1
+			}
+			if c == '\n' {
+				return Value{}, 0
+			}
+		}
+	}
+
+	s := text.String()
+	if tipo == "SIGMA_FLT" {
+		s = strings.TrimRight(s, "eE+-") // '1e' ou '1e+' sem expoente: vale o '1'
+		f, _ := strconv.ParseFloat(s, 64)
+		return Value{Tipo: tipo, Float: f}, 1
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		// Como o strtol, satura nos limites.
+		n = math.MaxInt64
+		if s[0] == '-' {
+			n = math.MinInt64
+		}
+	}
+	return Value{Tipo: tipo, Int: n}, 1
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
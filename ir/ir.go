@@ -0,0 +1,234 @@
+package ir
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Type: Tipos da representação intermediária. São os tipos da máquina,
+// não os da linguagem: SIGMA_INT vira i64, SIGMA_FLT vira f64 e textos
+// (SIGMA_STR) são ponteiros para bytes terminados em zero.
+type Type string
+
+const (
+	I64 Type = "i64"
+	F64 Type = "f64"
+	Ptr Type = "ptr"
+)
+
+// Value: Qualquer coisa que pode aparecer como operando de uma instrução.
+type Value interface {
+	Type() Type
+	String() string
+}
+
+// Temp: Temporário (registrador virtual). Cada um é definido uma única vez.
+type Temp struct {
+	ID int
+	Ty Type
+}
+
+func (t *Temp) Type() Type     { return t.Ty }
+func (t *Temp) String() string { return fmt.Sprintf("%%t%d", t.ID) }
+
+// IntConst: Inteiro imediato (ex: 42).
+type IntConst struct{ V int64 }
+
+func (c IntConst) Type() Type     { return I64 }
+func (c IntConst) String() string { return strconv.FormatInt(c.V, 10) }
+
+// FloatConst: Decimal imediato (ex: 2.5). O backend decide como materializá-lo.
+type FloatConst struct{ V float64 }
+
+func (c FloatConst) Type() Type { return F64 }
+func (c FloatConst) String() string {
+	s := strconv.FormatFloat(c.V, 'f', -1, 64)
+	if !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	return s
+}
+
+// SymAddr: Endereço de um símbolo global (variável ou string), ex: @msg_0.
+type SymAddr struct{ Name string }
+
+func (s SymAddr) Type() Type     { return Ptr }
+func (s SymAddr) String() string { return "@" + s.Name }
+
+// Mem: Um endereço de memória acessado por load/store/addr: @x ou @v[%t1].
+// O índice é sempre contado em elementos de 8 bytes.
+type Mem struct {
+	Sym   string
+	Index Value // nil para variáveis simples
+}
+
+func (m *Mem) String() string {
+	if m.Index != nil {
+		return fmt.Sprintf("@%s[%s]", m.Sym, m.Index)
+	}
+	return "@" + m.Sym
+}
+
+// Op: O código de operação de cada instrução.
+type Op string
+
+const (
+	OpCopy        Op = "copy"        // Dst = Args[0]
+	OpLoad        Op = "load"        // Dst = *Mem
+	OpStore       Op = "store"       // *Mem = Args[0]
+	OpAddr        Op = "addr"        // Dst = &Mem
+	OpAdd         Op = "add"         // Dst = Args[0] + Args[1]
+	OpSub         Op = "sub"         // Dst = Args[0] - Args[1]
+	OpMul         Op = "mul"         // Dst = Args[0] * Args[1]
+	OpDiv         Op = "div"         // Dst = Args[0] / Args[1]
+	OpShl         Op = "shl"         // Dst = Args[0] << Args[1] (só inteiros)
+	OpCall        Op = "call"        // Dst (opcional) = Func(Args...)
+	OpJump        Op = "jmp"         // Desvio incondicional para Targets[0]
+	OpBranch      Op = "br"          // Se Args[0] Cond Args[1] vai para Targets[0], senão Targets[1]
+	OpRet         Op = "ret"         // Retorna Args[0] ao chamador
+	OpUnreachable Op = "unreachable" // Depois de uma chamada que não retorna (exit)
+)
+
+// Cond: Condição de um desvio condicional.
+type Cond string
+
+const (
+	CondEq  Cond = "eq"
+	CondNe  Cond = "ne"
+	CondLt  Cond = "lt"
+	CondGt  Cond = "gt"
+	CondUlt Cond = "ult" // Menor, sem sinal (usado nos limites de vetores)
+)
+
+// Instr: Uma instrução de três endereços.
+type Instr struct {
+	Op      Op
+	Dst     *Temp
+	Args    []Value
+	Mem     *Mem
+	Func    string   // OpCall: nome da função chamada
+	Cond    Cond     // OpBranch
+	Targets []*Block // OpJump / OpBranch
+	Line    int      // Linha do fonte Sigma que originou a instrução
+	Comment string   // Descrição do comando Sigma (só na primeira instrução dele)
+}
+
+// IsTerminator: Jumps, branches, ret e unreachable encerram um bloco básico.
+func (in *Instr) IsTerminator() bool {
+	switch in.Op {
+	case OpJump, OpBranch, OpRet, OpUnreachable:
+		return true
+	}
+	return false
+}
+
+func (in *Instr) String() string {
+	args := make([]string, len(in.Args))
+	for i, a := range in.Args {
+		args[i] = a.String()
+	}
+	dst := ""
+	if in.Dst != nil {
+		dst = fmt.Sprintf("%s:%s = ", in.Dst, in.Dst.Ty)
+	}
+
+	switch in.Op {
+	case OpLoad, OpAddr:
+		return fmt.Sprintf("%s%s %s", dst, in.Op, in.Mem)
+	case OpStore:
+		return fmt.Sprintf("store %s, %s", in.Mem, args[0])
+	case OpCall:
+		return fmt.Sprintf("%scall %s(%s)", dst, in.Func, strings.Join(args, ", "))
+	case OpJump:
+		return "jmp " + in.Targets[0].Label
+	case OpBranch:
+		return fmt.Sprintf("br.%s %s, %s -> %s, %s", in.Cond, args[0], args[1], in.Targets[0].Label, in.Targets[1].Label)
+	}
+	if len(args) == 0 {
+		return dst + string(in.Op)
+	}
+	return fmt.Sprintf("%s%s %s", dst, in.Op, strings.Join(args, ", "))
+}
+
+// Block: Bloco básico. Só a última instrução pode desviar o fluxo.
+type Block struct {
+	Label  string
+	Instrs []*Instr
+}
+
+// Terminated: O bloco já termina em jmp/br/ret/unreachable?
+func (b *Block) Terminated() bool {
+	return len(b.Instrs) > 0 && b.Instrs[len(b.Instrs)-1].IsTerminator()
+}
+
+// Function: Uma função da IR. Por enquanto o Sigma só gera o 'main'.
+type Function struct {
+	Name   string
+	Blocks []*Block
+	Temps  []*Temp
+}
+
+// Global: Variável (ou vetor, com Size > 0) que vive na memória estática.
+// Init é o valor inicial literal; para textos, é o rótulo da string.
+type Global struct {
+	Name string
+	Type Type
+	Size int
+	Init string
+	Line int // Linha da declaração no fonte (0 para as criadas pelo compilador)
+}
+
+// StringConst: Texto constante terminado em zero (já com '\n', se houver).
+type StringConst struct {
+	Label string
+	Text  string
+}
+
+// Program: O resultado da tradução da AST.
+type Program struct {
+	Globals []*Global
+	Strings []*StringConst
+	Funcs   []*Function
+}
+
+// InstrCount: Total de instruções do programa (usado para medir as otimizações).
+func (p *Program) InstrCount() int {
+	n := 0
+	for _, f := range p.Funcs {
+		for _, b := range f.Blocks {
+			n += len(b.Instrs)
+		}
+	}
+	return n
+}
+
+// String: A listagem textual da IR, usada no relatório (--emit=ir).
+func (p *Program) String() string {
+	var sb strings.Builder
+	for _, s := range p.Strings {
+		sb.WriteString(fmt.Sprintf("string @%s = %s\n", s.Label, strconv.Quote(s.Text)))
+	}
+	for _, g := range p.Globals {
+		switch {
+		case g.Size > 0:
+			sb.WriteString(fmt.Sprintf("global @%s: [%d x %s]\n", g.Name, g.Size, g.Type))
+		default:
+			sb.WriteString(fmt.Sprintf("global @%s: %s = %s\n", g.Name, g.Type, g.Init))
+		}
+	}
+	for _, f := range p.Funcs {
+		sb.WriteString(fmt.Sprintf("\nfunc %s() {\n", f.Name))
+		for _, b := range f.Blocks {
+			sb.WriteString(b.Label + ":\n")
+			for _, in := range b.Instrs {
+				if in.Comment != "" {
+					sb.WriteString(fmt.Sprintf("    ; %s\n", in.Comment))
+				}
+				sb.WriteString("    " + in.String() + "\n")
+			}
+		}
+		sb.WriteString("}\n")
+	}
+	return sb.String()
+}
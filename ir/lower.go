@@ -0,0 +1,389 @@
+package ir
+
+import (
+	"csigma/parser"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Options: Escolhas de semântica feitas na linha de comando.
+type Options struct {
+	// AbortOnInvalidInput troca a política do 'input' diante de texto que não é
+	// número: em vez de pedir de novo (padrão), encerra com erro de execução.
+	AbortOnInvalidInput bool
+}
+
+// Funções de apoio chamadas pela IR. As três primeiras são o "runtime" do Sigma,
+// emitido pelo backend; as demais vêm da LibC.
+const (
+	FuncScan        = "sigma_scan"         // (formato, endereco) -> 1 ok, 0 invalido, -1 EOF
+	FuncBoundsError = "sigma_bounds_error" // (linha, nome, indice, tamanho), não retorna
+	FuncInputError  = "sigma_input_error"  // (nome), não retorna
+	FuncPrintf      = "printf"
+)
+
+// GlobalEOF: Flag devolvida por eof(), ligada quando um 'input' encontra o fim da entrada.
+const GlobalEOF = "sigma_eof"
+
+// builder: Estado da tradução AST -> IR.
+type builder struct {
+	opts   Options
+	prog   *Program
+	fn     *Function
+	cur    *Block
+	cold   []*Block // Blocos de erro, colocados no fim da função
+	labels int
+	line   int
+	note   string // Comentário pendente para a próxima instrução emitida
+
+	consts     map[string]Value  // Valor de cada constante
+	arrays     map[string]int    // Tamanho de cada vetor
+	types      map[string]Type   // Tipo de cada variável (ou dos elementos do vetor)
+	strs       map[string]string // Texto -> rótulo (textos repetidos são reaproveitados)
+	hasEOFFlag bool
+}
+
+// Lower: Traduz a AST (já validada e anotada pelo Analisador Semântico) para a IR.
+// Cada expressão linear vira uma sequência de temporários; 'input' e os acessos
+// a vetores viram blocos básicos ligados por desvios explícitos.
+func Lower(statements []parser.Statement, opts Options) *Program {
+	b := &builder{
+		opts:   opts,
+		prog:   &Program{},
+		fn:     &Function{Name: "main"},
+		consts: map[string]Value{},
+		arrays: map[string]int{},
+		types:  map[string]Type{},
+		strs:   map[string]string{},
+	}
+	b.prog.Funcs = append(b.prog.Funcs, b.fn)
+	b.startBlock(&Block{Label: "entry"})
+
+	for _, stmt := range statements {
+		switch s := stmt.(type) {
+		case *parser.VarDeclNode:
+			b.lowerVarDecl(s)
+		case *parser.ConstDeclNode:
+			b.lowerConstDecl(s)
+		case *parser.AssignmentNode:
+			b.line = s.Line
+			b.note = "Calculo Aritmetico: " + s.DestString() + " = " + s.ExprNode.String()
+			b.lowerAssignment(s)
+		case *parser.PrintNode:
+			b.line = s.Line
+			b.note = "Print: " + describePrint(s)
+			b.lowerPrint(s)
+		case *parser.InputNode:
+			b.line = s.Line
+			b.note = "Leitura de " + s.VarName
+			b.lowerInput(s)
+		}
+	}
+
+	b.note = "Fim do programa"
+	b.emit(&Instr{Op: OpRet, Args: []Value{IntConst{0}}})
+	b.fn.Blocks = append(b.fn.Blocks, b.cold...)
+	return b.prog
+}
+
+// --- DECLARAÇÕES ---
+
+func (b *builder) lowerVarDecl(s *parser.VarDeclNode) {
+	switch {
+	case s.Size > 0:
+		ty := I64
+		if s.ElemType == "float" {
+			ty = F64
+		}
+		b.arrays[s.Name] = s.Size
+		b.types[s.Name] = ty
+		b.prog.Globals = append(b.prog.Globals, &Global{Name: s.Name, Type: ty, Size: s.Size, Line: s.Line})
+	case s.IsString:
+		b.types[s.Name] = Ptr
+		label := b.newString("str", s.Value)
+		b.prog.Globals = append(b.prog.Globals, &Global{Name: s.Name, Type: Ptr, Init: label, Line: s.Line})
+	default:
+		ty := I64
+		if strings.Contains(s.Value, ".") {
+			ty = F64
+		}
+		b.types[s.Name] = ty
+		b.prog.Globals = append(b.prog.Globals, &Global{Name: s.Name, Type: ty, Init: s.Value, Line: s.Line})
+	}
+}
+
+// lowerConstDecl: Constantes não geram código nem memória: o valor calculado pelo
+// Analisador Semântico é guardado e usado como imediato.
+func (b *builder) lowerConstDecl(s *parser.ConstDeclNode) {
+	switch s.Tipo {
+	case "SIGMA_FLT":
+		v, _ := strconv.ParseFloat(s.Valor, 64)
+		b.consts[s.Name] = FloatConst{v}
+	case "SIGMA_STR":
+		b.consts[s.Name] = SymAddr{b.newString("str", s.Valor)}
+	default:
+		v, _ := strconv.ParseInt(s.Valor, 10, 64)
+		b.consts[s.Name] = IntConst{v}
+	}
+}
+
+// --- COMANDOS ---
+
+func (b *builder) lowerAssignment(s *parser.AssignmentNode) {
+	val := b.lowerExpr(&s.ExprNode)
+	mem := &Mem{Sym: s.Dest}
+	if s.DestIndex != nil {
+		mem = b.lowerIndex(s.Dest, s.DestIndex)
+	}
+	b.emit(&Instr{Op: OpStore, Mem: mem, Args: []Value{val}})
+}
+
+// lowerPrint: Monta a string de formato e uma única chamada ao printf.
+// O texto fixo tem '%' escapado e cada expressão ganha a conversão do seu tipo:
+// %ld (SIGMA_INT), %g (SIGMA_FLT) ou %s (SIGMA_STR).
+func (b *builder) lowerPrint(s *parser.PrintNode) {
+	var format strings.Builder
+	var args []Value
+
+	if s.Parts == nil {
+		format.WriteString(strings.ReplaceAll(s.Value, "%", "%%"))
+	}
+	for _, part := range s.Parts {
+		if part.Expr == nil {
+			format.WriteString(strings.ReplaceAll(part.Text, "%", "%%"))
+			continue
+		}
+		switch part.Expr.Tipo {
+		case "SIGMA_FLT":
+			format.WriteString("%g")
+		case "SIGMA_STR":
+			format.WriteString("%s")
+		default:
+			format.WriteString("%ld")
+		}
+		args = append(args, b.lowerExpr(part.Expr))
+	}
+	if !s.NoNewline {
+		format.WriteString("\n")
+	}
+
+	fmtLabel := b.newString("fmt", format.String())
+	b.emit(&Instr{Op: OpCall, Func: FuncPrintf, Args: append([]Value{SymAddr{fmtLabel}}, args...)})
+}
+
+// lowerInput: Lê um número com verificação do retorno do sigma_scan.
+//
+//	input_N:     [prompt]  r = sigma_scan(formato, &x)   r > 0 ? ok : bad
+//	input_bad_N: r < 0 ? eof : invalid
+//	input_inv_N: aviso e volta para input_N (ou erro fatal, com --input=abort)
+//	input_eof_N: eof() = 1, x = 0
+//	input_ok_N:  segue o programa
+func (b *builder) lowerInput(s *parser.InputNode) {
+	b.useEOFFlag()
+	n := b.nextLabel()
+	start := &Block{Label: fmt.Sprintf("input_%d", n)}
+	bad := &Block{Label: fmt.Sprintf("input_bad_%d", n)}
+	invalid := &Block{Label: fmt.Sprintf("input_inv_%d", n)}
+	eof := &Block{Label: fmt.Sprintf("input_eof_%d", n)}
+	ok := &Block{Label: fmt.Sprintf("input_ok_%d", n)}
+
+	note := b.note
+	b.note = ""
+	b.startBlock(start)
+	b.note = note
+	if s.Prompt != nil {
+		b.lowerPrint(s.Prompt)
+	}
+
+	mem := &Mem{Sym: s.VarName}
+	if s.Index != nil {
+		mem = b.lowerIndex(s.VarName, s.Index)
+	}
+	ty := I64
+	format := "%ld"
+	if s.Tipo == "SIGMA_FLT" {
+		ty = F64
+		format = "%lf"
+	}
+
+	addr := b.newTemp(Ptr)
+	b.emit(&Instr{Op: OpAddr, Dst: addr, Mem: mem})
+	res := b.newTemp(I64)
+	b.emit(&Instr{Op: OpCall, Dst: res, Func: FuncScan, Args: []Value{SymAddr{b.newString("fmt", format)}, addr}})
+	b.emit(&Instr{Op: OpBranch, Cond: CondGt, Args: []Value{res, IntConst{0}}, Targets: []*Block{ok, bad}})
+
+	b.startBlock(bad)
+	b.emit(&Instr{Op: OpBranch, Cond: CondLt, Args: []Value{res, IntConst{0}}, Targets: []*Block{eof, invalid}})
+
+	b.startBlock(invalid)
+	if b.opts.AbortOnInvalidInput {
+		b.emit(&Instr{Op: OpCall, Func: FuncInputError, Args: []Value{SymAddr{b.newString("msg", s.VarName)}}})
+		b.emit(&Instr{Op: OpUnreachable})
+	} else {
+		b.emit(&Instr{Op: OpCall, Func: FuncPrintf, Args: []Value{SymAddr{b.newString("fmt", "Entrada invalida, tente novamente.\n")}}})
+		b.emit(&Instr{Op: OpJump, Targets: []*Block{start}})
+	}
+
+	b.startBlock(eof)
+	b.emit(&Instr{Op: OpStore, Mem: &Mem{Sym: GlobalEOF}, Args: []Value{IntConst{1}}})
+	var zero Value = IntConst{0}
+	if ty == F64 {
+		zero = FloatConst{0}
+	}
+	b.emit(&Instr{Op: OpStore, Mem: mem, Args: []Value{zero}})
+
+	b.startBlock(ok)
+}
+
+// --- EXPRESSÕES ---
+
+// lowerExpr: A aritmética linear vira uma cadeia de temporários:
+// 'a + b * 2' => t0 = load a; t1 = load b; t2 = add t0, t1; t3 = mul t2, 2.
+func (b *builder) lowerExpr(e *parser.ExprNode) Value {
+	ty := irType(e.Tipo)
+	acc := b.lowerOperand(e.First, ty)
+	for _, op := range e.Ops {
+		rhs := b.lowerOperand(op.Operand, ty)
+		t := b.newTemp(ty)
+		b.emit(&Instr{Op: binOps[op.Operator], Dst: t, Args: []Value{acc, rhs}})
+		acc = t
+	}
+	return acc
+}
+
+var binOps = map[string]Op{"+": OpAdd, "-": OpSub, "*": OpMul, "/": OpDiv}
+
+// lowerOperand: Literais e constantes viram imediatos; variáveis, elementos de
+// vetor e eof() viram loads explícitos.
+func (b *builder) lowerOperand(o parser.Operand, ty Type) Value {
+	switch {
+	case o.IsString:
+		return SymAddr{b.newString("str", o.Value)}
+	case o.IsCall && o.Value == "len":
+		return IntConst{int64(b.arrays[o.Args[0].Value])}
+	case o.IsCall:
+		b.useEOFFlag()
+		t := b.newTemp(I64)
+		b.emit(&Instr{Op: OpLoad, Dst: t, Mem: &Mem{Sym: GlobalEOF}})
+		return t
+	case o.IsVar:
+		if c, ok := b.consts[o.Value]; ok && o.Index == nil {
+			return c
+		}
+		mem := &Mem{Sym: o.Value}
+		if o.Index != nil {
+			mem = b.lowerIndex(o.Value, o.Index)
+		}
+		t := b.newTemp(b.types[o.Value])
+		b.emit(&Instr{Op: OpLoad, Dst: t, Mem: mem})
+		return t
+	}
+
+	if ty == F64 {
+		v, _ := strconv.ParseFloat(o.Value, 64)
+		return FloatConst{v}
+	}
+	v, _ := strconv.ParseInt(o.Value, 10, 64)
+	return IntConst{v}
+}
+
+// lowerIndex: Calcula o índice de 'v[i]' e verifica os limites em execução.
+// Só um índice constante dentro dos limites dispensa a verificação; um fora
+// deles (que o Analisador Semântico já recusa) cai no erro de sempre.
+func (b *builder) lowerIndex(name string, idx *parser.ExprNode) *Mem {
+	v := b.lowerExpr(idx)
+	size := b.arrays[name]
+	if c, ok := v.(IntConst); ok && c.V >= 0 && c.V < int64(size) {
+		return &Mem{Sym: name, Index: v}
+	}
+
+	n := b.nextLabel()
+	ok := &Block{Label: fmt.Sprintf("idx_ok_%d", n)}
+	fail := &Block{Label: fmt.Sprintf("idx_fail_%d", n)}
+	// Comparação sem sinal: um índice negativo vira um número enorme e também falha.
+	b.emit(&Instr{Op: OpBranch, Cond: CondUlt, Args: []Value{v, IntConst{int64(size)}}, Targets: []*Block{ok, fail}})
+
+	fail.Instrs = append(fail.Instrs,
+		&Instr{Op: OpCall, Func: FuncBoundsError, Line: b.line, Args: []Value{
+			IntConst{int64(b.line)}, SymAddr{b.newString("msg", name)}, v, IntConst{int64(size)},
+		}},
+		&Instr{Op: OpUnreachable, Line: b.line},
+	)
+	b.cold = append(b.cold, fail)
+
+	b.startBlock(ok)
+	return &Mem{Sym: name, Index: v}
+}
+
+// --- INFRAESTRUTURA ---
+
+func (b *builder) emit(in *Instr) {
+	in.Line = b.line
+	if b.note != "" {
+		in.Comment = b.note
+		b.note = ""
+	}
+	b.cur.Instrs = append(b.cur.Instrs, in)
+}
+
+func (b *builder) newTemp(ty Type) *Temp {
+	t := &Temp{ID: len(b.fn.Temps), Ty: ty}
+	b.fn.Temps = append(b.fn.Temps, t)
+	return t
+}
+
+// nextLabel: Número único que identifica um grupo de blocos (input_3, input_ok_3...).
+func (b *builder) nextLabel() int {
+	b.labels++
+	return b.labels - 1
+}
+
+// startBlock: Passa a emitir em 'bl'. Se o bloco atual ainda não terminou,
+// ele "cai" no novo bloco por meio de um jmp explícito.
+func (b *builder) startBlock(bl *Block) {
+	if b.cur != nil && !b.cur.Terminated() {
+		b.emit(&Instr{Op: OpJump, Targets: []*Block{bl}})
+	}
+	b.fn.Blocks = append(b.fn.Blocks, bl)
+	b.cur = bl
+}
+
+// newString: Registra um texto constante e devolve o seu rótulo.
+func (b *builder) newString(prefix, text string) string {
+	key := prefix + "\x00" + text
+	if label, ok := b.strs[key]; ok {
+		return label
+	}
+	label := fmt.Sprintf("%s_%d", prefix, len(b.prog.Strings))
+	b.strs[key] = label
+	b.prog.Strings = append(b.prog.Strings, &StringConst{Label: label, Text: text})
+	return label
+}
+
+// useEOFFlag: Declara a flag de fim de entrada na primeira vez que é necessária.
+func (b *builder) useEOFFlag() {
+	if !b.hasEOFFlag {
+		b.hasEOFFlag = true
+		b.prog.Globals = append(b.prog.Globals, &Global{Name: GlobalEOF, Type: I64, Init: "0"})
+	}
+}
+
+// irType: Converte o tipo Sigma (anotado pelo Analisador Semântico) para a IR.
+func irType(sigma string) Type {
+	switch sigma {
+	case "SIGMA_FLT":
+		return F64
+	case "SIGMA_STR":
+		return Ptr
+	}
+	return I64
+}
+
+// describePrint: Texto curto do comando para os comentários da IR.
+func describePrint(s *parser.PrintNode) string {
+	if s.IsString {
+		return strconv.Quote(s.Value)
+	}
+	return s.Parts[0].Expr.String()
+}
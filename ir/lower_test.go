@@ -0,0 +1,60 @@
+package ir
+
+import (
+	"csigma/lexer"
+	"csigma/parser"
+	"csigma/semantic"
+	"testing"
+)
+
+// lower: Fonte -> IR, passando pelo Analisador Semântico para anotar os
+// tipos. Os erros dele são ignorados: os índices fora dos limites que ele
+// recusa também precisam virar código seguro aqui.
+func lower(t *testing.T, src string) *Program {
+	t.Helper()
+	statements, err := parser.NewParser(lexer.Tokenize(src)).ParseProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+	semantic.NewAnalyzer().Validar(statements)
+	return Lower(statements, Options{})
+}
+
+// boundsChecks: Quantas chamadas a sigma_bounds_error o programa tem.
+func boundsChecks(p *Program) int {
+	n := 0
+	for _, f := range p.Funcs {
+		for _, b := range f.Blocks {
+			for _, in := range b.Instrs {
+				if in.Op == OpCall && in.Func == FuncBoundsError {
+					n++
+				}
+			}
+		}
+	}
+	return n
+}
+
+// Só um índice constante dentro dos limites dispensa a verificação em execução.
+func TestLowerIndexBoundsCheck(t *testing.T) {
+	tests := []struct {
+		name, src string
+		checks    int
+	}{
+		{"literal", "var v[2]\nv[1] = 1\n", 0},
+		{"constante", "const X = 1\nvar v[2]\nv[X] = 1\n", 0},
+		{"variavel", "var v[2]\nvar i = 1\nv[i] = 1\n", 1},
+		{"expressao", "const X = 2\nvar v[2]\nv[X - 1] = 1\n", 1},
+		{"leitura", "var v[2]\nvar i = 1\nprint v[i]\n", 1},
+		{"input", "var v[2]\nvar i = 1\ninput v[i]\n", 1},
+		{"constante fora", "const X = 2\nvar v[X]\nv[X] = 1\n", 1},
+		{"len fora", "var v[2]\nv[len(v)] = 1\n", 1},
+		{"negativo", "const X = 0 - 1\nvar v[2]\nprint v[X]\n", 1},
+	}
+	for _, tt := range tests {
+		p := lower(t, tt.src)
+		if got := boundsChecks(p); got != tt.checks {
+			t.Errorf("%s: %d verificações de limites, quer %d\n%s", tt.name, got, tt.checks, p)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package lexer
 
+import "strings"
+
 // TokenType define a categoria do símbolo encontrado.
 // Usamos string para que o Log seja legível (ex: "PRINT" em vez de um número 7).
 type TokenType string
@@ -7,7 +9,9 @@ type TokenType string
 const (
 	// Palavras-Chave (Keywords)
 	TokenVar   TokenType = "VAR"
+	TokenConst TokenType = "CONST"
 	TokenPrint TokenType = "PRINT"
+	TokenWrite TokenType = "WRITE" // Como print, mas sem quebra de linha
 	TokenInput TokenType = "INPUT"
 
 	// Identificadores e Literais
@@ -24,10 +28,12 @@ const (
 	TokenDiv    TokenType = "/"
 
 	// Pontuação e Delimitadores
-	TokenLParen TokenType = "("
-	TokenRParen TokenType = ")"
-	TokenComma  TokenType = ","
-	TokenColon  TokenType = ":"
+	TokenLParen   TokenType = "("
+	TokenRParen   TokenType = ")"
+	TokenLBracket TokenType = "["
+	TokenRBracket TokenType = "]"
+	TokenComma    TokenType = ","
+	TokenColon    TokenType = ":"
 
 	// Tokens Especiais
 	TokenEOF     TokenType = "EOF"     // End Of File: Fim do arquivo
@@ -37,6 +43,16 @@ const (
 type Token struct {
 	Type    TokenType
 	Literal string
+	Line    int // Linha do fonte (começando em 1), usada nas mensagens de erro
+	Column  int // Coluna (em bytes, começando em 1) do primeiro caractere
+}
+
+// Comment: Um comentário '// ...' (trivia): não vira token, mas o lexer o
+// guarda com a posição para quem reescreve o fonte (o formatador).
+type Comment struct {
+	Text   string // Com as barras, sem a quebra de linha
+	Line   int
+	Column int
 }
 
 type Lexer struct {
@@ -44,10 +60,13 @@ type Lexer struct {
 	position     int    // Posição atual do caractere sendo lido (ch)
 	readPosition int    // Posição da "espiada" (próximo caractere)
 	ch           byte   // Caractere atual sob análise
+	line         int    // Linha em que está o caractere atual
+	lineStart    int    // Posição do primeiro caractere da linha atual
+	comments     []Comment
 }
 
 func NewLexer(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
 	l.readChar() // Inicializa o lexer lendo o primeiro caractere
 	return l
 }
@@ -55,6 +74,10 @@ func NewLexer(input string) *Lexer {
 // readChar: Avança o ponteiro de leitura.
 // ch recebe 0 (ASCII Nul) se chegarmos ao fim do arquivo.
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++ // Deixamos uma quebra de linha para trás
+		l.lineStart = l.readPosition
+	}
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
@@ -69,6 +92,7 @@ func (l *Lexer) NextToken() Token {
 	var tok Token
 
 	l.skipWhitespace() // Ignora espaços, tabs e quebras de linha
+	line, column := l.line, l.position-l.lineStart+1
 
 	switch l.ch {
 	case '=':
@@ -94,25 +118,32 @@ func (l *Lexer) NextToken() Token {
 		// Lógica de String: Captura tudo entre aspas.
 		tok.Type = TokenString
 		tok.Literal = l.readString()
+	case '[':
+		tok = Token{Type: TokenLBracket, Literal: string(l.ch)}
+	case ']':
+		tok = Token{Type: TokenRBracket, Literal: string(l.ch)}
 	case ':':
-		tok = Token{Type: TokenIllegal, Literal: ":"}
+		tok = Token{Type: TokenColon, Literal: string(l.ch)}
 	case ',':
-		tok = Token{Type: TokenIllegal, Literal: ","}
+		tok = Token{Type: TokenComma, Literal: string(l.ch)}
 	case 0:
 		tok = Token{Type: TokenEOF, Literal: ""}
 	default:
 		// Se for letra, lemos a palavra inteira (pode ser comando ou variável).
 		if isLetter(l.ch) {
 			literal := l.readIdentifier()
-			return Token{Type: lookupIdent(literal), Literal: literal}
+			return Token{Type: lookupIdent(literal), Literal: literal, Line: line, Column: column}
 		} else if isDigit(l.ch) {
 			// Se for dígito, lemos o número inteiro ou float.
-			return l.readNumber()
+			tok = l.readNumber()
+			tok.Line, tok.Column = line, column
+			return tok
 		} else {
 			tok = Token{Type: TokenIllegal, Literal: string(l.ch)}
 		}
 	}
 
+	tok.Line, tok.Column = line, column
 	l.readChar()
 	return tok
 }
@@ -142,14 +173,42 @@ func (l *Lexer) readNumber() Token {
 	return Token{Type: tipo, Literal: literal}
 }
 
-// skipComment: Avança o ponteiro até encontrar '\n', efetivamente ignorando o comentário.
+// skipComment: Avança o ponteiro até encontrar '\n'. O comentário não vira
+// token, mas fica guardado em Comments.
 func (l *Lexer) skipComment() {
+	c := Comment{Line: l.line, Column: l.position - l.lineStart + 1}
+	start := l.position
 	for l.ch != '\n' && l.ch != 0 {
 		l.readChar()
 	}
+	c.Text = strings.TrimRight(l.input[start:l.position], " \t\r")
+	l.comments = append(l.comments, c)
 	l.skipWhitespace()
 }
 
+// Tokens: Os tokens que faltam, até o EOF (incluído). Os comentários
+// encontrados no caminho ficam em Comments.
+func (l *Lexer) Tokens() []Token {
+	var tokens []Token
+	for {
+		tok := l.NextToken()
+		tokens = append(tokens, tok)
+		if tok.Type == TokenEOF {
+			return tokens
+		}
+	}
+}
+
+// Tokenize: Todos os tokens do fonte, terminando no EOF.
+func Tokenize(input string) []Token {
+	return NewLexer(input).Tokens()
+}
+
+// Comments: Os comentários encontrados até agora, na ordem do fonte.
+func (l *Lexer) Comments() []Comment {
+	return l.comments
+}
+
 // readString: Captura o conteúdo entre as aspas, sem incluir as próprias aspas no Literal.
 func (l *Lexer) readString() string {
 	position := l.position + 1
@@ -170,11 +229,13 @@ func (l *Lexer) peekChar() byte {
 	return l.input[l.readPosition]
 }
 
-// lookupIdent: Verifica se uma palavra é um comando do Sigma (var, print, input) ou uma variável.
+// lookupIdent: Verifica se uma palavra é um comando do Sigma (var, const, print, write, input) ou uma variável.
 func lookupIdent(ident string) TokenType {
 	keywords := map[string]TokenType{
 		"var":   TokenVar,
+		"const": TokenConst,
 		"print": TokenPrint,
+		"write": TokenWrite,
 		"input": TokenInput,
 	}
 	if tok, ok := keywords[ident]; ok {
@@ -183,4 +244,28 @@ func lookupIdent(ident string) TokenType {
 	return TokenIdent
 }
 
-// ... isLetter, isDigit, readIdentifier e skipWhitespace seguem lógica padrão de leitura de texto.
+// readIdentifier: Lê uma palavra completa (letras, dígitos e '_').
+func (l *Lexer) readIdentifier() string {
+	position := l.position
+	for isLetter(l.ch) || isDigit(l.ch) {
+		l.readChar()
+	}
+	return l.input[position:l.position]
+}
+
+// skipWhitespace: Ignora espaços, tabs e quebras de linha entre os tokens.
+func (l *Lexer) skipWhitespace() {
+	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
+		l.readChar()
+	}
+}
+
+// isLetter: Letras ASCII e '_' podem compor identificadores.
+func isLetter(ch byte) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+}
+
+// isDigit: Apenas dígitos decimais (0-9).
+func isDigit(ch byte) bool {
+	return '0' <= ch && ch <= '9'
+}
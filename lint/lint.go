@@ -0,0 +1,205 @@
+package lint
+
+import (
+	"csigma/lexer"
+	"csigma/parser"
+	"csigma/semantic"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Linter (csigma lint): avisos de estilo e de prováveis erros em programas
+// que compilam. Roda sobre a AST do parser e a Tabela de Símbolos do
+// Analisador Semântico; cada regra tem um nome (o ID) e uma gravidade, que o
+// arquivo de configuração pode mudar ou desligar.
+//
+// Um comentário 'lint:ignorar' cala as regras citadas (ou todas, sem nomes)
+// na linha em que está ou, sozinho em uma linha, na linha seguinte:
+//
+//	x = 7 / 2 // lint:ignorar divisao-truncada
+//
+//	// lint:ignorar
+//	y = y
+
+// Severity: A gravidade de um diagnóstico.
+type Severity string
+
+const (
+	Error   Severity = "erro"      // Faz o 'csigma lint' terminar com código 1
+	Warning Severity = "aviso"     // Provável erro
+	Hint    Severity = "dica"      // Estilo
+	Off     Severity = "desligada" // Só na configuração: a regra não roda
+)
+
+// Rule: Uma regra, com a gravidade padrão.
+type Rule struct {
+	ID          string
+	Severity    Severity
+	Description string
+}
+
+// Semantic: O ID dos erros do Analisador Semântico, que o lint também mostra.
+// Não é uma regra: não pode ser desligado nem calado.
+const Semantic = "semantica"
+
+// Rules: As regras, na ordem em que aparecem na documentação.
+var Rules = []Rule{
+	{"variavel-nao-lida", Warning, "variável declarada e nunca lida"},
+	{"leitura-antes-de-atribuir", Warning, "variável lida antes de qualquer atribuição ou input (o valor da declaração é 0 ou vazio)"},
+	{"auto-atribuicao", Warning, "atribuição de uma variável a ela mesma (x = x)"},
+	{"divisao-truncada", Warning, "divisão inteira entre valores conhecidos que descarta o resto (7 / 2 vale 3)"},
+	{"nome-sombreado", Warning, "variável ou constante com o nome de uma função nativa (eof, len)"},
+	{"nomes-so-na-caixa", Hint, "nomes que só diferem em maiúsculas e minúsculas (total e Total)"},
+	{"sem-quebra-final", Hint, "a saída do programa termina em um 'write', sem quebra de linha"},
+}
+
+// Diagnostic: Um problema encontrado, no trecho do comando (o fim, como no
+// parser.Span, é a posição logo depois do último caractere).
+type Diagnostic struct {
+	Rule               string
+	Severity           Severity
+	Line, Column       int
+	EndLine, EndColumn int
+	Message            string
+}
+
+// String: 'linha:coluna: gravidade: mensagem [regra]'.
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s: %s [%s]", d.Line, d.Column, d.Severity, d.Message, d.Rule)
+}
+
+// Config: O arquivo de configuração (JSON), com a gravidade de cada regra
+// que muda em relação ao padrão:
+//
+//	{"regras": {"divisao-truncada": "erro", "nomes-so-na-caixa": "desligada"}}
+type Config struct {
+	Rules map[string]Severity `json:"regras"`
+}
+
+// ConfigFile: O nome do arquivo procurado por FindConfig.
+const ConfigFile = ".sigmalint.json"
+
+// FindConfig: O arquivo de configuração mais próximo, de dir para cima.
+// Vazio se não houver nenhum.
+func FindConfig(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, ConfigFile)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// LoadConfig: Lê e confere o arquivo de configuração.
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("%s: %v", path, err)
+	}
+	for id, sev := range cfg.Rules {
+		if rule(id) == nil {
+			return cfg, fmt.Errorf("%s: regra desconhecida '%s'", path, id)
+		}
+		switch sev {
+		case Error, Warning, Hint, Off:
+		default:
+			return cfg, fmt.Errorf("%s: gravidade desconhecida '%s' na regra '%s' (use erro, aviso, dica ou desligada)", path, sev, id)
+		}
+	}
+	return cfg, nil
+}
+
+func rule(id string) *Rule {
+	for i := range Rules {
+		if Rules[i].ID == id {
+			return &Rules[i]
+		}
+	}
+	return nil
+}
+
+// severity: A gravidade da regra com a configuração.
+func (cfg Config) severity(id string) Severity {
+	if sev, ok := cfg.Rules[id]; ok {
+		return sev
+	}
+	return rule(id).Severity
+}
+
+// Check: Os diagnósticos do programa, ordenados por linha. O erro só vem
+// quando o programa nem chega a formar a AST (erro sintático).
+func Check(src string, cfg Config) ([]Diagnostic, error) {
+	l := lexer.NewLexer(src)
+	tokens := l.Tokens()
+	statements, err := parser.NewParser(tokens).ParseProgram()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &checker{cfg: cfg, analyzer: semantic.NewAnalyzer()}
+	c.run(statements)
+
+	ignored := suppressions(l.Comments(), statements)
+	var diags []Diagnostic
+	for _, d := range c.diags {
+		if d.Rule != Semantic && (ignored[d.Line][d.Rule] || ignored[d.Line][""]) {
+			continue
+		}
+		diags = append(diags, d)
+	}
+	sort.SliceStable(diags, func(i, j int) bool { return diags[i].Line < diags[j].Line })
+	return diags, nil
+}
+
+// suppressionTag: O começo do comentário que cala regras.
+const suppressionTag = "lint:ignorar"
+
+// suppressions: Linha -> regras caladas nela ("" cala todas).
+func suppressions(comments []lexer.Comment, statements []parser.Statement) map[int]map[string]bool {
+	code := map[int]bool{} // Linhas com código
+	for _, stmt := range statements {
+		sp := parser.SpanOf(stmt)
+		for line := sp.Line; line <= sp.EndLine; line++ {
+			code[line] = true
+		}
+	}
+	ignored := map[int]map[string]bool{}
+	for _, c := range comments {
+		text := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+		if !strings.HasPrefix(text, suppressionTag) {
+			continue
+		}
+		line := c.Line
+		if !code[line] {
+			line++ // Sozinho na linha: vale para a seguinte
+		}
+		if ignored[line] == nil {
+			ignored[line] = map[string]bool{}
+		}
+		ids := strings.FieldsFunc(text[len(suppressionTag):], func(r rune) bool { return r == ' ' || r == ',' || r == '\t' })
+		if len(ids) == 0 {
+			ignored[line][""] = true
+		}
+		for _, id := range ids {
+			ignored[line][id] = true
+		}
+	}
+	return ignored
+}
@@ -0,0 +1,229 @@
+package lint
+
+import (
+	"csigma/parser"
+	"csigma/semantic"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// checker: Percorre os comandos na ordem do fonte (o Sigma ainda não tem
+// desvios, então a ordem do texto é a ordem de execução), validando cada um
+// no Analisador Semântico antes de aplicar as regras.
+type checker struct {
+	cfg      Config
+	analyzer *semantic.SemanticAnalyzer
+	diags    []Diagnostic
+
+	vars     []string               // Variáveis, na ordem da declaração
+	decl     map[string]parser.Span // Onde cada nome foi declarado
+	lower    map[string]string      // Nome em minúsculas -> primeiro nome declarado
+	read     map[string]bool        // Variáveis lidas em algum lugar
+	assigned map[string]bool        // Variáveis que já receberam um valor
+	warned   map[string]bool        // Já avisadas por leitura antes da atribuição
+	output   *parser.PrintNode      // Último 'print'/'write' (nil depois de um 'input')
+}
+
+func (c *checker) run(statements []parser.Statement) {
+	c.decl = map[string]parser.Span{}
+	c.lower = map[string]string{}
+	c.read = map[string]bool{}
+	c.assigned = map[string]bool{}
+	c.warned = map[string]bool{}
+
+	for _, stmt := range statements {
+		sp := parser.SpanOf(stmt)
+		before := len(c.analyzer.Erros)
+		c.analyzer.Validar([]parser.Statement{stmt})
+		for _, msg := range c.analyzer.Erros[before:] {
+			c.diags = append(c.diags, diagnostic(Semantic, Error, sp, msg))
+		}
+		c.statement(stmt, sp)
+	}
+
+	for _, name := range c.vars {
+		if !c.read[name] {
+			c.report("variavel-nao-lida", c.decl[name], "'%s' é declarada mas nunca lida", name)
+		}
+	}
+	if c.output != nil && c.output.NoNewline {
+		c.report("sem-quebra-final", c.output.Span, "a saída termina sem quebra de linha e o prompt do terminal fica colado nela (use 'print' no último comando)")
+	}
+}
+
+// report: Registra um diagnóstico da regra, com a gravidade configurada.
+func (c *checker) report(id string, sp parser.Span, format string, args ...interface{}) {
+	sev := c.cfg.severity(id)
+	if sev == Off {
+		return
+	}
+	c.diags = append(c.diags, diagnostic(id, sev, sp, fmt.Sprintf(format, args...)))
+}
+
+func diagnostic(id string, sev Severity, sp parser.Span, msg string) Diagnostic {
+	return Diagnostic{Rule: id, Severity: sev, Line: sp.Line, Column: sp.Column,
+		EndLine: sp.EndLine, EndColumn: sp.EndColumn, Message: msg}
+}
+
+func (c *checker) statement(stmt parser.Statement, sp parser.Span) {
+	switch s := stmt.(type) {
+	case *parser.VarDeclNode:
+		if c.declare(s.Name, sp) {
+			c.vars = append(c.vars, s.Name)
+			// 'var x = 0' é só a reserva da variável: o valor de verdade vem
+			// depois, de uma atribuição ou de um 'input'.
+			c.assigned[s.Name] = s.ElemType == "" && !placeholder(s.Value, s.IsString)
+		}
+	case *parser.ConstDeclNode:
+		c.expr(&s.ExprNode, sp)
+		c.declare(s.Name, sp)
+	case *parser.PrintNode:
+		c.print(s, sp)
+		c.output = s
+	case *parser.InputNode:
+		if s.Prompt != nil {
+			c.print(s.Prompt, sp)
+		}
+		if s.Index != nil {
+			c.expr(s.Index, sp)
+		}
+		c.assigned[s.VarName] = true
+		c.output = nil // O Enter do usuário já quebrou a linha
+	case *parser.AssignmentNode:
+		if len(s.Ops) == 0 && s.First.IsVar && s.First.Value == s.Dest && sameIndex(s.First.Index, s.DestIndex) {
+			c.report("auto-atribuicao", sp, "'%s' recebe o próprio valor", s.DestString())
+		}
+		if s.DestIndex != nil {
+			c.expr(s.DestIndex, sp)
+		}
+		c.expr(&s.ExprNode, sp)
+		c.assigned[s.Dest] = true
+	}
+}
+
+// declare: Confere o nome de uma declaração. Falso se ele já existia (o
+// Analisador Semântico já acusou o erro).
+func (c *checker) declare(name string, sp parser.Span) bool {
+	if _, exists := c.decl[name]; exists {
+		return false
+	}
+	c.decl[name] = sp
+	if _, builtin := semantic.FuncaoEmbutida(name); builtin {
+		c.report("nome-sombreado", sp, "'%s' tem o nome da função nativa %s()", name, name)
+	}
+	lower := strings.ToLower(name)
+	if first, ok := c.lower[lower]; ok {
+		c.report("nomes-so-na-caixa", sp, "'%s' só difere de '%s' (linha %d) em maiúsculas e minúsculas", name, first, c.decl[first].Line)
+	} else {
+		c.lower[lower] = name
+	}
+	return true
+}
+
+// print: As expressões de um 'print', 'write' ou prompt de 'input'.
+func (c *checker) print(s *parser.PrintNode, sp parser.Span) {
+	for _, part := range s.Parts {
+		if part.Expr != nil {
+			c.expr(part.Expr, sp)
+		}
+	}
+}
+
+// expr: Marca as variáveis lidas na expressão e procura divisões truncadas.
+func (c *checker) expr(e *parser.ExprNode, sp parser.Span) {
+	c.operand(e.First, sp)
+	for _, op := range e.Ops {
+		c.operand(op.Operand, sp)
+	}
+	if e.Tipo == "SIGMA_INT" {
+		c.division(e, sp)
+	}
+}
+
+func (c *checker) operand(o parser.Operand, sp parser.Span) {
+	switch {
+	case o.IsCall:
+		for _, arg := range o.Args {
+			if o.Value == "len" && arg.IsVar && arg.Index == nil {
+				c.read[arg.Value] = true // len(v) só usa o tamanho, não os elementos
+				continue
+			}
+			c.operand(arg, sp)
+		}
+	case o.IsVar:
+		if o.Index != nil {
+			c.expr(o.Index, sp)
+		}
+		c.read[o.Value] = true
+		if _, isVar := c.assigned[o.Value]; isVar && !c.assigned[o.Value] && !c.warned[o.Value] {
+			c.warned[o.Value] = true
+			c.report("leitura-antes-de-atribuir", sp, "'%s' é lida antes de receber um valor (atribuição ou input)", o.Value)
+		}
+	}
+}
+
+// division: Calcula a expressão inteira da esquerda para a direita enquanto
+// os valores forem conhecidos (literais e constantes) e avisa quando uma
+// divisão descarta o resto.
+func (c *checker) division(e *parser.ExprNode, sp parser.Span) {
+	acc, known := c.value(e.First)
+	for _, op := range e.Ops {
+		n, ok := c.value(op.Operand)
+		if !known || !ok {
+			known = false
+			continue
+		}
+		switch op.Operator {
+		case "+":
+			acc += n
+		case "-":
+			acc -= n
+		case "*":
+			acc *= n
+		case "/":
+			if n == 0 {
+				known = false // Erro de compilação, não um aviso
+				continue
+			}
+			if acc%n != 0 {
+				c.report("divisao-truncada", sp, "%d / %d vale %d na divisão inteira (o resto %d é descartado); use decimais, como %d.0 / %d.0", acc, n, acc/n, acc%n, acc, n)
+			}
+			acc /= n
+		}
+	}
+}
+
+// value: O valor inteiro de um literal ou de uma constante.
+func (c *checker) value(o parser.Operand) (int64, bool) {
+	if o.IsCall || o.IsString || o.Index != nil {
+		return 0, false
+	}
+	text := o.Value
+	if o.IsVar {
+		sym, ok := c.analyzer.TabelaSimbolos[o.Value]
+		if !ok || !sym.Constante {
+			return 0, false
+		}
+		text = sym.Valor
+	}
+	n, err := strconv.ParseInt(text, 10, 64)
+	return n, err == nil
+}
+
+// placeholder: Valor inicial que só reserva a variável: 0, 0.0 ou "".
+func placeholder(value string, isString bool) bool {
+	if isString {
+		return value == ""
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	return err == nil && f == 0
+}
+
+// sameIndex: Os dois índices são a mesma expressão (ou ambos ausentes).
+func sameIndex(a, b *parser.ExprNode) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.String() == b.String()
+}
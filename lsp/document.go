@@ -0,0 +1,239 @@
+package lsp
+
+import (
+	"csigma/lexer"
+	"csigma/lint"
+	"csigma/parser"
+	"csigma/semantic"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// document: Um arquivo aberto no editor, já analisado. A análise é refeita do
+// zero a cada mudança: os programas Sigma são pequenos.
+type document struct {
+	uri        string
+	text       string
+	lines      []string
+	tokens     []lexer.Token // Inclui os tokens das expressões '{...}' dos textos
+	comments   []lexer.Comment
+	statements []parser.Statement // nil com erro sintático
+	symbols    map[string]semantic.Simbolo
+	defs       map[string]lexer.Token // Nome -> token do nome na declaração
+	diags      []Diagnostic
+}
+
+// analyze: Passa o texto pelo lexer, parser, Analisador Semântico e linter.
+// Um pânico em alguma fase vira um diagnóstico, e não derruba o servidor.
+func analyze(uri, text string) (doc *document) {
+	doc = &document{uri: uri, text: text, lines: strings.Split(text, "\n"),
+		symbols: map[string]semantic.Simbolo{}, defs: map[string]lexer.Token{}}
+	defer func() {
+		if r := recover(); r != nil {
+			doc.diags = append(doc.diags, Diagnostic{Severity: 1, Source: "csigma",
+				Message: fmt.Sprintf("erro interno do compilador: %v", r)})
+		}
+	}()
+
+	l := lexer.NewLexer(text)
+	tokens := l.Tokens()
+	for _, tok := range tokens {
+		if tok.Type == lexer.TokenIllegal {
+			doc.illegal(tok)
+		}
+	}
+	doc.comments = l.Comments()
+	for i, tok := range tokens {
+		doc.tokens = append(doc.tokens, tok)
+		if tok.Type == lexer.TokenString {
+			doc.tokens = append(doc.tokens, embedded(tok)...)
+		}
+		if (tok.Type == lexer.TokenVar || tok.Type == lexer.TokenConst) && i+1 < len(tokens) && tokens[i+1].Type == lexer.TokenIdent {
+			if _, ok := doc.defs[tokens[i+1].Literal]; !ok {
+				doc.defs[tokens[i+1].Literal] = tokens[i+1]
+			}
+		}
+	}
+
+	p := parser.NewParser(tokens)
+	statements, err := p.ParseProgram()
+	if err != nil {
+		doc.diags = append(doc.diags, Diagnostic{Range: doc.tokenRange(p.Token()), Severity: 1, Source: "csigma", Message: err.Error()})
+		return doc
+	}
+	doc.statements = statements
+
+	// Os diagnósticos vêm do linter, que também valida cada comando no
+	// Analisador Semântico; a Tabela de Símbolos vem de uma análise à parte.
+	var cfg lint.Config
+	if path := uriPath(uri); path != "" {
+		if cfgPath := lint.FindConfig(filepath.Dir(path)); cfgPath != "" {
+			if cfg, err = lint.LoadConfig(cfgPath); err != nil {
+				doc.diags = append(doc.diags, Diagnostic{Severity: 2, Source: "csigma", Message: err.Error()})
+			}
+		}
+	}
+	diags, _ := lint.Check(text, cfg)
+	for _, d := range diags {
+		doc.diags = append(doc.diags, Diagnostic{
+			Range:    Range{doc.position(d.Line, d.Column), doc.position(d.EndLine, d.EndColumn)},
+			Severity: severity(d.Severity), Code: d.Rule, Source: "csigma", Message: d.Message,
+		})
+	}
+	analyzer := semantic.NewAnalyzer()
+	analyzer.Validar(statements)
+	doc.symbols = analyzer.TabelaSimbolos
+	return doc
+}
+
+// illegal: Avisa de um caractere que o lexer não reconhece. O lexer lê
+// bytes: um caractere acentuado chega como vários tokens, e só o primeiro
+// byte gera o aviso.
+func (d *document) illegal(tok lexer.Token) {
+	text := d.lines[tok.Line-1][tok.Column-1:]
+	if utf8.RuneStart(text[0]) {
+		r, size := utf8.DecodeRuneInString(text)
+		d.diags = append(d.diags, Diagnostic{
+			Range:    Range{d.position(tok.Line, tok.Column), d.position(tok.Line, tok.Column+size)},
+			Severity: 2, Source: "csigma",
+			Message: fmt.Sprintf("caractere inválido '%c' (ignorado pelo compilador)", r),
+		})
+	}
+}
+
+// severity: A gravidade do lint na escala do protocolo.
+func severity(s lint.Severity) int {
+	switch s {
+	case lint.Error:
+		return 1
+	case lint.Warning:
+		return 2
+	}
+	return 4
+}
+
+// embedded: Os tokens das expressões '{...}' de um texto interpolado, com as
+// posições no arquivo. Textos com quebra de linha ficam de fora.
+func embedded(str lexer.Token) []lexer.Token {
+	raw := str.Literal
+	if strings.Contains(raw, "\n") {
+		return nil
+	}
+	var tokens []lexer.Token
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '{' {
+			continue
+		}
+		if i+1 < len(raw) && raw[i+1] == '{' {
+			i++
+			continue
+		}
+		end := strings.IndexByte(raw[i+1:], '}')
+		if end < 0 {
+			break
+		}
+		offset := str.Column + 1 + i // Coluna do '{' (a do token é a das aspas)
+		for _, tok := range lexer.Tokenize(raw[i+1 : i+1+end]) {
+			if tok.Type == lexer.TokenEOF {
+				break
+			}
+			tok.Line = str.Line
+			tok.Column += offset
+			tokens = append(tokens, tok)
+		}
+		i += end + 1
+	}
+	return tokens
+}
+
+// length: O tamanho do token no fonte, em bytes.
+func length(tok lexer.Token) int {
+	if tok.Type == lexer.TokenString {
+		return len(tok.Literal) + 2
+	}
+	return len(tok.Literal)
+}
+
+// tokenRange: O trecho de um token (um texto com quebras de linha termina na
+// linha em que começou).
+func (d *document) tokenRange(tok lexer.Token) Range {
+	end := tok.Column + length(tok)
+	if n := strings.IndexByte(tok.Literal, '\n'); n >= 0 {
+		end = tok.Column + 1 + n
+	}
+	return Range{d.position(tok.Line, tok.Column), d.position(tok.Line, end)}
+}
+
+// position: Converte linha e coluna do lexer (a partir de 1, em bytes) para
+// a posição do protocolo.
+func (d *document) position(line, column int) Position {
+	if line < 1 {
+		return Position{}
+	}
+	if line > len(d.lines) {
+		return Position{Line: line - 1}
+	}
+	text := d.lines[line-1]
+	if column-1 > len(text) {
+		column = len(text) + 1
+	}
+	return Position{Line: line - 1, Character: utf16Len(text[:column-1])}
+}
+
+// column: O inverso de position: a coluna do lexer para uma posição.
+func (d *document) column(pos Position) (line, column int) {
+	if pos.Line < 0 || pos.Line >= len(d.lines) {
+		return pos.Line + 1, 1
+	}
+	text, units, i := d.lines[pos.Line], 0, 0
+	for i < len(text) && units < pos.Character {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		units += utf16Units(r)
+		i += size
+	}
+	return pos.Line + 1, i + 1
+}
+
+// tokenAt: O identificador na posição (o cursor pode estar logo depois dele).
+func (d *document) tokenAt(pos Position) (lexer.Token, bool) {
+	line, column := d.column(pos)
+	for _, tok := range d.tokens {
+		if tok.Type == lexer.TokenIdent && tok.Line == line && column >= tok.Column && column <= tok.Column+length(tok) {
+			return tok, true
+		}
+	}
+	return lexer.Token{}, false
+}
+
+// end: A posição do fim do documento.
+func (d *document) end() Position {
+	last := len(d.lines) - 1
+	return Position{Line: last, Character: utf16Len(d.lines[last])}
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16Units(r)
+	}
+	return n
+}
+
+func utf16Units(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+// uriPath: O caminho de um URI 'file://' (vazio para outros esquemas).
+func uriPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	return filepath.FromSlash(u.Path)
+}
@@ -0,0 +1,225 @@
+package lsp
+
+import (
+	"csigma/lexer"
+	"csigma/parser"
+	"csigma/semantic"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// hover: O tipo do identificador sob o cursor, da Tabela de Símbolos.
+func (d *document) hover(pos Position) *hover {
+	tok, ok := d.tokenAt(pos)
+	if !ok {
+		return nil
+	}
+	var text string
+	if sym, ok := d.symbols[tok.Literal]; ok {
+		text = describe(sym)
+	} else if tipo, ok := semantic.FuncaoEmbutida(tok.Literal); ok {
+		text = fmt.Sprintf("função nativa %s(): %s", tok.Literal, tipo)
+	} else {
+		return nil
+	}
+	if def, ok := d.defs[tok.Literal]; ok {
+		text += fmt.Sprintf("\n\ndeclarada na linha %d", def.Line)
+	}
+	return &hover{Contents: markupContent{Kind: "markdown", Value: text}, Range: d.tokenRange(tok)}
+}
+
+// describe: Um símbolo como aparece no hover: 'var total: SIGMA_INT',
+// 'var v: SIGMA_FLT[10]' ou 'const MAX: SIGMA_INT = 100'.
+func describe(sym semantic.Simbolo) string {
+	switch {
+	case sym.Constante:
+		return fmt.Sprintf("```\nconst %s: %s = %s\n```", sym.Nome, sym.Tipo, sym.Valor)
+	case sym.Tamanho > 0:
+		return fmt.Sprintf("```\nvar %s: %s[%d]\n```", sym.Nome, sym.Tipo, sym.Tamanho)
+	}
+	return fmt.Sprintf("```\nvar %s: %s\n```", sym.Nome, sym.Tipo)
+}
+
+// definition: Onde o identificador sob o cursor foi declarado (nil para
+// funções nativas e nomes desconhecidos).
+func (d *document) definition(pos Position) *Location {
+	tok, ok := d.tokenAt(pos)
+	if !ok {
+		return nil
+	}
+	def, ok := d.defs[tok.Literal]
+	if !ok {
+		return nil
+	}
+	return &Location{URI: d.uri, Range: d.tokenRange(def)}
+}
+
+// symbolList: As variáveis, vetores e constantes declarados, na ordem do fonte.
+func (d *document) symbolList() []documentSymbol {
+	symbols := []documentSymbol{}
+	for _, stmt := range d.statements {
+		var name string
+		var sp parser.Span
+		kind := symbolVariable
+		switch s := stmt.(type) {
+		case *parser.VarDeclNode:
+			name, sp = s.Name, s.Span
+			if s.ElemType != "" {
+				kind = symbolArray
+			}
+		case *parser.ConstDeclNode:
+			name, sp, kind = s.Name, s.Span, symbolConstant
+		default:
+			continue
+		}
+		def, ok := d.defs[name]
+		if !ok || def.Line != sp.Line {
+			continue // Declaração repetida: só a primeira conta
+		}
+		symbols = append(symbols, documentSymbol{
+			Name:           name,
+			Detail:         d.symbols[name].Tipo,
+			Kind:           kind,
+			Range:          Range{d.position(sp.Line, sp.Column), d.position(sp.EndLine, sp.EndColumn)},
+			SelectionRange: d.tokenRange(def),
+		})
+	}
+	return symbols
+}
+
+// --- TOKENS SEMÂNTICOS ---
+
+// A legenda enviada no 'initialize': o editor escolhe as cores pelos nomes.
+var (
+	tokenTypes     = []string{"keyword", "variable", "function", "number", "string", "operator", "comment", "type"}
+	tokenModifiers = []string{"declaration", "readonly", "defaultLibrary"}
+)
+
+// Índices na legenda.
+const (
+	typeKeyword = iota
+	typeVariable
+	typeFunction
+	typeNumber
+	typeString
+	typeOperator
+	typeComment
+	typeType
+)
+
+const (
+	modDeclaration = 1 << iota
+	modReadonly
+	modDefaultLibrary
+)
+
+// piece: Um trecho destacado, em uma linha só (colunas do lexer).
+type piece struct {
+	line, column, end int
+	kind, mods        int
+}
+
+// semanticTokens: Os trechos destacados, codificados como o protocolo pede:
+// cinco inteiros por trecho (linha e início relativos ao anterior, tamanho,
+// tipo e modificadores).
+func (d *document) semanticTokens() []int {
+	var pieces []piece
+	for i, tok := range d.tokens {
+		p := piece{line: tok.Line, column: tok.Column, end: tok.Column + length(tok), kind: -1}
+		switch tok.Type {
+		case lexer.TokenVar, lexer.TokenConst, lexer.TokenPrint, lexer.TokenWrite, lexer.TokenInput:
+			p.kind = typeKeyword
+		case lexer.TokenInt, lexer.TokenFloat:
+			p.kind = typeNumber
+		case lexer.TokenPlus, lexer.TokenMinus, lexer.TokenMult, lexer.TokenDiv, lexer.TokenAssign:
+			p.kind = typeOperator
+		case lexer.TokenString:
+			pieces = append(pieces, d.stringPieces(tok)...)
+		case lexer.TokenIdent:
+			p.kind, p.mods = d.identKind(i)
+		}
+		if p.kind >= 0 {
+			pieces = append(pieces, p)
+		}
+	}
+	for _, c := range d.comments {
+		pieces = append(pieces, piece{line: c.Line, column: c.Column, end: c.Column + len(c.Text), kind: typeComment})
+	}
+	sort.Slice(pieces, func(i, j int) bool {
+		if pieces[i].line != pieces[j].line {
+			return pieces[i].line < pieces[j].line
+		}
+		return pieces[i].column < pieces[j].column
+	})
+
+	data := []int{}
+	prev := Position{}
+	for _, p := range pieces {
+		start, end := d.position(p.line, p.column), d.position(p.line, p.end)
+		char := start.Character
+		if start.Line == prev.Line {
+			char -= prev.Character
+		}
+		data = append(data, start.Line-prev.Line, char, end.Character-start.Character, p.kind, p.mods)
+		prev = start
+	}
+	return data
+}
+
+// identKind: O tipo de um identificador: função nativa (antes de '('), tipo
+// de vetor ('float' em 'var v: float[3]'), constante ou variável.
+func (d *document) identKind(i int) (kind, mods int) {
+	tok := d.tokens[i]
+	next := func(t lexer.TokenType) bool { return i+1 < len(d.tokens) && d.tokens[i+1].Type == t }
+	prev := func(t lexer.TokenType) bool { return i > 0 && d.tokens[i-1].Type == t }
+	if _, ok := semantic.FuncaoEmbutida(tok.Literal); ok && next(lexer.TokenLParen) {
+		return typeFunction, modDefaultLibrary
+	}
+	if prev(lexer.TokenColon) && next(lexer.TokenLBracket) {
+		return typeType, 0
+	}
+	if def, ok := d.defs[tok.Literal]; ok && def.Line == tok.Line && def.Column == tok.Column {
+		mods |= modDeclaration
+	}
+	if d.symbols[tok.Literal].Constante || (i > 0 && prev(lexer.TokenConst)) {
+		mods |= modReadonly
+	}
+	return typeVariable, mods
+}
+
+// stringPieces: Um texto, sem as expressões '{...}' (que vêm como tokens à
+// parte) e quebrado em linhas, já que um trecho não passa de uma linha.
+func (d *document) stringPieces(tok lexer.Token) []piece {
+	text := "\"" + tok.Literal + "\""
+	if strings.Contains(tok.Literal, "\n") {
+		var pieces []piece
+		line, column := tok.Line, tok.Column
+		for _, part := range strings.Split(text, "\n") {
+			pieces = append(pieces, piece{line: line, column: column, end: column + len(part), kind: typeString})
+			line, column = line+1, 1
+		}
+		return pieces
+	}
+
+	var pieces []piece
+	start := 0 // Início do pedaço de texto fixo, em text
+	for i := 1; i < len(text)-1; i++ {
+		if text[i] != '{' {
+			continue
+		}
+		if text[i+1] == '{' {
+			i++
+			continue
+		}
+		end := strings.IndexByte(text[i+1:], '}')
+		if end < 0 {
+			break
+		}
+		pieces = append(pieces, piece{line: tok.Line, column: tok.Column + start, end: tok.Column + i + 1, kind: typeString})
+		start = i + 1 + end
+		i = start
+	}
+	pieces = append(pieces, piece{line: tok.Line, column: tok.Column + start, end: tok.Column + len(text), kind: typeString})
+	return pieces
+}
@@ -0,0 +1,200 @@
+package lsp
+
+import (
+	"bufio"
+	"csigma/format"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Servidor da linguagem (csigma lsp): fala o Language Server Protocol pela
+// entrada e saída padrão e reaproveita as fases do compilador para o editor:
+//
+//   - diagnósticos a cada mudança (erros sintáticos e semânticos e avisos do lint);
+//   - hover com o tipo das variáveis (da Tabela de Símbolos);
+//   - ir para a definição de uma variável ou constante;
+//   - símbolos do documento, tokens semânticos (destaque de sintaxe) e formatação.
+//
+// O texto é sincronizado inteiro a cada mudança e reanalisado do zero.
+
+// Server: O estado do servidor: os documentos abertos.
+type Server struct {
+	out         io.Writer
+	docs        map[string]*document
+	initialized bool
+	shutdown    bool
+}
+
+// Serve: Atende o editor até a notificação 'exit' ou o fim da entrada.
+// Devolve erro se a saída não veio depois de um 'shutdown'.
+func Serve(in io.Reader, out io.Writer) error {
+	s := &Server{out: out, docs: map[string]*document{}}
+	r := bufio.NewReader(in)
+	for {
+		body, err := readMessage(r)
+		if err == io.EOF {
+			return fmt.Errorf("entrada encerrada sem 'exit'")
+		}
+		if err != nil {
+			return err
+		}
+		var msg message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			s.reply(nil, nil, &responseError{codeParseError, err.Error()})
+			continue
+		}
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return fmt.Errorf("'exit' sem 'shutdown'")
+			}
+			return nil
+		}
+		s.handle(&msg)
+	}
+}
+
+// handle: Despacha uma mensagem. Pedidos (com ID) sempre recebem resposta;
+// notificações desconhecidas são ignoradas.
+func (s *Server) handle(msg *message) {
+	if msg.ID == nil {
+		s.notification(msg)
+		return
+	}
+	if !s.initialized && msg.Method != "initialize" {
+		s.reply(msg.ID, nil, &responseError{codeNotInitialized, "o servidor ainda não recebeu 'initialize'"})
+		return
+	}
+	if s.shutdown {
+		s.reply(msg.ID, nil, &responseError{codeInvalidRequest, "o servidor já recebeu 'shutdown'"})
+		return
+	}
+
+	switch msg.Method {
+	case "initialize":
+		s.initialized = true
+		s.reply(msg.ID, map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":           map[string]interface{}{"openClose": true, "change": 1},
+				"hoverProvider":              true,
+				"definitionProvider":         true,
+				"documentSymbolProvider":     true,
+				"documentFormattingProvider": true,
+				"semanticTokensProvider": map[string]interface{}{
+					"legend": map[string]interface{}{"tokenTypes": tokenTypes, "tokenModifiers": tokenModifiers},
+					"full":   true,
+				},
+			},
+			"serverInfo": map[string]string{"name": "csigma"},
+		}, nil)
+	case "shutdown":
+		s.shutdown = true
+		s.reply(msg.ID, nil, nil)
+	case "textDocument/hover":
+		var p positionParams
+		if doc := s.document(msg, &p, &p.TextDocument); doc != nil {
+			s.reply(msg.ID, doc.hover(p.Position), nil)
+		}
+	case "textDocument/definition":
+		var p positionParams
+		if doc := s.document(msg, &p, &p.TextDocument); doc != nil {
+			s.reply(msg.ID, doc.definition(p.Position), nil)
+		}
+	case "textDocument/documentSymbol":
+		var p documentParams
+		if doc := s.document(msg, &p, &p.TextDocument); doc != nil {
+			s.reply(msg.ID, doc.symbolList(), nil)
+		}
+	case "textDocument/semanticTokens/full":
+		var p documentParams
+		if doc := s.document(msg, &p, &p.TextDocument); doc != nil {
+			s.reply(msg.ID, semanticTokens{Data: doc.semanticTokens()}, nil)
+		}
+	case "textDocument/formatting":
+		var p documentParams
+		if doc := s.document(msg, &p, &p.TextDocument); doc != nil {
+			s.reply(msg.ID, doc.format(), nil)
+		}
+	default:
+		s.reply(msg.ID, nil, &responseError{codeMethodNotFound, "método não suportado: " + msg.Method})
+	}
+}
+
+// notification: Abertura, mudança e fechamento de documentos.
+func (s *Server) notification(msg *message) {
+	switch msg.Method {
+	case "textDocument/didOpen":
+		var p didOpenParams
+		if json.Unmarshal(msg.Params, &p) == nil {
+			s.update(p.TextDocument.URI, p.TextDocument.Text)
+		}
+	case "textDocument/didChange":
+		var p didChangeParams
+		if json.Unmarshal(msg.Params, &p) == nil && len(p.ContentChanges) > 0 {
+			s.update(p.TextDocument.URI, p.ContentChanges[len(p.ContentChanges)-1].Text)
+		}
+	case "textDocument/didClose":
+		var p documentParams
+		if json.Unmarshal(msg.Params, &p) == nil {
+			delete(s.docs, p.TextDocument.URI)
+			s.publish(p.TextDocument.URI, []Diagnostic{})
+		}
+	}
+}
+
+// update: Reanalisa o documento e publica os diagnósticos.
+func (s *Server) update(uri, text string) {
+	doc := analyze(uri, text)
+	s.docs[uri] = doc
+	diags := doc.diags
+	if diags == nil {
+		diags = []Diagnostic{}
+	}
+	s.publish(uri, diags)
+}
+
+func (s *Server) publish(uri string, diags []Diagnostic) {
+	params, _ := json.Marshal(publishDiagnosticsParams{URI: uri, Diagnostics: diags})
+	writeMessage(s.out, &message{Method: "textDocument/publishDiagnostics", Params: params})
+}
+
+// document: Lê os parâmetros do pedido e devolve o documento citado, ou
+// responde com o erro e devolve nil.
+func (s *Server) document(msg *message, params interface{}, id *textDocumentIdentifier) *document {
+	if err := json.Unmarshal(msg.Params, params); err != nil {
+		s.reply(msg.ID, nil, &responseError{codeInvalidParams, err.Error()})
+		return nil
+	}
+	doc, ok := s.docs[id.URI]
+	if !ok {
+		s.reply(msg.ID, nil, &responseError{codeInvalidParams, "documento não aberto: " + id.URI})
+		return nil
+	}
+	return doc
+}
+
+// reply: Responde a um pedido. Sem erro, um resultado nil vira 'null'.
+func (s *Server) reply(id *json.RawMessage, result interface{}, err *responseError) {
+	msg := &message{ID: id, Error: err}
+	if err == nil {
+		msg.Result = result
+		if result == nil {
+			msg.Result = json.RawMessage("null")
+		}
+	}
+	if id == nil {
+		null := json.RawMessage("null")
+		msg.ID = &null
+	}
+	writeMessage(s.out, msg)
+}
+
+// format: A formatação do documento como uma única edição (vazia se o texto
+// já estiver formatado ou tiver erro sintático).
+func (d *document) format() []textEdit {
+	out, err := format.Source(d.text)
+	if err != nil || out == d.text {
+		return []textEdit{}
+	}
+	return []textEdit{{Range: Range{End: d.end()}, NewText: out}}
+}
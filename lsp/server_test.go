@@ -0,0 +1,196 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+const testURI = "file:///tmp/teste.sig"
+
+const testSource = `const MAX = 10
+var total = 5
+total = total + MAX
+print "total={total}"
+y = 1
+`
+
+// request: Um pedido ao servidor; id 0 é notificação.
+type request struct {
+	id     int
+	method string
+	params interface{}
+}
+
+// session: Manda os pedidos ao servidor, terminando com shutdown e exit, e
+// devolve as respostas (pelo ID) e as notificações recebidas.
+func session(t *testing.T, reqs ...request) (map[int]json.RawMessage, []message) {
+	t.Helper()
+	reqs = append(reqs, request{999, "shutdown", nil}, request{0, "exit", nil})
+	var in bytes.Buffer
+	for _, r := range reqs {
+		msg := &message{JSONRPC: "2.0", Method: r.method}
+		if r.id != 0 {
+			id := json.RawMessage(strconv.Itoa(r.id))
+			msg.ID = &id
+		}
+		if r.params != nil {
+			params, err := json.Marshal(r.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg.Params = params
+		}
+		writeMessage(&in, msg)
+	}
+
+	var out bytes.Buffer
+	if err := Serve(&in, &out); err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+
+	results := map[int]json.RawMessage{}
+	var notes []message
+	r := bufio.NewReader(&out)
+	for {
+		body, err := readMessage(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		var reply struct {
+			ID     *int            `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+			Result json.RawMessage `json:"result"`
+			Error  *responseError  `json:"error"`
+		}
+		if err := json.Unmarshal(body, &reply); err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case reply.Error != nil:
+			t.Errorf("pedido %d: erro %d: %s", *reply.ID, reply.Error.Code, reply.Error.Message)
+		case reply.ID != nil:
+			results[*reply.ID] = reply.Result
+		default:
+			notes = append(notes, message{Method: reply.Method, Params: reply.Params})
+		}
+	}
+	return results, notes
+}
+
+func at(id int, method string, line, character int) request {
+	return request{id, method, positionParams{TextDocument: textDocumentIdentifier{URI: testURI}, Position: Position{line, character}}}
+}
+
+// Uma sessão completa: initialize, didOpen (com o diagnóstico publicado),
+// hover e definition.
+func TestServe(t *testing.T) {
+	results, notes := session(t,
+		request{1, "initialize", map[string]interface{}{"capabilities": map[string]interface{}{}}},
+		request{0, "initialized", map[string]interface{}{}},
+		request{0, "textDocument/didOpen", didOpenParams{TextDocument: textDocumentItem{URI: testURI, Text: testSource}}},
+		at(2, "textDocument/hover", 2, 9),       // total, na expressão
+		at(3, "textDocument/hover", 2, 17),      // MAX
+		at(4, "textDocument/definition", 3, 14), // total, dentro do texto interpolado
+		at(5, "textDocument/definition", 2, 16), // MAX
+		at(6, "textDocument/hover", 2, 14),      // '+': nada
+	)
+
+	// initialize: as capacidades anunciadas.
+	var init struct {
+		Capabilities map[string]interface{} `json:"capabilities"`
+	}
+	if err := json.Unmarshal(results[1], &init); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []string{"hoverProvider", "definitionProvider", "textDocumentSync"} {
+		if init.Capabilities[c] == nil {
+			t.Errorf("initialize: falta a capacidade %s", c)
+		}
+	}
+
+	// didOpen: um único diagnóstico, a variável não declarada da linha 5.
+	if len(notes) != 1 || notes[0].Method != "textDocument/publishDiagnostics" {
+		t.Fatalf("notificações: %+v", notes)
+	}
+	var diags publishDiagnosticsParams
+	if err := json.Unmarshal(notes[0].Params, &diags); err != nil {
+		t.Fatal(err)
+	}
+	if diags.URI != testURI || len(diags.Diagnostics) != 1 {
+		t.Fatalf("diagnósticos: %+v", diags)
+	}
+	if d := diags.Diagnostics[0]; d.Severity != 1 || d.Range.Start.Line != 4 {
+		t.Errorf("diagnóstico: %+v, quer um erro na linha 4", d)
+	}
+
+	// hover
+	hovers := []struct {
+		id   int
+		want hover
+	}{
+		{2, hover{markupContent{"markdown", "```\nvar total: SIGMA_INT\n```\n\ndeclarada na linha 2"}, Range{Position{2, 8}, Position{2, 13}}}},
+		{3, hover{markupContent{"markdown", "```\nconst MAX: SIGMA_INT = 10\n```\n\ndeclarada na linha 1"}, Range{Position{2, 16}, Position{2, 19}}}},
+	}
+	for _, h := range hovers {
+		var got hover
+		if err := json.Unmarshal(results[h.id], &got); err != nil {
+			t.Fatal(err)
+		}
+		if got != h.want {
+			t.Errorf("hover %d = %+v, quer %+v", h.id, got, h.want)
+		}
+	}
+	if string(results[6]) != "null" {
+		t.Errorf("hover sobre '+' = %s, quer null", results[6])
+	}
+
+	// definition
+	definitions := []struct {
+		id   int
+		want Location
+	}{
+		{4, Location{testURI, Range{Position{1, 4}, Position{1, 9}}}},
+		{5, Location{testURI, Range{Position{0, 6}, Position{0, 9}}}},
+	}
+	for _, d := range definitions {
+		var got Location
+		if err := json.Unmarshal(results[d.id], &got); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, d.want) {
+			t.Errorf("definition %d = %+v, quer %+v", d.id, got, d.want)
+		}
+	}
+}
+
+// Pedidos antes do initialize são recusados, e o exit sem shutdown é erro.
+func TestServeProtocolErrors(t *testing.T) {
+	id := json.RawMessage("1")
+	var in bytes.Buffer
+	writeMessage(&in, &message{JSONRPC: "2.0", ID: &id, Method: "textDocument/hover"})
+	writeMessage(&in, &message{JSONRPC: "2.0", Method: "exit"})
+	var out bytes.Buffer
+	if err := Serve(&in, &out); err == nil {
+		t.Error("exit sem shutdown: quer erro")
+	}
+	body, err := readMessage(bufio.NewReader(&out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reply message
+	if err := json.Unmarshal(body, &reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Error == nil || reply.Error.Code != codeNotInitialized {
+		t.Errorf("resposta = %s, quer o erro %d", body, codeNotInitialized)
+	}
+}
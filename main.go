@@ -1,28 +1,111 @@
 package main
 
 import (
+	"bytes"
+	"csigma/ast"
+	"csigma/cgen"
 	"csigma/codegen"
+	"csigma/ir"
 	"csigma/lexer"
+	"csigma/opt"
 	"csigma/parser"
+	"csigma/report"
+	"csigma/semantic"
+	"flag"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 )
 
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Println("Uso: go run main.go <arquivo.sig>")
+	// Subcomandos: ferramentas sobre o fonte, cada uma no seu arquivo.
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "fmt":
+			os.Exit(runFmt(os.Args[2:]))
+		case "lint":
+			os.Exit(runLint(os.Args[2:]))
+		case "lsp":
+			os.Exit(runLsp(os.Args[2:]))
+		case "repl":
+			os.Exit(runRepl(os.Args[2:]))
+		case "debug":
+			os.Exit(runDebug(os.Args[2:]))
+		}
+	}
+
+	inputPolicy := flag.String("input", "retry", "politica para entrada invalida: retry (pede de novo) ou abort (erro de execucao)")
+	o0 := flag.Bool("O0", false, "sem otimizacoes (padrao)")
+	o1 := flag.Bool("O1", false, "dobra de constantes, simplificacoes algebricas e alocacao de registradores")
+	backendName := flag.String("backend", "nasm", "codigo gerado: nasm (Intel, montado pelo NASM), gas (AT&T, montado pelo gcc), elf (montador interno) ou c (C99, compilado pelo cc)")
+	noPeephole := flag.Bool("no-peephole", false, "desliga a otimizacao peephole sobre o assembly gerado")
+	noPie := flag.Bool("no-pie", false, "liga um executavel de endereco fixo (o padrao e PIE, independente de posicao)")
+	noLibc := flag.Bool("nolibc", false, "runtime proprio sobre chamadas de sistema: executavel estatico, sem LibC, ligado pelo ld (com --backend=elf, pelo linker interno)")
+	debug := flag.Bool("g", false, "informacao de depuracao DWARF (linhas do fonte e variaveis) para o gdb")
+	reportFlag := flag.String("report", "text", "formato do relatorio: text (o .log) ou json (tambem <fonte>.json, para scripts e editores)")
+	emitFlag := flag.String("emit", "", "listagens extras, separadas por virgula: ir, listing (fonte intercalado com o assembly), ast-dot (<fonte>.dot), ast-json (<fonte>.ast.json)")
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		fmt.Println("Uso: go run . [--input=retry|abort] [-O0|-O1] [--backend=nasm|gas|elf|c] [--nolibc] [--no-pie] [-g] [--no-peephole] [--emit=ir,listing,ast-dot,ast-json] [--report=text|json] <arquivo.sig>")
+		fmt.Println("     go run . fmt|lint|lsp|repl|debug ...")
+		return
+	}
+	if *o0 && *o1 {
+		fmt.Println("Escolha apenas um nivel de otimizacao: -O0 ou -O1")
+		return
+	}
+	optLevel := 0
+	if *o1 {
+		optLevel = 1
+	}
+	if *inputPolicy != "retry" && *inputPolicy != "abort" {
+		fmt.Printf("Politica de entrada desconhecida: %s (use retry ou abort)\n", *inputPolicy)
+		return
+	}
+	opts := ir.Options{AbortOnInvalidInput: *inputPolicy == "abort"}
+	// O backend C não é um codegen.Backend: parte da AST, e não da IR.
+	backend, ok := codegen.Backends[*backendName]
+	if !ok && *backendName != "c" {
+		fmt.Printf("Backend desconhecido: %s (use nasm, gas, elf ou c)\n", *backendName)
+		return
+	}
+	if *noLibc && backend == nil {
+		fmt.Println("--nolibc nao se aplica ao backend c (o programa C usa a LibC)")
+		return
+	}
+
+	if *reportFlag != "text" && *reportFlag != "json" {
+		fmt.Printf("Formato de relatorio desconhecido: %s (use text ou json)\n", *reportFlag)
 		return
 	}
 
-	inputPath := os.Args[1]
+	emit := map[string]bool{}
+	for _, e := range strings.Split(*emitFlag, ",") {
+		switch e {
+		case "":
+		case "ir", "listing", "ast-dot", "ast-json":
+			emit[e] = true
+		default:
+			fmt.Printf("Listagem desconhecida: --emit=%s (use ir, listing, ast-dot ou ast-json)\n", e)
+			return
+		}
+	}
+
+	inputPath := flag.Arg(0)
 	dir := filepath.Dir(inputPath)
 	baseName := strings.TrimSuffix(filepath.Base(inputPath), ".sig")
 	logPath := filepath.Join(dir, baseName+".log")
+	// O gdb procura o fonte pelo caminho gravado na informação de depuração.
+	sourcePath, err := filepath.Abs(inputPath)
+	if err != nil {
+		sourcePath = inputPath
+	}
 
 	logFile, err := os.Create(logPath)
 	if err != nil {
@@ -34,7 +117,48 @@ func main() {
 	mw := io.MultiWriter(os.Stdout, logFile)
 	logPrint := func(f string, a ...interface{}) { fmt.Fprintf(mw, f, a...) }
 
-	content, _ := os.ReadFile(inputPath)
+	// Relatório estruturado: preenchido junto com o .log e gravado no fim,
+	// mesmo quando a compilação para no meio.
+	emitList := []string{}
+	for e := range emit {
+		emitList = append(emitList, e)
+	}
+	sort.Strings(emitList)
+	rep := report.New(inputPath, report.Options{
+		Backend: *backendName, OptLevel: optLevel, Input: *inputPolicy, Peephole: !*noPeephole,
+		NoLibc: *noLibc, PIE: !*noPie, Debug: *debug, Emit: emitList,
+	})
+	jsonPath := filepath.Join(dir, baseName+".json")
+	if *reportFlag == "json" {
+		defer func() {
+			if err := rep.Write(jsonPath); err != nil {
+				fmt.Printf("Erro ao gravar o relatorio JSON: %v\n", err)
+			}
+		}()
+	}
+
+	// run: Executa um programa externo (montador, linker, compilador C),
+	// guardando no relatório o código de saída e o stderr.
+	run := func(args []string) (string, error) {
+		cmd := exec.Command(args[0], args[1:]...)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		start := time.Now()
+		err := cmd.Run()
+		c := report.Command{Args: args, Stderr: stderr.String(), Seconds: time.Since(start).Seconds()}
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			c.ExitCode = exitErr.ExitCode()
+		} else if err != nil {
+			c.ExitCode = -1
+		}
+		rep.Commands = append(rep.Commands, c)
+		if err != nil {
+			rep.Error(fmt.Sprintf("%s: %v", args[0], err))
+		}
+		return c.Stderr, err
+	}
+
+	content, readErr := os.ReadFile(inputPath)
 
 	logPrint("======================================================================\n")
 	logPrint("   CSIGMA PLATINUM - RELATORIO TECNICO DE COMPILACAO\n")
@@ -45,76 +169,300 @@ func main() {
 	// --- FASE 1: LEXER ---
 	logPrint("\n[FASE 1] ANALISE LEXICA (Scanner):\n")
 	logPrint("----------------------------------------------------------------------\n")
-	l := lexer.NewLexer(string(content))
-	var tokens []lexer.Token
-	for {
-		tok := l.NextToken()
-		tokens = append(tokens, tok)
+	rep.Begin("lexer")
+	if readErr != nil {
+		logPrint("\n[ERRO] Nao foi possivel ler o fonte: %v\n", readErr)
+		rep.Error(readErr.Error())
+		return
+	}
+	tokens := lexer.Tokenize(string(content))
+	for _, tok := range tokens {
+		rep.Tokens = append(rep.Tokens, report.Token{Type: string(tok.Type), Literal: tok.Literal, Line: tok.Line, Column: tok.Column})
 		logPrint("  Token: [%-12s] | Literal: \"%s\"\n", tok.Type, tok.Literal)
-		if tok.Type == lexer.TokenEOF {
-			break
-		}
 	}
 
 	// --- FASE 2: PARSER ---
 	logPrint("\n[FASE 2] ANALISE SINTATICA (Dump da AST):\n")
 	logPrint("----------------------------------------------------------------------\n")
+	rep.Begin("parser")
 	p := parser.NewParser(tokens)
 	statements, err := p.ParseProgram()
 	if err != nil {
 		logPrint("\n[ERRO SINTATICO] %v\n", err)
+		// No fim do arquivo, a linha que ficou incompleta é a do último token.
+		line := p.Token().Line
+		if p.Token().Type == lexer.TokenEOF && len(tokens) > 1 {
+			line = tokens[len(tokens)-2].Line
+		}
+		rep.ErrorAt(line, err.Error())
 		return
 	}
 
 	for i, stmt := range statements {
-		switch s := stmt.(type) {
-		case *parser.VarDeclNode:
-			logPrint("  [%02d] DECLARACAO:  Var %s = %s\n", i, s.Name, s.Value)
-		case *parser.PrintNode:
-			logPrint("  [%02d] PRINT:       \"%s\" (String: %v)\n", i, s.Value, s.IsString)
-		case *parser.InputNode:
-			logPrint("  [%02d] INPUT:       Ler para variavel %s\n", i, s.VarName)
-		case *parser.AssignmentNode:
-			exprStr := s.First
-			for _, op := range s.Ops {
-				exprStr += " " + op.Operator + " " + op.Value
-			}
-			logPrint("  [%02d] CALCULO:     %s = %s\n", i, s.Dest, exprStr)
+		kind, line, text := describe(stmt)
+		logPrint("  [%02d] %-13s%s\n", i, kind+":", text)
+		rep.AST = append(rep.AST, report.Statement{Kind: kind, Line: line, Text: text})
+	}
+
+	// --- Validação Semântica (tipos e declarações) ---
+	rep.Begin("semantic")
+	analyzer := semantic.NewAnalyzer()
+	semErr := analyzer.Analisar(statements)
+	for _, sym := range analyzer.TabelaSimbolos {
+		rep.Symbols = append(rep.Symbols, report.Symbol{Name: sym.Nome, Type: sym.Tipo, Size: sym.Tamanho, Constant: sym.Constante, Value: sym.Valor})
+	}
+	sort.Slice(rep.Symbols, func(i, j int) bool { return rep.Symbols[i].Name < rep.Symbols[j].Name })
+
+	// Exportação da AST, já com os tipos que o Analisador Semântico anotou.
+	if emit["ast-dot"] {
+		dotPath := filepath.Join(dir, baseName+".dot")
+		if err := os.WriteFile(dotPath, []byte(ast.DOT(statements)), 0644); err != nil {
+			logPrint("  > AST (Graphviz): FALHOU: %v\n", err)
+		} else {
+			logPrint("  > [OK] AST (Graphviz) gravada em '%s'.\n", dotPath)
+		}
+	}
+	if emit["ast-json"] {
+		astPath := filepath.Join(dir, baseName+".ast.json")
+		data, err := ast.JSON(statements)
+		if err == nil {
+			err = os.WriteFile(astPath, append(data, '\n'), 0644)
+		}
+		if err != nil {
+			logPrint("  > AST (JSON): FALHOU: %v\n", err)
+		} else {
+			logPrint("  > [OK] AST (JSON) gravada em '%s'.\n", astPath)
 		}
 	}
+	if semErr != nil {
+		logPrint("\n[ERRO SEMANTICO] %v\n", semErr)
+		// O analisador já listou os erros no terminal; aqui eles vão para o log.
+		for i, e := range analyzer.Erros {
+			fmt.Fprintf(logFile, "  >> %s\n", e)
+			rep.ErrorAt(analyzer.Linhas[i], e)
+		}
+		return
+	}
+	logPrint("\n  > [OK] Analise semantica: %d simbolo(s) na tabela.\n", len(analyzer.TabelaSimbolos))
 
 	// --- FASE 3: CODEGEN (Com listagem no log) ---
 	logPrint("\n[FASE 3] GERACAO DE CODIGO (Assembly x86_64):\n")
 	logPrint("----------------------------------------------------------------------\n")
-	nasmCode := codegen.GenerateNASM(statements)
+	// A AST vira primeiro a IR (código de três endereços em blocos básicos);
+	// o backend NASM só conhece a IR.
+	rep.Begin("ir")
+	prog := ir.Lower(statements, opts)
+	avisos := opt.Warnings(prog)
+	for _, a := range avisos {
+		logPrint("  > [AVISO] %s\n", a)
+		rep.WarningAt(a.Line, a.Message)
+	}
+	antes := prog.InstrCount()
+	if erros := opt.Optimize(prog, optLevel); len(erros) > 0 {
+		logPrint("\n[ERRO DE COMPILACAO] %d erro(s):\n", len(erros))
+		for _, e := range erros {
+			logPrint("  >> %s\n", e)
+			rep.ErrorAt(e.Line, e.Message)
+		}
+		return
+	}
+	logPrint("  > [OK] Otimizacao -O%d: %d -> %d instrucoes na IR.\n", optLevel, antes, prog.InstrCount())
+	if emit["ir"] {
+		rep.IR = prog.String()
+		logPrint("  Representacao intermediaria (IR):\n\n%s\n", prog)
+		logPrint("----------------------------------------------------------------------\n")
+	}
+	if backend == nil {
+		// --- BACKEND C: a AST vira um arquivo C99, compilado pelo cc ---
+		source := ""
+		if *debug {
+			source = sourcePath
+		}
+		rep.Begin("codegen")
+		cCode := cgen.Generate(statements, opts, source)
+		logPrint("%s\n", cCode)
+		cPath := "output" + cgen.Extension
+		rep.Output = &report.Output{Backend: "c", Path: cPath, Code: cCode}
+		os.WriteFile(cPath, []byte(cCode), 0644)
+		logPrint("----------------------------------------------------------------------\n")
+		logPrint("  > [OK] Arquivo '%s' gravado no disco.\n", cPath)
+
+		logPrint("\n[FASE 4] COMPILACAO (C99):\n")
+		logPrint("----------------------------------------------------------------------\n")
+		ccCmd := cgen.CompileCommand(cPath, baseName, optLevel >= 1)
+		if *noPie {
+			ccCmd = append(ccCmd, "-no-pie")
+		}
+		if *debug {
+			ccCmd = append(ccCmd, "-g")
+		}
+		rep.Begin("compile")
+		logPrint("  > Executando %s... ", strings.Join(ccCmd, " "))
+		if out, err := run(ccCmd); err != nil {
+			logPrint("FALHOU: %v\n%s", err, out)
+			return
+		}
+		logPrint("OK.\n")
+		logPrint("\n======================================================================\n")
+		logPrint("   RESULTADO FINAL: ./%s\n", baseName)
+		logPrint("   Log gerado em:   %s\n", logPath)
+		if *reportFlag == "json" {
+			logPrint("   Relatorio JSON:  %s\n", jsonPath)
+		}
+		logPrint("======================================================================\n")
+		rep.Success, rep.Executable = true, baseName
+		return
+	}
+	rep.Begin("codegen")
+	cgOpts := codegen.Options{RegAlloc: optLevel >= 1, Peephole: !*noPeephole, NoLibc: *noLibc, Debug: *debug, Source: sourcePath}
+	asmCode := backend.Generate(prog, cgOpts)
+	if optLevel >= 1 {
+		// Mede o ganho da alocação comparando com a geração que usa só a memória.
+		semAlocacao := codegen.CountInstructions(backend.Generate(prog, codegen.Options{Peephole: cgOpts.Peephole, NoLibc: cgOpts.NoLibc}))
+		comAlocacao := codegen.CountInstructions(asmCode)
+		logPrint("  > [OK] Alocacao de registradores: %d -> %d instrucoes no main (%+.0f%%).\n",
+			semAlocacao, comAlocacao, 100*float64(comAlocacao-semAlocacao)/float64(semAlocacao))
+	}
+	if cgOpts.Peephole {
+		semPeephole := codegen.CountInstructions(backend.Generate(prog, codegen.Options{RegAlloc: cgOpts.RegAlloc, NoLibc: cgOpts.NoLibc}))
+		comPeephole := codegen.CountInstructions(asmCode)
+		logPrint("  > [OK] Peephole: %d -> %d instrucoes no main (%+.0f%%).\n",
+			semPeephole, comPeephole, 100*float64(comPeephole-semPeephole)/float64(semPeephole))
+	}
 
 	// Grava no log o código gerado
-	logPrint("%s\n", nasmCode)
+	logPrint("%s\n", asmCode)
+	asmPath := "output" + backend.Extension()
+	rep.Output = &report.Output{Backend: backend.Name(), Path: asmPath, Code: asmCode}
+	if emit["listing"] {
+		listing, err := codegen.Listing(backend, prog, cgOpts, string(content))
+		if err != nil {
+			logPrint("  > Listagem: FALHOU: %v\n", err)
+			rep.Error(err.Error())
+		} else {
+			logPrint("----------------------------------------------------------------------\n")
+			logPrint("  Listagem (fonte intercalado com o assembly):\n\n%s\n", listing)
+			rep.Output.Listing = listing
+		}
+	}
 
-	os.WriteFile("output.asm", []byte(nasmCode), 0644)
+	os.WriteFile(asmPath, []byte(asmCode), 0644)
 	logPrint("----------------------------------------------------------------------\n")
-	logPrint("  > [OK] Arquivo 'output.asm' gravado no disco.\n")
+	logPrint("  > [OK] Arquivo '%s' gravado no disco.\n", asmPath)
 
 	// --- FASE 4: BUILD ---
-	logPrint("\n[FASE 4] MONTAGEM E LINKAGEM (NASM & GCC):\n")
+	// O código só usa endereços relativos ao RIP, então o executável é PIE
+	// (carregado em qualquer endereço). Sem LibC, o ld liga o objeto sozinho:
+	// um PIE estático, sem interpretador nem relocações, com entrada em _start.
+	// Com o backend elf, nem o ld: o linker interno grava o executável.
+	// --no-pie volta ao endereço fixo, para comparação.
+	linkCmd := []string{"gcc", "output.o", "-o", baseName, "-pie"}
+	if *noPie {
+		linkCmd[4] = "-no-pie"
+	}
+	if *noLibc {
+		linkCmd = []string{"ld", "output.o", "-o", baseName, "-pie", "--no-dynamic-linker"}
+		if *noPie {
+			linkCmd = linkCmd[:4]
+		}
+	}
+	linker, internalLink := backend.(codegen.Linker)
+	internalLink = internalLink && *noLibc
+	linkerName := strings.ToUpper(linkCmd[0])
+	if internalLink {
+		linkerName = "LINKER INTERNO"
+	}
+	logPrint("\n[FASE 4] MONTAGEM E LINKAGEM (%s & %s):\n", strings.ToUpper(backend.Name()), linkerName)
 	logPrint("----------------------------------------------------------------------\n")
 
-	logPrint("  > Executando NASM... ")
-	if err := exec.Command("nasm", "-f", "elf64", "output.asm", "-o", "output.o").Run(); err != nil {
-		logPrint("FALHOU: %v\n", err)
-		return
+	rep.Begin("assemble")
+	if asm, ok := backend.(codegen.Assembler); ok {
+		logPrint("  > Montador interno: gravando output.o... ")
+		objCode, err := asm.Assemble(prog, cgOpts)
+		if err == nil {
+			err = os.WriteFile("output.o", objCode, 0644)
+		}
+		if err != nil {
+			logPrint("FALHOU: %v\n", err)
+			rep.Error(err.Error())
+			return
+		}
+		logPrint("OK (%d bytes).\n", len(objCode))
+	} else {
+		asmCmd := backend.AssembleCommand(asmPath, "output.o")
+		logPrint("  > Executando %s... ", strings.Join(asmCmd, " "))
+		if stderr, err := run(asmCmd); err != nil {
+			logPrint("FALHOU: %v\n%s", err, stderr)
+			return
+		}
+		logPrint("OK.\n")
 	}
-	logPrint("OK.\n")
 
-	logPrint("  > Executando GCC...  ")
-	if err := exec.Command("gcc", "output.o", "-o", baseName, "-no-pie").Run(); err != nil {
-		logPrint("FALHOU: %v\n", err)
-		return
+	rep.Begin("link")
+	if internalLink {
+		logPrint("  > Linker interno: gravando %s... ", baseName)
+		exe, err := linker.Link(prog, cgOpts, !*noPie)
+		if err == nil {
+			err = os.WriteFile(baseName, exe, 0755)
+		}
+		if err != nil {
+			logPrint("FALHOU: %v\n", err)
+			rep.Error(err.Error())
+			return
+		}
+		logPrint("OK (%d bytes).\n", len(exe))
+	} else {
+		logPrint("  > Executando %s... ", strings.Join(linkCmd, " "))
+		if stderr, err := run(linkCmd); err != nil {
+			logPrint("FALHOU: %v\n%s", err, stderr)
+			return
+		}
+		logPrint("OK.\n")
 	}
-	logPrint("OK.\n")
 
 	logPrint("\n======================================================================\n")
 	logPrint("   RESULTADO FINAL: ./%s\n", baseName)
 	logPrint("   Log gerado em:   %s\n", logPath)
+	if *reportFlag == "json" {
+		logPrint("   Relatorio JSON:  %s\n", jsonPath)
+	}
 	logPrint("======================================================================\n")
+	rep.Success, rep.Executable = true, baseName
+}
+
+// describe: Um comando da AST em uma linha do relatório: o tipo (DECLARACAO,
+// PRINT...), a linha do fonte e o resumo.
+func describe(stmt parser.Statement) (kind string, line int, text string) {
+	switch s := stmt.(type) {
+	case *parser.VarDeclNode:
+		if s.Size > 0 {
+			return "DECLARACAO", s.Line, fmt.Sprintf("Vetor %s[%d] (%s)", s.Name, s.Size, s.ElemType)
+		}
+		return "DECLARACAO", s.Line, fmt.Sprintf("Var %s = %s", s.Name, s.Value)
+	case *parser.ConstDeclNode:
+		return "CONSTANTE", s.Line, fmt.Sprintf("Const %s = %s", s.Name, s.ExprNode.String())
+	case *parser.PrintNode:
+		kind = "PRINT"
+		if s.NoNewline {
+			kind = "WRITE"
+		}
+		if s.IsString && s.Parts != nil {
+			return kind, s.Line, fmt.Sprintf("\"%s\" (Interpolada: %d partes)", s.Value, len(s.Parts))
+		} else if s.IsString {
+			return kind, s.Line, fmt.Sprintf("\"%s\" (String: %v)", s.Value, s.IsString)
+		}
+		return kind, s.Line, fmt.Sprintf("\"%s\" (String: %v)", s.Parts[0].Expr, s.IsString)
+	case *parser.InputNode:
+		target := s.VarName
+		if s.Index != nil {
+			target += "[" + s.Index.String() + "]"
+		}
+		if s.Prompt != nil {
+			return "INPUT", s.Line, fmt.Sprintf("Ler para variavel %s (Prompt: \"%s\")", target, s.Prompt.Value)
+		}
+		return "INPUT", s.Line, fmt.Sprintf("Ler para variavel %s", target)
+	case *parser.AssignmentNode:
+		return "CALCULO", s.Line, fmt.Sprintf("%s = %s", s.DestString(), s.ExprNode.String())
+	}
+	return "", 0, ""
 }
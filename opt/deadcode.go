@@ -0,0 +1,294 @@
+package opt
+
+import "csigma/ir"
+
+// Warnings: Avisos sobre código sem efeito: variáveis nunca lidas, atribuições
+// cujo valor ninguém lê e comandos que nunca executam. Roda sobre a IR ainda
+// não otimizada, então os avisos são os mesmos em qualquer nível.
+func Warnings(prog *ir.Program) []Diagnostic {
+	var avisos []Diagnostic
+	reads := readSymbols(prog)
+
+	user := map[string]bool{} // Declaradas no fonte (o compilador também cria globais)
+	for _, g := range prog.Globals {
+		if g.Line == 0 {
+			continue
+		}
+		user[g.Name] = true
+		if !reads[g.Name] {
+			avisos = append(avisos, diagnostic(g.Line, "Variável Não Usada (linha %d): '%s' é declarada mas nunca lida", g.Line, g.Name))
+		}
+	}
+
+	seen := map[string]bool{} // Um aviso por linha (um 'input' grava em mais de um caminho)
+	add := func(d Diagnostic) {
+		if !seen[d.Message] {
+			seen[d.Message] = true
+			avisos = append(avisos, d)
+		}
+	}
+	for _, fn := range prog.Funcs {
+		reach := reachable(fn)
+		for _, b := range fn.Blocks {
+			if reach[b] {
+				continue
+			}
+			for _, in := range b.Instrs {
+				if in.Line > 0 {
+					add(diagnostic(in.Line, "Código Inalcançável (linha %d): o comando nunca é executado", in.Line))
+				}
+			}
+		}
+
+		// Atribuições mortas em variáveis que são lidas em algum lugar (as
+		// nunca lidas já foram avisadas acima).
+		dead := deadStores(fn)
+		for _, b := range fn.Blocks {
+			for _, in := range b.Instrs {
+				if dead[in] && reach[b] && in.Mem.Index == nil && user[in.Mem.Sym] && reads[in.Mem.Sym] {
+					add(diagnostic(in.Line, "Atribuição Inútil (linha %d): o valor gravado em '%s' nunca é lido", in.Line, in.Mem.Sym))
+				}
+			}
+		}
+	}
+	return avisos
+}
+
+// readSymbols: Variáveis e vetores lidos por algum 'load'.
+func readSymbols(prog *ir.Program) map[string]bool {
+	reads := map[string]bool{}
+	for _, fn := range prog.Funcs {
+		for _, b := range fn.Blocks {
+			for _, in := range b.Instrs {
+				if in.Op == ir.OpLoad {
+					reads[in.Mem.Sym] = true
+				}
+			}
+		}
+	}
+	return reads
+}
+
+// reachable: Blocos alcançados a partir da entrada.
+func reachable(fn *ir.Function) map[*ir.Block]bool {
+	seen := map[*ir.Block]bool{}
+	var visit func(b *ir.Block)
+	visit = func(b *ir.Block) {
+		if seen[b] {
+			return
+		}
+		seen[b] = true
+		if len(b.Instrs) == 0 {
+			return
+		}
+		for _, t := range b.Instrs[len(b.Instrs)-1].Targets {
+			visit(t)
+		}
+	}
+	if len(fn.Blocks) > 0 {
+		visit(fn.Blocks[0])
+	}
+	return seen
+}
+
+// --- VIVACIDADE DAS VARIÁVEIS ---
+
+// deadStores: 'store' cujo valor nunca é lido. Uma variável simples está viva
+// em um ponto se algum caminho a partir dali a lê antes de regravá-la; o 'addr'
+// do 'input' conta como leitura, pois o endereço escapa para o scanf. Em
+// vetores não se sabe qual elemento é lido: a gravação só é morta se o vetor
+// inteiro nunca for lido.
+func deadStores(fn *ir.Function) map[*ir.Instr]bool {
+	arrays := map[string]bool{} // Vetores lidos (ou passados ao scanf)
+	for _, b := range fn.Blocks {
+		for _, in := range b.Instrs {
+			if (in.Op == ir.OpLoad || in.Op == ir.OpAddr) && in.Mem.Index != nil {
+				arrays[in.Mem.Sym] = true
+			}
+		}
+	}
+
+	dead := map[*ir.Instr]bool{}
+	out := liveOut(fn)
+	for _, b := range fn.Blocks {
+		live := map[string]bool{}
+		for v := range out[b] {
+			live[v] = true
+		}
+		for i := len(b.Instrs) - 1; i >= 0; i-- {
+			in := b.Instrs[i]
+			if in.Op == ir.OpStore && in.Mem.Index != nil && !arrays[in.Mem.Sym] {
+				dead[in] = true
+			}
+			if in.Op == ir.OpStore && in.Mem.Index == nil && !live[in.Mem.Sym] {
+				dead[in] = true
+			}
+			transfer(in, live)
+		}
+	}
+	return dead
+}
+
+// transfer: Atualiza, de trás para a frente, o conjunto de variáveis vivas.
+func transfer(in *ir.Instr, live map[string]bool) {
+	if in.Mem == nil || in.Mem.Index != nil {
+		return
+	}
+	switch in.Op {
+	case ir.OpStore:
+		delete(live, in.Mem.Sym)
+	case ir.OpLoad, ir.OpAddr:
+		live[in.Mem.Sym] = true
+	}
+}
+
+// liveOut: Variáveis simples vivas na saída de cada bloco. No fim do 'main'
+// (ret ou exit) nenhuma está viva.
+func liveOut(fn *ir.Function) map[*ir.Block]map[string]bool {
+	in := map[*ir.Block]map[string]bool{}
+	out := map[*ir.Block]map[string]bool{}
+	for _, b := range fn.Blocks {
+		in[b], out[b] = map[string]bool{}, map[string]bool{}
+	}
+	for changed := true; changed; {
+		changed = false
+		for i := len(fn.Blocks) - 1; i >= 0; i-- {
+			b := fn.Blocks[i]
+			for _, succ := range b.Instrs[len(b.Instrs)-1].Targets {
+				for v := range in[succ] {
+					out[b][v] = true
+				}
+			}
+			live := map[string]bool{}
+			for v := range out[b] {
+				live[v] = true
+			}
+			for j := len(b.Instrs) - 1; j >= 0; j-- {
+				transfer(b.Instrs[j], live)
+			}
+			for v := range live {
+				if !in[b][v] {
+					in[b][v] = true
+					changed = true
+				}
+			}
+		}
+	}
+	return out
+}
+
+// --- ELIMINAÇÃO (-O1) ---
+
+// removeDeadCode: Apaga as gravações mortas e, em seguida, as contas que só
+// existiam para alimentá-las (temporários sem uso), até nada mais sumir.
+func removeDeadCode(fn *ir.Function) {
+	dead := deadStores(fn)
+	for changed := true; changed; {
+		changed = false
+		used := map[*ir.Temp]bool{}
+		for _, b := range fn.Blocks {
+			for _, in := range b.Instrs {
+				if dead[in] {
+					continue
+				}
+				for _, t := range tempsRead(in) {
+					used[t] = true
+				}
+			}
+		}
+		for _, b := range fn.Blocks {
+			for _, in := range b.Instrs {
+				if !dead[in] && in.Dst != nil && !used[in.Dst] && pure(in) {
+					dead[in] = true
+					changed = true
+				}
+			}
+		}
+	}
+
+	for _, b := range fn.Blocks {
+		kept := b.Instrs[:0]
+		comment := ""
+		for _, in := range b.Instrs {
+			if dead[in] {
+				if in.Comment != "" {
+					comment = in.Comment
+				}
+				continue
+			}
+			if comment != "" && in.Comment == "" {
+				in.Comment = comment
+			}
+			comment = ""
+			kept = append(kept, in)
+		}
+		b.Instrs = kept
+	}
+}
+
+// tempsRead: Temporários lidos pela instrução (inclusive no índice de @v[%t]).
+func tempsRead(in *ir.Instr) []*ir.Temp {
+	var ts []*ir.Temp
+	for _, a := range in.Args {
+		if t, ok := a.(*ir.Temp); ok {
+			ts = append(ts, t)
+		}
+	}
+	if in.Mem != nil {
+		if t, ok := in.Mem.Index.(*ir.Temp); ok {
+			ts = append(ts, t)
+		}
+	}
+	return ts
+}
+
+// pure: A instrução só calcula um valor (pode sumir se ninguém o usa). Chamadas
+// têm efeitos, e a divisão inteira por um divisor desconhecido pode derrubar o
+// programa (SIGFPE): essa fica.
+func pure(in *ir.Instr) bool {
+	switch in.Op {
+	case ir.OpCopy, ir.OpLoad, ir.OpAddr, ir.OpAdd, ir.OpSub, ir.OpMul, ir.OpShl:
+		return true
+	case ir.OpDiv:
+		_, constante := in.Args[1].(ir.IntConst)
+		return in.Dst.Ty == ir.F64 || constante
+	}
+	return false
+}
+
+// removeUnusedGlobals: Variáveis que nenhuma instrução usa mais não precisam de
+// memória, e textos que nenhuma variável ou instrução usa saem da seção .data.
+func removeUnusedGlobals(prog *ir.Program) {
+	used := map[string]bool{}
+	for _, fn := range prog.Funcs {
+		for _, b := range fn.Blocks {
+			for _, in := range b.Instrs {
+				if in.Mem != nil {
+					used[in.Mem.Sym] = true
+				}
+				for _, a := range in.Args {
+					if s, ok := a.(ir.SymAddr); ok {
+						used[s.Name] = true
+					}
+				}
+			}
+		}
+	}
+
+	globals := prog.Globals[:0]
+	for _, g := range prog.Globals {
+		if used[g.Name] {
+			globals = append(globals, g)
+			used[g.Init] = true // O rótulo do texto de uma variável SIGMA_STR
+		}
+	}
+	prog.Globals = globals
+
+	strs := prog.Strings[:0]
+	for _, s := range prog.Strings {
+		if used[s.Label] {
+			strs = append(strs, s)
+		}
+	}
+	prog.Strings = strs
+}
@@ -0,0 +1,328 @@
+package opt
+
+import (
+	"csigma/ir"
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// Optimize: Passagem de otimização sobre a IR, escolhida pelo nível (-O0/-O1).
+//
+//   - Nível 0: a IR segue como foi gerada.
+//   - Nível 1: dobra de constantes (2 * 3 => 6), identidades algébricas
+//     (x*1, x+0, x*0...), multiplicação por potência de 2 vira deslocamento,
+//     desvios com resultado conhecido viram jmp e blocos inalcançáveis somem.
+//     Atribuições cujo valor nunca é lido, as contas que as alimentavam e a
+//     memória das variáveis que ficaram sem uso também somem.
+//
+// A divisão por uma constante zero é sempre um erro de compilação, em qualquer
+// nível: no x86 ela derrubaria o programa com SIGFPE.
+func Optimize(prog *ir.Program, level int) []Diagnostic {
+	var erros []Diagnostic
+	for _, fn := range prog.Funcs {
+		erros = append(erros, checkDivZero(fn)...)
+	}
+	if len(erros) > 0 || level < 1 {
+		return erros
+	}
+	for _, fn := range prog.Funcs {
+		fold(fn)
+		removeUnreachable(fn)
+		mergeBlocks(fn)
+		removeDeadCode(fn)
+		renumber(fn)
+	}
+	removeUnusedGlobals(prog)
+	return nil
+}
+
+// Diagnostic: Um erro ou aviso sobre a IR, com a linha do comando de origem.
+type Diagnostic struct {
+	Line    int
+	Message string
+}
+
+func (d Diagnostic) String() string { return d.Message }
+
+func diagnostic(line int, format string, a ...interface{}) Diagnostic {
+	return Diagnostic{Line: line, Message: fmt.Sprintf(format, a...)}
+}
+
+// checkDivZero: Como a aritmética é linear, o divisor é sempre um único operando:
+// se ele for constante, já aparece como imediato na IR.
+func checkDivZero(fn *ir.Function) []Diagnostic {
+	var erros []Diagnostic
+	for _, b := range fn.Blocks {
+		for _, in := range b.Instrs {
+			if in.Op != ir.OpDiv {
+				continue
+			}
+			switch c := in.Args[1].(type) {
+			case ir.IntConst:
+				if c.V == 0 {
+					erros = append(erros, diagnostic(in.Line, "Divisão por Zero (linha %d): o divisor é a constante 0", in.Line))
+				}
+			case ir.FloatConst:
+				if c.V == 0 {
+					erros = append(erros, diagnostic(in.Line, "Divisão por Zero (linha %d): o divisor é a constante 0.0", in.Line))
+				}
+			}
+		}
+	}
+	return erros
+}
+
+// fold: Percorre as instruções na ordem. Quando o resultado de uma instrução é
+// conhecido, ela some e os usos do temporário passam a usar o valor (subst).
+// Como cada temporário é definido uma única vez e antes dos seus usos, uma
+// passada basta.
+func fold(fn *ir.Function) {
+	subst := map[*ir.Temp]ir.Value{}
+	replace := func(v ir.Value) ir.Value {
+		if t, ok := v.(*ir.Temp); ok {
+			if r, ok := subst[t]; ok {
+				return r
+			}
+		}
+		return v
+	}
+
+	for _, b := range fn.Blocks {
+		kept := b.Instrs[:0]
+		comment := "" // Comentário de uma instrução removida, herdado pela próxima
+		for _, in := range b.Instrs {
+			for i, a := range in.Args {
+				in.Args[i] = replace(a)
+			}
+			if in.Mem != nil && in.Mem.Index != nil {
+				in.Mem.Index = replace(in.Mem.Index)
+			}
+
+			if v, ok := simplify(in); ok {
+				subst[in.Dst] = v
+				if in.Comment != "" {
+					comment = in.Comment
+				}
+				continue
+			}
+			foldBranch(in)
+
+			if comment != "" && in.Comment == "" {
+				in.Comment = comment
+			}
+			comment = ""
+			kept = append(kept, in)
+		}
+		b.Instrs = kept
+	}
+}
+
+// simplify: Se o valor de uma operação aritmética é conhecido (uma constante ou
+// um dos operandos), devolve esse valor. Também troca x * 2^k por x << k.
+func simplify(in *ir.Instr) (ir.Value, bool) {
+	switch in.Op {
+	case ir.OpAdd, ir.OpSub, ir.OpMul, ir.OpDiv, ir.OpShl:
+	default:
+		return nil, false
+	}
+	x, y := in.Args[0], in.Args[1]
+
+	if in.Dst.Ty == ir.F64 {
+		a, aok := x.(ir.FloatConst)
+		c, cok := y.(ir.FloatConst)
+		switch {
+		case aok && cok:
+			return ir.FloatConst{V: evalFloat(in.Op, a.V, c.V)}, true
+		// Só identidades exatas em ponto flutuante: x*0 não é 0 se x for NaN,
+		// e x+0.0 troca -0.0 por 0.0.
+		case cok && c.V == 1 && (in.Op == ir.OpMul || in.Op == ir.OpDiv),
+			cok && c.V == 0 && in.Op == ir.OpSub:
+			return x, true
+		case aok && a.V == 1 && in.Op == ir.OpMul:
+			return y, true
+		}
+		return nil, false
+	}
+
+	a, aok := x.(ir.IntConst)
+	c, cok := y.(ir.IntConst)
+	switch {
+	case aok && cok && in.Op == ir.OpDiv && a.V == math.MinInt64 && c.V == -1:
+		// O quociente não cabe em 64 bits e o idiv derruba o programa
+		// (SIGFPE): a divisão fica para a execução, como em -O0.
+		return nil, false
+	case aok && cok:
+		return ir.IntConst{V: evalInt(in.Op, a.V, c.V)}, true
+	case cok && c.V == 0 && (in.Op == ir.OpAdd || in.Op == ir.OpSub || in.Op == ir.OpShl),
+		cok && c.V == 1 && (in.Op == ir.OpMul || in.Op == ir.OpDiv):
+		return x, true
+	case aok && a.V == 0 && in.Op == ir.OpAdd,
+		aok && a.V == 1 && in.Op == ir.OpMul:
+		return y, true
+	case (aok && a.V == 0 || cok && c.V == 0) && in.Op == ir.OpMul:
+		return ir.IntConst{V: 0}, true
+	}
+
+	// Potência de 2: x * 8 => x << 3. Na divisão a troca não vale: o idiv
+	// arredonda em direção ao zero e o deslocamento aritmético (sar) para baixo
+	// (-7 / 2 = -3, mas -7 >> 1 = -4).
+	if in.Op == ir.OpMul && (aok || cok) {
+		val, k := x, c
+		if aok {
+			val, k = y, a
+		}
+		if k.V > 0 && k.V&(k.V-1) == 0 {
+			in.Op = ir.OpShl
+			in.Args = []ir.Value{val, ir.IntConst{V: int64(bits.TrailingZeros64(uint64(k.V)))}}
+		}
+	}
+	return nil, false
+}
+
+// evalInt: Mesma semântica do x86 de 64 bits: soma e produto dão a volta
+// (overflow silencioso) e a divisão trunca em direção ao zero. O único caso
+// em que o Go e o idiv discordam, MinInt64 / -1, nunca chega aqui (simplify).
+func evalInt(op ir.Op, a, b int64) int64 {
+	switch op {
+	case ir.OpAdd:
+		return a + b
+	case ir.OpSub:
+		return a - b
+	case ir.OpMul:
+		return a * b
+	case ir.OpShl:
+		return a << uint(b)
+	}
+	return a / b // O divisor zero já foi recusado por checkDivZero
+}
+
+func evalFloat(op ir.Op, a, b float64) float64 {
+	switch op {
+	case ir.OpAdd:
+		return a + b
+	case ir.OpSub:
+		return a - b
+	case ir.OpMul:
+		return a * b
+	}
+	return a / b
+}
+
+// foldBranch: Um desvio entre duas constantes (ex: a verificação de limites de
+// v[2*2]) vira um jmp para o único destino possível.
+func foldBranch(in *ir.Instr) {
+	if in.Op != ir.OpBranch {
+		return
+	}
+	a, aok := in.Args[0].(ir.IntConst)
+	c, cok := in.Args[1].(ir.IntConst)
+	if !aok || !cok {
+		return
+	}
+	var taken bool
+	switch in.Cond {
+	case ir.CondEq:
+		taken = a.V == c.V
+	case ir.CondNe:
+		taken = a.V != c.V
+	case ir.CondLt:
+		taken = a.V < c.V
+	case ir.CondGt:
+		taken = a.V > c.V
+	case ir.CondUlt:
+		taken = uint64(a.V) < uint64(c.V)
+	}
+	target := in.Targets[1]
+	if taken {
+		target = in.Targets[0]
+	}
+	in.Op, in.Cond, in.Args, in.Targets = ir.OpJump, "", nil, []*ir.Block{target}
+}
+
+// removeUnreachable: Descarta os blocos que nenhum caminho a partir da entrada
+// alcança (ex: o bloco de erro de um índice que se provou válido).
+func removeUnreachable(fn *ir.Function) {
+	if len(fn.Blocks) == 0 {
+		return
+	}
+	seen := map[*ir.Block]bool{}
+	var visit func(b *ir.Block)
+	visit = func(b *ir.Block) {
+		if seen[b] {
+			return
+		}
+		seen[b] = true
+		if len(b.Instrs) == 0 {
+			return
+		}
+		for _, t := range b.Instrs[len(b.Instrs)-1].Targets {
+			visit(t)
+		}
+	}
+	visit(fn.Blocks[0])
+
+	kept := fn.Blocks[:0]
+	for _, b := range fn.Blocks {
+		if seen[b] {
+			kept = append(kept, b)
+		}
+	}
+	fn.Blocks = kept
+}
+
+// mergeBlocks: Um bloco que termina em 'jmp B', sendo o único caminho até B,
+// absorve as instruções de B (ex: idx_ok_N depois de um desvio já resolvido).
+func mergeBlocks(fn *ir.Function) {
+	preds := map[*ir.Block]int{}
+	for _, b := range fn.Blocks {
+		for _, t := range b.Instrs[len(b.Instrs)-1].Targets {
+			preds[t]++
+		}
+	}
+
+	merged := map[*ir.Block]bool{}
+	for _, a := range fn.Blocks {
+		if merged[a] {
+			continue
+		}
+		for {
+			last := a.Instrs[len(a.Instrs)-1]
+			if last.Op != ir.OpJump {
+				break
+			}
+			b := last.Targets[0]
+			if b == a || b == fn.Blocks[0] || preds[b] != 1 {
+				break
+			}
+			if last.Comment != "" && b.Instrs[0].Comment == "" {
+				b.Instrs[0].Comment = last.Comment
+			}
+			a.Instrs = append(a.Instrs[:len(a.Instrs)-1], b.Instrs...)
+			merged[b] = true
+		}
+	}
+
+	kept := fn.Blocks[:0]
+	for _, b := range fn.Blocks {
+		if !merged[b] {
+			kept = append(kept, b)
+		}
+	}
+	fn.Blocks = kept
+}
+
+// renumber: Os temporários que sobraram são numerados de novo (%t0, %t1...),
+// para que a listagem fique contínua e o backend não reserve espaço à toa.
+func renumber(fn *ir.Function) {
+	var temps []*ir.Temp
+	for _, b := range fn.Blocks {
+		for _, in := range b.Instrs {
+			if in.Dst != nil {
+				in.Dst.ID = len(temps)
+				temps = append(temps, in.Dst)
+			}
+		}
+	}
+	fn.Temps = temps
+}
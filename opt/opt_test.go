@@ -0,0 +1,45 @@
+package opt
+
+import (
+	"csigma/ir"
+	"csigma/lexer"
+	"csigma/parser"
+	"csigma/semantic"
+	"testing"
+)
+
+// Em -O1 o desvio da verificação de limites de um índice calculado só com
+// constantes é dobrado: dentro dos limites ela some, fora dela o erro passa a
+// ser o único caminho.
+func TestOptimizeBoundsCheck(t *testing.T) {
+	tests := []struct {
+		name, src string
+		checks    int
+	}{
+		{"dentro", "const X = 2\nvar v[X]\nv[X - 1] = 7\nprint v[X - 1]\n", 0},
+		{"fora", "const X = 2\nvar v[X]\nv[X + 0] = 7\n", 1},
+		{"variavel", "var v[2]\nvar i = 1\nv[i] = 7\n", 1},
+	}
+	for _, tt := range tests {
+		statements, err := parser.NewParser(lexer.Tokenize(tt.src)).ParseProgram()
+		if err != nil {
+			t.Fatal(err)
+		}
+		semantic.NewAnalyzer().Validar(statements)
+		prog := ir.Lower(statements, ir.Options{})
+		if diags := Optimize(prog, 1); len(diags) > 0 {
+			t.Fatalf("%s: %v", tt.name, diags)
+		}
+		n := 0
+		for _, b := range prog.Funcs[0].Blocks {
+			for _, in := range b.Instrs {
+				if in.Op == ir.OpCall && in.Func == ir.FuncBoundsError {
+					n++
+				}
+			}
+		}
+		if n != tt.checks {
+			t.Errorf("%s: %d verificações de limites depois do -O1, quer %d\n%s", tt.name, n, tt.checks, prog)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package parser
 import (
 	"csigma/lexer"
 	"fmt"
+	"strconv"
+	"strings"
 )
 
 // Statement: Interface base. No Go, interfaces vazias permitem que
@@ -11,37 +13,147 @@ type Statement interface{}
 
 // --- NÓS DA AST (Modelagem de Dados) ---
 
-// VarDeclNode armazena 'var x = 10'.
+// Span: O trecho do fonte de um comando, do primeiro caractere (Line, Column)
+// até a posição logo depois do último (EndLine, EndColumn). Colunas em bytes,
+// começando em 1.
+type Span struct {
+	Line, Column       int
+	EndLine, EndColumn int
+}
+
+// VarDeclNode armazena 'var x = 10' (ou 'var nome = "Sigma"', com IsString).
+// Vetores ('var v[10]' ou 'var v: float[10]') têm Size > 0 e o tipo dos
+// elementos em ElemType ("int" ou "float"); nesse caso Value fica vazio.
+// SizeConst guarda o nome da constante usada como tamanho ('var v[MAX]'),
+// e o Analisador Semântico preenche Size com o seu valor.
 type VarDeclNode struct {
-	Name  string
-	Value string
+	Name      string
+	Value     string
+	IsString  bool
+	Size      int
+	SizeConst string
+	ElemType  string
+	Line      int
+	Span      Span
+}
+
+// ConstDeclNode armazena 'const MAX = 100' ou 'const AREA = MAX * MAX'.
+// A expressão é avaliada em tempo de compilação pelo Analisador Semântico,
+// que deixa o resultado em Valor (e o tipo em ExprNode.Tipo).
+type ConstDeclNode struct {
+	Name string
+	ExprNode
+	Valor string
+	Line  int
+	Span  Span
 }
 
 // PrintNode identifica se o que será impresso é uma constante textual ou variável.
+// Parts só é preenchido quando há valores a formatar: na interpolação
+// ("a = {a}") ou ao imprimir uma expressão (print res).
+// NoNewline marca o comando 'write', que mantém o cursor na mesma linha.
 type PrintNode struct {
-	Value    string
-	IsString bool
+	Value     string
+	IsString  bool
+	Parts     []InterpPart
+	NoNewline bool
+	Line      int
+	Span      Span
+}
+
+// InterpPart: um pedaço de uma string interpolada.
+// Ou é texto fixo (Text), ou é uma expressão embutida entre chaves (Expr).
+type InterpPart struct {
+	Text string
+	Expr *ExprNode
 }
 
 // InputNode mapeia o comando 'input' para o destino na memória.
+// Prompt é opcional ('input "Digite a: " a') e se comporta como um 'write'.
+// Tipo (preenchido pelo Analisador Semântico) decide entre ler %ld ou %lf.
+// Index aponta a posição quando o destino é um elemento de vetor ('input v[i]').
 type InputNode struct {
 	VarName string
+	Index   *ExprNode
+	Prompt  *PrintNode
+	Tipo    string
+	Line    int
+	Span    Span
+}
+
+// Operand: um valor atômico da expressão (literal numérico, texto ou variável).
+// IsCall marca uma chamada de função embutida, como 'eof()' ou 'len(v)'; o nome
+// fica em Value e os argumentos em Args. Index é preenchido no acesso 'v[i]'.
+type Operand struct {
+	Value    string
+	IsVar    bool
+	IsString bool
+	IsCall   bool
+	Args     []Operand
+	Index    *ExprNode
 }
 
 // OrderOp: Estrutura crucial para a aritmética.
 // Guarda o operador e o valor seguinte, permitindo cálculos encadeados.
 type OrderOp struct {
 	Operator string
-	Value    string
-	IsVar    bool
+	Operand
+}
+
+// ExprNode: Expressão aritmética linear (avaliada da esquerda para a direita).
+// Tipo é preenchido pelo Analisador Semântico (SIGMA_INT, SIGMA_FLT, SIGMA_STR)
+// e consultado pelo CodeGen para escolher registradores e formatos.
+type ExprNode struct {
+	First Operand
+	Ops   []OrderOp
+	Tipo  string
 }
 
 // AssignmentNode: O nó mais complexo. Guarda a variável de destino (Dest),
 // o primeiro valor (First) e uma fatia (slice) de todas as operações seguintes.
+// DestIndex só existe quando o destino é um elemento de vetor ('v[i] = ...').
 type AssignmentNode struct {
-	Dest  string
-	First string
-	Ops   []OrderOp
+	Dest      string
+	DestIndex *ExprNode
+	ExprNode
+	Line int
+	Span Span
+}
+
+// DestString: O destino como aparece no fonte ('res' ou 'v[i + 1]').
+func (n *AssignmentNode) DestString() string {
+	if n.DestIndex != nil {
+		return n.Dest + "[" + n.DestIndex.String() + "]"
+	}
+	return n.Dest
+}
+
+// String: Reconstrói a expressão em texto (usado no Dump da AST).
+func (e *ExprNode) String() string {
+	var sb strings.Builder
+	sb.WriteString(e.First.String())
+	for _, op := range e.Ops {
+		sb.WriteString(" " + op.Operator + " " + op.Operand.String())
+	}
+	return sb.String()
+}
+
+// String: Literais de texto voltam a ganhar aspas para não serem confundidos com variáveis.
+func (o Operand) String() string {
+	if o.IsString {
+		return "\"" + o.Value + "\""
+	}
+	if o.IsCall {
+		args := make([]string, len(o.Args))
+		for i, arg := range o.Args {
+			args[i] = arg.String()
+		}
+		return o.Value + "(" + strings.Join(args, ", ") + ")"
+	}
+	if o.Index != nil {
+		return o.Value + "[" + o.Index.String() + "]"
+	}
+	return o.Value
 }
 
 // --- ESTRUTURA E LÓGICA DO PARSER ---
@@ -63,12 +175,15 @@ func (p *Parser) ParseProgram() ([]Statement, error) {
 	for p.pos < len(p.tokens) && p.tokens[p.pos].Type != lexer.TokenEOF {
 		var stmt Statement
 		var err error
+		start := p.pos
 
 		// Padrão de Projeto: Recursive Descent Lite
 		switch p.tokens[p.pos].Type {
 		case lexer.TokenVar:
 			stmt, err = p.parseVarDecl()
-		case lexer.TokenPrint:
+		case lexer.TokenConst:
+			stmt, err = p.parseConstDecl()
+		case lexer.TokenPrint, lexer.TokenWrite:
 			stmt, err = p.parsePrint()
 		case lexer.TokenInput:
 			stmt, err = p.parseInput()
@@ -83,78 +198,421 @@ func (p *Parser) ParseProgram() ([]Statement, error) {
 		if err != nil {
 			return nil, err
 		}
+		setSpan(stmt, p.span(start))
 		statements = append(statements, stmt)
 	}
 	return statements, nil
 }
 
+// span: O trecho do fonte dos tokens de tokens[start] até o último consumido.
+func (p *Parser) span(start int) Span {
+	first, last := p.tokens[start], p.tokens[p.pos-1]
+	text := last.Literal
+	if last.Type == lexer.TokenString {
+		text = "\"" + text + "\""
+	}
+	sp := Span{Line: first.Line, Column: first.Column, EndLine: last.Line, EndColumn: last.Column + len(text)}
+	if n := strings.Count(text, "\n"); n > 0 {
+		// Texto com quebras de linha: termina em outra linha.
+		sp.EndLine += n
+		sp.EndColumn = len(text) - strings.LastIndexByte(text, '\n')
+	}
+	return sp
+}
+
+// SpanOf: O trecho do fonte de um comando (vazio se não veio do parser).
+func SpanOf(stmt Statement) Span {
+	switch s := stmt.(type) {
+	case *VarDeclNode:
+		return s.Span
+	case *ConstDeclNode:
+		return s.Span
+	case *PrintNode:
+		return s.Span
+	case *InputNode:
+		return s.Span
+	case *AssignmentNode:
+		return s.Span
+	}
+	return Span{}
+}
+
+// setSpan: Guarda o trecho no nó do comando.
+func setSpan(stmt Statement, sp Span) {
+	switch s := stmt.(type) {
+	case *VarDeclNode:
+		s.Span = sp
+	case *ConstDeclNode:
+		s.Span = sp
+	case *PrintNode:
+		s.Span = sp
+	case *InputNode:
+		s.Span = sp
+	case *AssignmentNode:
+		s.Span = sp
+	}
+}
+
 // parseAssignment: Implementa a "Aritmética Linear".
-// Ele lê o destino, o '=' e então entra em um loop para capturar quantos
-// operadores e valores existirem na mesma linha.
+// Ele lê o destino, o '=' e então delega a expressão para parseExpr.
 func (p *Parser) parseAssignment() (Statement, error) {
-	// 1. Captura o destino (L-Value)
+	// 1. Captura o destino (L-Value), que pode ser um elemento de vetor
+	line := p.tokens[p.pos].Line
 	dest := p.tokens[p.pos].Literal
 	p.pos++
 
+	var destIndex *ExprNode
+	if p.peekIs(lexer.TokenLBracket) {
+		idx, err := p.parseIndex(dest)
+		if err != nil {
+			return nil, err
+		}
+		destIndex = idx
+	}
+
 	// 2. Consome o sinal de atribuição
-	if p.pos >= len(p.tokens) || p.tokens[p.pos].Type != lexer.TokenAssign {
+	if !p.peekIs(lexer.TokenAssign) {
 		return nil, fmt.Errorf("erro sintático: esperado '=' após identificador '%s'", dest)
 	}
 	p.pos++
 
-	// 3. Captura o primeiro valor da expressão
+	// 3. Captura a expressão à direita do '='
+	expr, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	return &AssignmentNode{Dest: dest, DestIndex: destIndex, ExprNode: *expr, Line: line}, nil
+}
+
+// parseIndex: Consome '[expressão]' logo após o nome de um vetor.
+func (p *Parser) parseIndex(name string) (*ExprNode, error) {
+	p.pos++ // pula '['
+	idx, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.peekIs(lexer.TokenRBracket) {
+		return nil, fmt.Errorf("erro sintático: esperado ']' no índice de '%s'", name)
+	}
+	p.pos++ // pula ']'
+	return idx, nil
+}
+
+// Token: O token em que a análise parou; depois de um erro, o que o causou
+// (usado pelo editor para apontar a posição do erro sintático).
+func (p *Parser) Token() lexer.Token {
 	if p.pos >= len(p.tokens) {
-		return nil, fmt.Errorf("erro sintático: expressão incompleta")
+		return p.tokens[len(p.tokens)-1]
 	}
-	first := p.tokens[p.pos].Literal
-	p.pos++
+	return p.tokens[p.pos]
+}
 
-	stmt := &AssignmentNode{Dest: dest, First: first}
+// peekIs: Confere o tipo do token atual sem consumi-lo.
+func (p *Parser) peekIs(t lexer.TokenType) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].Type == t
+}
 
-	// 4. Loop de Operadores: Enquanto houver +, -, * ou /, o parser continua montando a AST.
+// parseExpr: Lê o primeiro valor e entra em um loop para capturar quantos
+// operadores e valores existirem na sequência.
+func (p *Parser) parseExpr() (*ExprNode, error) {
+	first, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	expr := &ExprNode{First: first}
+
+	// Loop de Operadores: Enquanto houver +, -, * ou /, o parser continua montando a AST.
 	// Isso permite que 'a + b * c / d' seja capturado em um único nó.
 	for p.pos < len(p.tokens) && isOperator(p.tokens[p.pos].Type) {
 		op := p.tokens[p.pos].Literal
 		p.pos++
 
-		if p.pos >= len(p.tokens) {
+		if p.pos >= len(p.tokens) || p.tokens[p.pos].Type == lexer.TokenEOF {
 			return nil, fmt.Errorf("erro sintático: esperado valor após operador '%s'", op)
 		}
 
-		val := p.tokens[p.pos].Literal
-		isVar := p.tokens[p.pos].Type == lexer.TokenIdent
+		val, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		expr.Ops = append(expr.Ops, OrderOp{Operator: op, Operand: val})
+	}
+
+	return expr, nil
+}
 
-		stmt.Ops = append(stmt.Ops, OrderOp{
-			Operator: op,
-			Value:    val,
-			IsVar:    isVar,
-		})
+// parseOperand: Consome um único valor (número, texto, variável, 'v[i]' ou 'funcao(...)').
+func (p *Parser) parseOperand() (Operand, error) {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].Type == lexer.TokenEOF {
+		return Operand{}, fmt.Errorf("erro sintático: expressão incompleta")
+	}
+	tok := p.tokens[p.pos]
+	switch tok.Type {
+	case lexer.TokenIdent:
+		p.pos++
+		if p.peekIs(lexer.TokenLParen) {
+			return p.parseCall(tok.Literal)
+		}
+		if p.peekIs(lexer.TokenLBracket) {
+			idx, err := p.parseIndex(tok.Literal)
+			if err != nil {
+				return Operand{}, err
+			}
+			return Operand{Value: tok.Literal, IsVar: true, Index: idx}, nil
+		}
+		return Operand{Value: tok.Literal, IsVar: true}, nil
+	case lexer.TokenInt, lexer.TokenFloat:
 		p.pos++
+		return Operand{Value: tok.Literal}, nil
+	case lexer.TokenString:
+		p.pos++
+		return Operand{Value: tok.Literal, IsString: true}, nil
 	}
+	return Operand{}, fmt.Errorf("erro sintático: valor inesperado '%s' na expressão", tok.Literal)
+}
 
-	return stmt, nil
+// parseCall: Consome 'nome(arg1, arg2, ...)'. Os argumentos são valores simples.
+func (p *Parser) parseCall(name string) (Operand, error) {
+	p.pos++ // pula '('
+	call := Operand{Value: name, IsCall: true}
+	for !p.peekIs(lexer.TokenRParen) {
+		if len(call.Args) > 0 {
+			if !p.peekIs(lexer.TokenComma) {
+				return Operand{}, fmt.Errorf("erro sintático: esperado ',' ou ')' em '%s(...)'", name)
+			}
+			p.pos++ // pula ','
+		}
+		arg, err := p.parseOperand()
+		if err != nil {
+			return Operand{}, err
+		}
+		call.Args = append(call.Args, arg)
+	}
+	p.pos++ // pula ')'
+	return call, nil
 }
 
 // --- FUNÇÕES DE CONSUMO DE TOKENS ---
 
 // parseVarDecl: Transforma 'var x = 0' em um nó estruturado.
+// Também reconhece vetores: 'var v[10]' (inteiros) e 'var v: float[10]'.
 func (p *Parser) parseVarDecl() (Statement, error) {
+	line := p.tokens[p.pos].Line
 	p.pos++ // pula 'var'
+	if !p.peekIs(lexer.TokenIdent) {
+		return nil, fmt.Errorf("erro sintático: esperado nome após 'var'")
+	}
 	name := p.tokens[p.pos].Literal
 	p.pos++ // pula nome
+
+	if p.peekIs(lexer.TokenLBracket) || p.peekIs(lexer.TokenColon) {
+		return p.parseArrayDecl(name, line)
+	}
+
+	if !p.peekIs(lexer.TokenAssign) {
+		return nil, fmt.Errorf("erro sintático: esperado '=' após 'var %s'", name)
+	}
 	p.pos++ // pula '='
+	if !p.peekIs(lexer.TokenInt) && !p.peekIs(lexer.TokenFloat) && !p.peekIs(lexer.TokenString) {
+		return nil, fmt.Errorf("erro sintático: esperado valor inicial (número ou texto) em 'var %s ='", name)
+	}
+	isStr := p.tokens[p.pos].Type == lexer.TokenString
 	val := p.tokens[p.pos].Literal
 	p.pos++ // pula valor
-	return &VarDeclNode{Name: name, Value: val}, nil
+	return &VarDeclNode{Name: name, Value: val, IsString: isStr, Line: line}, nil
 }
 
-// parsePrint: Identifica o conteúdo do comando print.
+// parseArrayDecl: Lê o '[tamanho]' (precedido opcionalmente por ': tipo').
+// O tamanho precisa ser um inteiro literal, pois a memória é reservada na compilação.
+func (p *Parser) parseArrayDecl(name string, line int) (Statement, error) {
+	elemType := "int"
+	if p.peekIs(lexer.TokenColon) {
+		p.pos++ // pula ':'
+		if !p.peekIs(lexer.TokenIdent) {
+			return nil, fmt.Errorf("erro sintático: esperado tipo após '%s:'", name)
+		}
+		elemType = p.tokens[p.pos].Literal
+		if elemType != "int" && elemType != "float" {
+			return nil, fmt.Errorf("erro sintático: tipo de vetor desconhecido '%s' (use int ou float)", elemType)
+		}
+		p.pos++ // pula tipo
+	}
+
+	if !p.peekIs(lexer.TokenLBracket) {
+		return nil, fmt.Errorf("erro sintático: esperado '[tamanho]' na declaração do vetor '%s'", name)
+	}
+	p.pos++ // pula '['
+	decl := &VarDeclNode{Name: name, ElemType: elemType, Line: line}
+	switch {
+	case p.peekIs(lexer.TokenInt):
+		size, err := strconv.Atoi(p.tokens[p.pos].Literal)
+		if err != nil {
+			return nil, fmt.Errorf("erro sintático: o tamanho do vetor '%s' não cabe em um inteiro de 64 bits (%s)", name, p.tokens[p.pos].Literal)
+		}
+		decl.Size = size
+	case p.peekIs(lexer.TokenIdent):
+		decl.SizeConst = p.tokens[p.pos].Literal
+	default:
+		return nil, fmt.Errorf("erro sintático: o tamanho do vetor '%s' deve ser um inteiro literal ou uma constante", name)
+	}
+	p.pos++ // pula tamanho
+	if !p.peekIs(lexer.TokenRBracket) {
+		return nil, fmt.Errorf("erro sintático: esperado ']' na declaração do vetor '%s'", name)
+	}
+	p.pos++ // pula ']'
+
+	return decl, nil
+}
+
+// parseConstDecl: Transforma 'const NOME = expressão' em um nó estruturado.
+func (p *Parser) parseConstDecl() (Statement, error) {
+	line := p.tokens[p.pos].Line
+	p.pos++ // pula 'const'
+	if !p.peekIs(lexer.TokenIdent) {
+		return nil, fmt.Errorf("erro sintático: esperado nome após 'const'")
+	}
+	name := p.tokens[p.pos].Literal
+	p.pos++ // pula nome
+	if !p.peekIs(lexer.TokenAssign) {
+		return nil, fmt.Errorf("erro sintático: esperado '=' após 'const %s'", name)
+	}
+	p.pos++ // pula '='
+
+	expr, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return &ConstDeclNode{Name: name, ExprNode: *expr, Line: line}, nil
+}
+
+// parsePrint: Identifica o conteúdo do comando print (ou write).
+// Strings com '{' passam pela interpolação; qualquer outro valor é uma expressão.
 func (p *Parser) parsePrint() (Statement, error) {
-	p.pos++ // pula 'print'
-	isStr := p.tokens[p.pos].Type == lexer.TokenString
+	cmd := p.tokens[p.pos]
+	p.pos++ // pula 'print' / 'write'
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].Type == lexer.TokenEOF {
+		return nil, fmt.Errorf("erro sintático: esperado valor após '%s'", cmd.Literal)
+	}
+	noNewline := cmd.Type == lexer.TokenWrite
+
+	if p.tokens[p.pos].Type == lexer.TokenString {
+		stmt, err := p.parseStringOutput(noNewline)
+		if err != nil {
+			return nil, err
+		}
+		stmt.Line = cmd.Line
+		return stmt, nil
+	}
+
 	val := p.tokens[p.pos].Literal
+	expr, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return &PrintNode{Value: val, Parts: []InterpPart{{Expr: expr}}, NoNewline: noNewline, Line: cmd.Line}, nil
+}
+
+// parseStringOutput: Consome uma string literal (interpolada ou não) como saída.
+func (p *Parser) parseStringOutput(noNewline bool) (*PrintNode, error) {
+	val := p.tokens[p.pos].Literal
+	line := p.tokens[p.pos].Line
+	p.pos++
+	stmt := &PrintNode{Value: val, IsString: true, NoNewline: noNewline, Line: line}
+	if strings.ContainsAny(val, "{}") {
+		parts, err := parseInterpolation(val)
+		if err != nil {
+			return nil, err
+		}
+		stmt.Parts = parts
+	}
+	return stmt, nil
+}
+
+// parseInput: Transforma 'input x' (ou 'input "Prompt: " x') em um nó que aponta
+// a variável de destino, que também pode ser um elemento de vetor ('input v[i]').
+func (p *Parser) parseInput() (Statement, error) {
+	stmt := &InputNode{Line: p.tokens[p.pos].Line}
+	p.pos++ // pula 'input'
+	if p.pos < len(p.tokens) && p.tokens[p.pos].Type == lexer.TokenString {
+		prompt, err := p.parseStringOutput(true)
+		if err != nil {
+			return nil, err
+		}
+		stmt.Prompt = prompt
+	}
+
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].Type != lexer.TokenIdent {
+		return nil, fmt.Errorf("erro sintático: esperado variável após 'input'")
+	}
+	stmt.VarName = p.tokens[p.pos].Literal
 	p.pos++
-	return &PrintNode{Value: val, IsString: isStr}, nil
+	if p.peekIs(lexer.TokenLBracket) {
+		idx, err := p.parseIndex(stmt.VarName)
+		if err != nil {
+			return nil, err
+		}
+		stmt.Index = idx
+	}
+	return stmt, nil
+}
+
+// parseInterpolation: Quebra "a = {a}, soma = {a + b}" em texto fixo e expressões.
+// Cada trecho entre chaves é reanalisado por um Lexer/Parser próprio, de modo que
+// a expressão embutida segue exatamente a mesma gramática de uma atribuição.
+// Para imprimir chaves literais, basta duplicá-las: "{{" e "}}".
+func parseInterpolation(raw string) ([]InterpPart, error) {
+	var parts []InterpPart
+	var text strings.Builder
+
+	for i := 0; i < len(raw); i++ {
+		ch := raw[i]
+		switch {
+		case ch == '{' && i+1 < len(raw) && raw[i+1] == '{':
+			text.WriteByte('{')
+			i++
+		case ch == '}' && i+1 < len(raw) && raw[i+1] == '}':
+			text.WriteByte('}')
+			i++
+		case ch == '}':
+			return nil, fmt.Errorf("erro sintático: '}' sem '{' correspondente em \"%s\"", raw)
+		case ch == '{':
+			end := strings.IndexByte(raw[i+1:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("erro sintático: '{' sem '}' correspondente em \"%s\"", raw)
+			}
+			inner := raw[i+1 : i+1+end]
+			expr, err := parseEmbeddedExpr(inner)
+			if err != nil {
+				return nil, err
+			}
+			if text.Len() > 0 {
+				parts = append(parts, InterpPart{Text: text.String()})
+				text.Reset()
+			}
+			parts = append(parts, InterpPart{Expr: expr})
+			i += end + 1
+		default:
+			text.WriteByte(ch)
+		}
+	}
+	if text.Len() > 0 {
+		parts = append(parts, InterpPart{Text: text.String()})
+	}
+	return parts, nil
+}
+
+// parseEmbeddedExpr: Analisa o conteúdo de '{...}' exigindo que tudo seja consumido.
+func parseEmbeddedExpr(src string) (*ExprNode, error) {
+	sub := NewParser(lexer.Tokenize(src))
+	expr, err := sub.parseExpr()
+	if err != nil {
+		return nil, fmt.Errorf("%v (na interpolação '{%s}')", err, src)
+	}
+	if sub.tokens[sub.pos].Type != lexer.TokenEOF {
+		return nil, fmt.Errorf("erro sintático: '%s' inesperado na interpolação '{%s}'", sub.tokens[sub.pos].Literal, src)
+	}
+	return expr, nil
 }
 
 // isOperator: Helper para definir os limites da expressão aritmética.
@@ -0,0 +1,386 @@
+package regalloc
+
+import (
+	"csigma/ir"
+	"sort"
+)
+
+// Registers: Registradores callee-saved da convenção System V. As chamadas ao
+// printf/scanf não os alteram, então um valor guardado neles sobrevive a um
+// 'print' ou 'input' no meio do caminho. Decimais também moram aqui (os bits do
+// double): todos os XMM são destruídos pelas chamadas.
+var Registers = []string{"rbx", "r12", "r13", "r14", "r15"}
+
+// Allocation: Onde cada valor da função vive durante toda a sua vida.
+type Allocation struct {
+	// Reg: Temporários que ganharam um registrador. Os demais ficam na pilha.
+	Reg map[*ir.Temp]string
+	// VarReg: Variáveis promovidas a registrador. As demais ficam na pilha do 'main'.
+	VarReg map[string]string
+	// Shared: Temporários que são a própria variável: um 'load @x' cujo valor
+	// não muda até o último uso, ou um resultado gravado logo em seguida em @x.
+	// Eles não geram cópia nenhuma.
+	Shared map[*ir.Temp]string
+	// LiveIn: Variáveis promovidas lidas antes de serem gravadas: o prólogo
+	// põe o valor inicial da declaração no registrador.
+	LiveIn []string
+	// Used: Registradores usados (o 'main' precisa preservá-los para quem o chamou).
+	Used []string
+}
+
+// value: Um temporário (*ir.Temp) ou uma variável promovida (string).
+type value interface{}
+
+// interval: Do primeiro ao último ponto em que o valor está vivo.
+type interval struct {
+	v          value
+	start, end int
+	reg        string
+	born       bool // Definido na própria instrução 'start' (e não apenas lido ali)
+}
+
+// Allocate: Alocação por varredura linear (linear scan). Os intervalos de vida
+// vêm da análise de vivacidade (liveness) sobre os blocos básicos, então valores
+// usados dentro de um laço (como a nova tentativa do 'input') continuam vivos
+// no laço inteiro. Quando faltam registradores, fica na memória o valor cujo
+// intervalo termina mais longe.
+func Allocate(prog *ir.Program, fn *ir.Function, regs []string) *Allocation {
+	a := &Allocation{
+		Reg:    map[*ir.Temp]string{},
+		VarReg: map[string]string{},
+		Shared: map[*ir.Temp]string{},
+	}
+	promoted := promotable(prog, fn)
+
+	// Posição global de cada instrução, na ordem dos blocos.
+	pos := map[*ir.Instr]int{}
+	n := 0
+	for _, b := range fn.Blocks {
+		for _, in := range b.Instrs {
+			pos[in] = n
+			n++
+		}
+	}
+
+	// Usos de cada temporário: quantos e em que blocos/posições.
+	uses := map[*ir.Temp][]int{}
+	useBlock := map[*ir.Temp]map[*ir.Block]bool{}
+	for _, b := range fn.Blocks {
+		for _, in := range b.Instrs {
+			for _, t := range tempUses(in) {
+				uses[t] = append(uses[t], pos[in])
+				if useBlock[t] == nil {
+					useBlock[t] = map[*ir.Block]bool{}
+				}
+				useBlock[t][b] = true
+			}
+		}
+	}
+
+	// Resultado gravado logo em seguida em uma variável: 'res = a + b' calcula
+	// direto no registrador de 'res'.
+	writes := map[string][]int{}
+	for _, b := range fn.Blocks {
+		for i, in := range b.Instrs {
+			if in.Op == ir.OpStore && in.Mem.Index == nil && promoted[in.Mem.Sym] {
+				writes[in.Mem.Sym] = append(writes[in.Mem.Sym], pos[in])
+			}
+			if in.Dst == nil || in.Op == ir.OpLoad || in.Op == ir.OpCall || i+1 >= len(b.Instrs) {
+				continue
+			}
+			next := b.Instrs[i+1]
+			if next.Op == ir.OpStore && next.Mem.Index == nil && promoted[next.Mem.Sym] &&
+				next.Args[0] == ir.Value(in.Dst) && len(uses[in.Dst]) == 1 {
+				a.Shared[in.Dst] = next.Mem.Sym
+				writes[next.Mem.Sym] = append(writes[next.Mem.Sym], pos[in])
+			}
+		}
+	}
+
+	// 'load @x' sem cópia: todos os usos no mesmo bloco e nenhuma escrita em @x
+	// antes do último uso.
+	for _, b := range fn.Blocks {
+		for _, in := range b.Instrs {
+			if in.Op != ir.OpLoad || in.Mem.Index != nil || !promoted[in.Mem.Sym] {
+				continue
+			}
+			ok := len(useBlock[in.Dst]) == 0 || (len(useBlock[in.Dst]) == 1 && useBlock[in.Dst][b])
+			last := pos[in]
+			for _, u := range uses[in.Dst] {
+				if u > last {
+					last = u
+				}
+				if u < pos[in] {
+					ok = false
+				}
+			}
+			for _, w := range writes[in.Mem.Sym] {
+				if w > pos[in] && w < last {
+					ok = false
+				}
+			}
+			if ok {
+				a.Shared[in.Dst] = in.Mem.Sym
+			}
+		}
+	}
+
+	key := func(v ir.Value) value {
+		t, ok := v.(*ir.Temp)
+		if !ok {
+			return nil
+		}
+		if name, ok := a.Shared[t]; ok {
+			return name
+		}
+		return t
+	}
+
+	// --- VIVACIDADE (liveness) ---
+	// use[b]: valores lidos em b antes de qualquer definição; def[b]: definidos em b.
+	type sets struct{ use, def, in, out map[value]bool }
+	info := map[*ir.Block]*sets{}
+	var order []value // Ordem da primeira aparição (deixa a alocação determinística)
+	seen := map[value]bool{}
+	note := func(v value) {
+		if !seen[v] {
+			seen[v] = true
+			order = append(order, v)
+		}
+	}
+	for _, b := range fn.Blocks {
+		s := &sets{use: map[value]bool{}, def: map[value]bool{}, in: map[value]bool{}, out: map[value]bool{}}
+		info[b] = s
+		for _, in := range b.Instrs {
+			for _, u := range instrUses(in, key, promoted) {
+				note(u)
+				if !s.def[u] {
+					s.use[u] = true
+				}
+			}
+			if d := instrDef(in, key, promoted); d != nil {
+				note(d)
+				s.def[d] = true
+			}
+		}
+	}
+	// in[b] = use[b] + (out[b] - def[b]); out[b] = união de in[sucessores].
+	for changed := true; changed; {
+		changed = false
+		for i := len(fn.Blocks) - 1; i >= 0; i-- {
+			b := fn.Blocks[i]
+			s := info[b]
+			for _, succ := range b.Instrs[len(b.Instrs)-1].Targets {
+				for v := range info[succ].in {
+					if !s.out[v] {
+						s.out[v] = true
+						changed = true
+					}
+				}
+			}
+			for v := range s.use {
+				if !s.in[v] {
+					s.in[v] = true
+					changed = true
+				}
+			}
+			for v := range s.out {
+				if !s.def[v] && !s.in[v] {
+					s.in[v] = true
+					changed = true
+				}
+			}
+		}
+	}
+
+	// --- INTERVALOS ---
+	ivs := map[value]*interval{}
+	defs := map[value][]int{}
+	extend := func(v value, p int) {
+		iv, ok := ivs[v]
+		if !ok {
+			ivs[v] = &interval{v: v, start: p, end: p}
+			return
+		}
+		if p < iv.start {
+			iv.start = p
+		}
+		if p > iv.end {
+			iv.end = p
+		}
+	}
+	for _, b := range fn.Blocks {
+		first, last := pos[b.Instrs[0]], pos[b.Instrs[len(b.Instrs)-1]]
+		for v := range info[b].in {
+			extend(v, first)
+		}
+		for v := range info[b].out {
+			extend(v, last)
+		}
+		for _, in := range b.Instrs {
+			for _, u := range instrUses(in, key, promoted) {
+				extend(u, pos[in])
+			}
+			if d := instrDef(in, key, promoted); d != nil {
+				extend(d, pos[in])
+				defs[d] = append(defs[d], pos[in])
+			}
+		}
+	}
+	for v, ps := range defs {
+		for _, p := range ps {
+			if p == ivs[v].start {
+				ivs[v].born = true
+			}
+		}
+	}
+
+	// --- VARREDURA LINEAR ---
+	var list []*interval
+	for _, v := range order {
+		list = append(list, ivs[v])
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].start < list[j].start })
+
+	free := append([]string{}, regs...)
+	var active []*interval
+	for _, cur := range list {
+		// Libera os registradores de intervalos que já terminaram. Um valor que
+		// morre na instrução em que outro nasce pode ceder o registrador:
+		// as leituras acontecem antes da escrita.
+		kept := active[:0]
+		for _, iv := range active {
+			if iv.end < cur.start || iv.end == cur.start && cur.born {
+				free = append(free, iv.reg)
+			} else {
+				kept = append(kept, iv)
+			}
+		}
+		active = kept
+		sort.Slice(free, func(i, j int) bool { return regIndex(regs, free[i]) < regIndex(regs, free[j]) })
+
+		if len(free) > 0 {
+			cur.reg, free = free[0], free[1:]
+			active = append(active, cur)
+		} else {
+			// Derramamento (spill): quem termina mais longe cede o lugar.
+			far := cur
+			for _, iv := range active {
+				if iv.end > far.end {
+					far = iv
+				}
+			}
+			if far != cur {
+				cur.reg, far.reg = far.reg, ""
+				for i, iv := range active {
+					if iv == far {
+						active[i] = cur
+					}
+				}
+			}
+		}
+	}
+
+	for _, iv := range list {
+		if iv.reg == "" {
+			continue
+		}
+		switch v := iv.v.(type) {
+		case *ir.Temp:
+			a.Reg[v] = iv.reg
+		case string:
+			a.VarReg[v] = iv.reg
+			if info[fn.Blocks[0]].in[v] {
+				a.LiveIn = append(a.LiveIn, v)
+			}
+		}
+	}
+	for _, r := range regs {
+		if owned(a, r) {
+			a.Used = append(a.Used, r)
+		}
+	}
+	return a
+}
+
+// promotable: Variáveis simples cujo endereço nunca é tomado (o 'input' grava
+// pela memória) podem morar em um registrador.
+func promotable(prog *ir.Program, fn *ir.Function) map[string]bool {
+	ok := map[string]bool{}
+	for _, g := range prog.Globals {
+		if g.Size == 0 {
+			ok[g.Name] = true
+		}
+	}
+	for _, b := range fn.Blocks {
+		for _, in := range b.Instrs {
+			if in.Op == ir.OpAddr {
+				delete(ok, in.Mem.Sym)
+			}
+		}
+	}
+	return ok
+}
+
+// tempUses: Temporários lidos pela instrução (inclusive no índice de @v[%t]).
+func tempUses(in *ir.Instr) []*ir.Temp {
+	var ts []*ir.Temp
+	for _, a := range in.Args {
+		if t, ok := a.(*ir.Temp); ok {
+			ts = append(ts, t)
+		}
+	}
+	if in.Mem != nil {
+		if t, ok := in.Mem.Index.(*ir.Temp); ok {
+			ts = append(ts, t)
+		}
+	}
+	return ts
+}
+
+func instrUses(in *ir.Instr, key func(ir.Value) value, promoted map[string]bool) []value {
+	var vs []value
+	for _, t := range tempUses(in) {
+		vs = append(vs, key(t))
+	}
+	if in.Op == ir.OpLoad && in.Mem.Index == nil && promoted[in.Mem.Sym] {
+		if _, shared := key(in.Dst).(string); !shared {
+			vs = append(vs, in.Mem.Sym)
+		}
+	}
+	return vs
+}
+
+func instrDef(in *ir.Instr, key func(ir.Value) value, promoted map[string]bool) value {
+	if in.Op == ir.OpStore && in.Mem.Index == nil && promoted[in.Mem.Sym] {
+		return in.Mem.Sym
+	}
+	if in.Dst != nil && !(in.Op == ir.OpLoad && key(in.Dst) == value(in.Mem.Sym)) {
+		return key(in.Dst)
+	}
+	return nil
+}
+
+func regIndex(regs []string, r string) int {
+	for i, x := range regs {
+		if x == r {
+			return i
+		}
+	}
+	return len(regs)
+}
+
+// owned: O registrador terminou com algum dono?
+func owned(a *Allocation, r string) bool {
+	for _, x := range a.Reg {
+		if x == r {
+			return true
+		}
+	}
+	for _, x := range a.VarReg {
+		if x == r {
+			return true
+		}
+	}
+	return false
+}
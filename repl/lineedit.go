@@ -0,0 +1,213 @@
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// Edição de linha para o terminal, sem bibliotecas externas: o terminal fica
+// em modo cru enquanto a linha é digitada (rawMode, por sistema) e as teclas
+// são tratadas aqui. Fora do readLine o terminal volta ao normal, para que o
+// 'input' dos programas leia com o eco e a edição do próprio terminal.
+//
+//	← →, Home/End, Ctrl-A/E   move o cursor
+//	↑ ↓                       histórico
+//	Backspace, Delete         apaga
+//	Ctrl-U / Ctrl-K           apaga até o início / até o fim
+//	Ctrl-C                    descarta a linha
+//	Ctrl-D                    sai (com a linha vazia)
+
+// maxHistory: Entradas guardadas no arquivo do histórico.
+const maxHistory = 500
+
+// lineEditor: O editor de linha e o histórico.
+type lineEditor struct {
+	fd      int
+	in      *bufio.Reader
+	out     io.Writer
+	path    string
+	history []string
+}
+
+// newLineEditor: Um editor, se a entrada for um terminal. r é o leitor
+// compartilhado com o interpretador (o que um lê o outro não perde).
+func newLineEditor(in io.Reader, r *bufio.Reader, out io.Writer, path string) (*lineEditor, bool) {
+	f, ok := in.(*os.File)
+	if !ok || !isTerminal(int(f.Fd())) {
+		return nil, false
+	}
+	ed := &lineEditor{fd: int(f.Fd()), in: r, out: out, path: path}
+	if path != "" {
+		if data, err := os.ReadFile(path); err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				if line != "" {
+					ed.history = append(ed.history, line)
+				}
+			}
+		}
+	}
+	return ed, true
+}
+
+// save: Grava o histórico (as últimas maxHistory linhas).
+func (ed *lineEditor) save() error {
+	if ed == nil || ed.path == "" {
+		return nil
+	}
+	h := ed.history
+	if len(h) > maxHistory {
+		h = h[len(h)-maxHistory:]
+	}
+	return os.WriteFile(ed.path, []byte(strings.Join(h, "\n")+"\n"), 0600)
+}
+
+// readLine: Uma linha digitada. false no Ctrl-D com a linha vazia ou no fim
+// da entrada.
+func (ed *lineEditor) readLine(prompt string) (string, bool) {
+	restore, err := rawMode(ed.fd)
+	if err != nil {
+		// Sem modo cru, a linha é lida como o terminal a entregar.
+		fmt.Fprint(ed.out, prompt)
+		line, err := ed.in.ReadString('\n')
+		if err != nil && line == "" {
+			return "", false
+		}
+		return strings.TrimRight(line, "\r\n"), true
+	}
+	defer restore()
+
+	var buf []rune
+	pos := 0
+	hist := len(ed.history) // Posição no histórico (len: a linha nova)
+	draft := ""             // A linha nova, guardada ao navegar no histórico
+	redraw := func() {
+		// Volta ao início, reescreve e apaga o resto; o cursor recua até pos.
+		fmt.Fprintf(ed.out, "\r%s%s\x1b[K", prompt, string(buf))
+		if back := len(buf) - pos; back > 0 {
+			fmt.Fprintf(ed.out, "\x1b[%dD", back)
+		}
+	}
+	setLine := func(s string) {
+		buf = []rune(s)
+		pos = len(buf)
+		redraw()
+	}
+	redraw()
+
+	for {
+		c, err := ed.in.ReadByte()
+		if err != nil {
+			return "", false
+		}
+		switch c {
+		case '\r', '\n':
+			fmt.Fprint(ed.out, "\r\n")
+			line := string(buf)
+			if strings.TrimSpace(line) != "" && (len(ed.history) == 0 || ed.history[len(ed.history)-1] != line) {
+				ed.history = append(ed.history, line)
+			}
+			return line, true
+		case 1: // Ctrl-A
+			pos = 0
+		case 3: // Ctrl-C
+			fmt.Fprint(ed.out, "^C\r\n")
+			buf, pos = nil, 0
+		case 4: // Ctrl-D
+			if len(buf) == 0 {
+				return "", false
+			}
+			if pos < len(buf) {
+				buf = append(buf[:pos], buf[pos+1:]...)
+			}
+		case 5: // Ctrl-E
+			pos = len(buf)
+		case 11: // Ctrl-K
+			buf = buf[:pos]
+		case 21: // Ctrl-U
+			buf, pos = buf[pos:], 0
+		case 8, 127: // Backspace
+			if pos > 0 {
+				buf = append(buf[:pos-1], buf[pos:]...)
+				pos--
+			}
+		case 27: // Sequência de escape: ESC [ x ou ESC O x
+			b1, _ := ed.in.ReadByte()
+			b2, _ := ed.in.ReadByte()
+			if b1 != '[' && b1 != 'O' {
+				break
+			}
+			if b2 >= '0' && b2 <= '9' {
+				// ESC [ n ~ (Home, Delete, End)
+				if t, _ := ed.in.ReadByte(); t != '~' {
+					break
+				}
+				switch b2 {
+				case '1', '7':
+					pos = 0
+				case '4', '8':
+					pos = len(buf)
+				case '3':
+					if pos < len(buf) {
+						buf = append(buf[:pos], buf[pos+1:]...)
+					}
+				}
+				break
+			}
+			switch b2 {
+			case 'A': // ↑
+				if hist > 0 {
+					if hist == len(ed.history) {
+						draft = string(buf)
+					}
+					hist--
+					setLine(ed.history[hist])
+				}
+			case 'B': // ↓
+				if hist < len(ed.history) {
+					hist++
+					if hist == len(ed.history) {
+						setLine(draft)
+					} else {
+						setLine(ed.history[hist])
+					}
+				}
+			case 'C': // →
+				if pos < len(buf) {
+					pos++
+				}
+			case 'D': // ←
+				if pos > 0 {
+					pos--
+				}
+			case 'H':
+				pos = 0
+			case 'F':
+				pos = len(buf)
+			}
+		default:
+			if c < 32 {
+				break // Outros caracteres de controle: ignorados
+			}
+			r := rune(c)
+			if c >= utf8.RuneSelf {
+				// Os bytes restantes do caractere UTF-8.
+				seq := []byte{c}
+				for !utf8.FullRune(seq) && len(seq) < utf8.UTFMax {
+					b, err := ed.in.ReadByte()
+					if err != nil {
+						break
+					}
+					seq = append(seq, b)
+				}
+				r, _ = utf8.DecodeRune(seq)
+			}
+			buf = append(buf[:pos], append([]rune{r}, buf[pos:]...)...)
+			pos++
+		}
+		redraw()
+	}
+}
@@ -0,0 +1,377 @@
+package repl
+
+import (
+	"bufio"
+	"csigma/ast"
+	"csigma/codegen"
+	"csigma/interp"
+	"csigma/ir"
+	"csigma/lexer"
+	"csigma/opt"
+	"csigma/parser"
+	"csigma/semantic"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// REPL (csigma repl): lê um comando por vez, passa pelas fases do compilador
+// e o executa no interpretador (pacote interp), mostrando o resultado na hora.
+//
+//   - a Tabela de Símbolos do Analisador Semântico continua de uma entrada para
+//     a outra (um comando com erro não deixa declarações pela metade);
+//   - uma expressão solta ('x * 3') é mostrada como se fosse 'print x * 3';
+//   - uma entrada incompleta (texto sem fechar, parêntese aberto, operador no
+//     fim) continua na linha seguinte, com o prompt '...';
+//   - :tokens, :ast e :asm mostram o que cada fase produziu para a última entrada.
+//
+// As linhas são numeradas na sessão inteira, como se as entradas aceitas
+// formassem um único arquivo .sig (é esse arquivo que o :asm compila).
+
+// Options: As opções da sessão.
+type Options struct {
+	AbortOnInvalidInput bool   // Como --input=abort
+	History             string // Arquivo do histórico ("" para não gravar)
+}
+
+const (
+	prompt     = "sigma> "
+	contPrompt = "   ... "
+)
+
+const help = `Digite comandos do Sigma ('var x = 10', 'x = x * 3', 'print x') ou uma
+expressão para ver o valor. Uma linha vazia termina uma entrada incompleta.
+
+  :tokens   tokens da última entrada (lexer)
+  :ast      árvore da última entrada, com os tipos (parser e Analisador Semântico)
+  :asm      assembly gerado para a última entrada (backend NASM)
+  :vars     variáveis e constantes declaradas
+  :help     esta ajuda
+  :quit     sai (também :sair ou Ctrl-D)
+`
+
+// Session: O estado do REPL.
+type Session struct {
+	in       *bufio.Reader
+	out      io.Writer
+	editor   *lineEditor // nil quando a entrada não é um terminal
+	opts     interp.Options
+	analyzer *semantic.SemanticAnalyzer
+	machine  *interp.Machine
+
+	source []string // Entradas aceitas, na ordem (o "arquivo" da sessão)
+	lines  int      // Linhas em source
+
+	// A última entrada aceita, para :tokens, :ast e :asm.
+	lastTokens     []lexer.Token
+	lastStatements []parser.Statement
+	lastFirst      int // Primeira e última linha da entrada na sessão
+	lastLast       int
+}
+
+// Run: Atende o usuário até :quit ou o fim da entrada. Prompts e edição de
+// linha só aparecem quando a entrada é um terminal.
+func Run(in io.Reader, out io.Writer, opts Options) error {
+	s := &Session{in: bufio.NewReader(in), out: out, analyzer: semantic.NewAnalyzer(),
+		opts: interp.Options{AbortOnInvalidInput: opts.AbortOnInvalidInput}}
+	s.machine = interp.New(s.analyzer.TabelaSimbolos, s.in, out, s.opts)
+	if ed, ok := newLineEditor(in, s.in, out, opts.History); ok {
+		s.editor = ed
+		fmt.Fprintln(out, "CSigma REPL. Digite :help para ajuda e :quit para sair.")
+	}
+	for {
+		src, ok := s.read()
+		if !ok {
+			if s.editor != nil {
+				fmt.Fprintln(out)
+			}
+			return s.editor.save()
+		}
+		trimmed := strings.TrimSpace(src)
+		if strings.HasPrefix(trimmed, ":") {
+			if !s.command(trimmed) {
+				return s.editor.save()
+			}
+			continue
+		}
+		if trimmed != "" {
+			s.eval(src)
+		}
+	}
+}
+
+// read: Uma entrada completa, com as linhas de continuação.
+func (s *Session) read() (string, bool) {
+	var lines []string
+	for {
+		p := prompt
+		if len(lines) > 0 {
+			p = contPrompt
+		}
+		line, ok := s.readLine(p)
+		if !ok {
+			if len(lines) > 0 {
+				return strings.Join(lines, "\n"), true
+			}
+			return "", false
+		}
+		if len(lines) > 0 && strings.TrimSpace(line) == "" {
+			break // Linha vazia: a entrada termina como está
+		}
+		lines = append(lines, line)
+		src := strings.Join(lines, "\n")
+		if strings.HasPrefix(strings.TrimSpace(src), ":") || !incomplete(src) {
+			break
+		}
+	}
+	return strings.Join(lines, "\n"), true
+}
+
+func (s *Session) readLine(p string) (string, bool) {
+	if s.editor != nil {
+		return s.editor.readLine(p)
+	}
+	line, err := s.in.ReadString('\n')
+	if err != nil && line == "" {
+		return "", false
+	}
+	return strings.TrimRight(line, "\r\n"), true
+}
+
+// --- AVALIAÇÃO ---
+
+// eval: Analisa e executa uma entrada. Erros de qualquer fase são mostrados e
+// a sessão continua.
+func (s *Session) eval(src string) {
+	tokens, statements, err := s.parse(src)
+	if err != nil || (len(statements) == 0 && len(tokens) > 1) {
+		// Talvez uma expressão solta: mostra o valor.
+		if t, st, e := s.parse("print " + src); e == nil && len(st) > 0 {
+			src, tokens, statements, err = "print "+src, t, st, nil
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(s.out, "[ERRO SINTATICO] %v\n", err)
+		return
+	}
+	if len(statements) == 0 {
+		if len(tokens) > 1 {
+			fmt.Fprintln(s.out, "[ERRO SINTATICO] entrada sem nenhum comando")
+		}
+		return
+	}
+
+	if !s.validate(statements) {
+		return
+	}
+	s.source = append(s.source, src)
+	s.lastTokens, s.lastStatements = tokens, statements
+	s.lastFirst, s.lastLast = s.lines+1, s.lines+strings.Count(src, "\n")+1
+	s.lines = s.lastLast
+
+	for _, stmt := range statements {
+		err := s.machine.Exec(stmt)
+		if _, ok := stmt.(*parser.InputNode); ok && s.editor != nil {
+			s.skipNewline()
+		}
+		if err != nil {
+			fmt.Fprintln(s.out, err)
+			return
+		}
+	}
+}
+
+// skipNewline: O 'input' deixa o fim da linha digitada na entrada, que o
+// editor leria como uma linha vazia.
+func (s *Session) skipNewline() {
+	for s.in.Buffered() > 0 {
+		c, _ := s.in.ReadByte()
+		if c == '\n' {
+			return
+		}
+		if c != ' ' && c != '\t' && c != '\r' {
+			s.in.UnreadByte()
+			return
+		}
+	}
+}
+
+// parse: Tokens e comandos de uma entrada, com as linhas já contadas na sessão.
+func (s *Session) parse(src string) ([]lexer.Token, []parser.Statement, error) {
+	tokens := lexer.Tokenize(src)
+	for i := range tokens {
+		tokens[i].Line += s.lines
+	}
+	statements, err := parser.NewParser(tokens).ParseProgram()
+	return tokens, statements, err
+}
+
+// validate: Passa os comandos pelo Analisador Semântico da sessão. Com erro,
+// desfaz as declarações que a entrada acrescentou à Tabela de Símbolos.
+func (s *Session) validate(statements []parser.Statement) bool {
+	before := map[string]bool{}
+	for name := range s.analyzer.TabelaSimbolos {
+		before[name] = true
+	}
+	s.analyzer.Validar(statements)
+	if len(s.analyzer.Erros) == 0 {
+		return true
+	}
+	for _, e := range s.analyzer.Erros {
+		fmt.Fprintf(s.out, "[ERRO SEMANTICO] %s\n", e)
+	}
+	s.analyzer.Erros, s.analyzer.Linhas = s.analyzer.Erros[:0], s.analyzer.Linhas[:0]
+	for name := range s.analyzer.TabelaSimbolos {
+		if !before[name] {
+			delete(s.analyzer.TabelaSimbolos, name)
+		}
+	}
+	return false
+}
+
+// incomplete: A entrada pede mais linhas: texto sem fechar, parênteses ou
+// colchetes abertos, ou um operador, '=' ou ',' no fim.
+func incomplete(src string) bool {
+	depth := 0
+	inString := false
+	last := byte(0) // Último caractere significativo fora de textos
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case inString:
+			if c == '"' {
+				inString = false
+				last = c
+			}
+			continue
+		case c == '"':
+			inString = true
+		case c == '/' && i+1 < len(src) && src[i+1] == '/':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			continue
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		}
+		if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+			last = c
+		}
+	}
+	if inString || depth > 0 {
+		return true
+	}
+	return last != 0 && strings.IndexByte("+-*/=,", last) >= 0
+}
+
+// --- META-COMANDOS ---
+
+// command: Executa um meta-comando. Devolve false para sair.
+func (s *Session) command(cmd string) bool {
+	switch cmd {
+	case ":quit", ":q", ":sair":
+		return false
+	case ":help", ":ajuda":
+		io.WriteString(s.out, help)
+	case ":tokens":
+		if s.last() {
+			for _, tok := range s.lastTokens {
+				fmt.Fprintf(s.out, "%4d:%-3d %-8s %q\n", tok.Line, tok.Column, tok.Type, tok.Literal)
+			}
+		}
+	case ":ast":
+		if s.last() {
+			io.WriteString(s.out, ast.Tree(s.lastStatements))
+		}
+	case ":asm":
+		if s.last() {
+			listing, err := s.asm()
+			if err != nil {
+				fmt.Fprintf(s.out, "[ERRO] %v\n", err)
+			} else {
+				io.WriteString(s.out, listing)
+			}
+		}
+	case ":vars":
+		s.vars()
+	default:
+		fmt.Fprintf(s.out, "Comando desconhecido: %s (:help lista os comandos)\n", cmd)
+	}
+	return true
+}
+
+func (s *Session) last() bool {
+	if s.lastStatements == nil {
+		fmt.Fprintln(s.out, "Nenhuma entrada ainda.")
+		return false
+	}
+	return true
+}
+
+// vars: A tabela das variáveis, com tipo e valor atual.
+func (s *Session) vars() {
+	list := s.machine.Variables()
+	if len(list) == 0 {
+		fmt.Fprintln(s.out, "Nenhuma variável declarada.")
+		return
+	}
+	for _, v := range list {
+		kind := "var"
+		if v.Constant {
+			kind = "const"
+		}
+		value := v.Value.String()
+		if v.Elements != nil {
+			parts := make([]string, len(v.Elements))
+			for i, e := range v.Elements {
+				parts[i] = e.String()
+			}
+			value = "[" + strings.Join(parts, ", ") + "]"
+		}
+		if v.Tipo == "SIGMA_STR" && v.Elements == nil {
+			value = fmt.Sprintf("%q", value)
+		}
+		fmt.Fprintf(s.out, "  %-5s %-12s %-9s = %s\n", kind, v.Name, v.Tipo, value)
+	}
+}
+
+// asm: Compila a sessão inteira, como um arquivo .sig, e mostra da listagem
+// só as linhas da última entrada.
+func (s *Session) asm() (string, error) {
+	src := strings.Join(s.source, "\n") + "\n"
+	statements, err := parser.NewParser(lexer.Tokenize(src)).ParseProgram()
+	if err != nil {
+		return "", err
+	}
+	analyzer := semantic.NewAnalyzer()
+	analyzer.Validar(statements)
+	if len(analyzer.Erros) > 0 {
+		return "", errors.New(analyzer.Erros[0])
+	}
+	prog := ir.Lower(statements, ir.Options{AbortOnInvalidInput: s.opts.AbortOnInvalidInput})
+	if erros := opt.Optimize(prog, 0); len(erros) > 0 {
+		return "", errors.New(erros[0].Message)
+	}
+	listing, err := codegen.Listing(codegen.Backends["nasm"], prog, codegen.Options{Peephole: true}, src)
+	if err != nil {
+		return "", err
+	}
+
+	// Cada linha do fonte ('  12 | ...') vem seguida das suas instruções
+	// ('     | ...').
+	var sb strings.Builder
+	keep := false
+	for _, line := range strings.SplitAfter(listing, "\n") {
+		var n int
+		if _, err := fmt.Sscanf(line, "%d |", &n); err == nil {
+			keep = n >= s.lastFirst && n <= s.lastLast
+		}
+		if keep {
+			sb.WriteString(line)
+		}
+	}
+	return sb.String(), nil
+}
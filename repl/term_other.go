@@ -0,0 +1,20 @@
+//go:build !linux
+
+package repl
+
+import (
+	"errors"
+	"os"
+)
+
+// Terminal nos outros sistemas: sem modo cru, as linhas são lidas com a
+// edição do próprio terminal (e sem o histórico das setas).
+
+func isTerminal(fd int) bool {
+	fi, err := os.Stdin.Stat()
+	return err == nil && int(os.Stdin.Fd()) == fd && fi.Mode()&os.ModeCharDevice != 0
+}
+
+func rawMode(fd int) (func(), error) {
+	return nil, errors.New("modo cru não suportado")
+}
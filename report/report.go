@@ -0,0 +1,159 @@
+package report
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// Relatório estruturado (--report=json): as mesmas informações do relatório
+// em texto (.log), em um documento JSON para scripts de correção e editores.
+// Version muda sempre que um campo existente muda de sentido ou de formato;
+// campos novos podem aparecer sem mudar a versão.
+
+// Version: A versão do formato do documento.
+const Version = 1
+
+// Report: O documento completo. Os campos seguem a ordem das fases; os de
+// uma fase que não chegou a rodar ficam vazios.
+type Report struct {
+	Version     int          `json:"version"`
+	Source      string       `json:"source"`
+	Date        string       `json:"date"` // RFC 3339
+	Options     Options      `json:"options"`
+	Success     bool         `json:"success"`
+	Executable  string       `json:"executable,omitempty"`
+	Tokens      []Token      `json:"tokens"`
+	AST         []Statement  `json:"ast"`
+	Symbols     []Symbol     `json:"symbols"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	IR          string       `json:"ir,omitempty"` // Só com --emit=ir
+	Output      *Output      `json:"output,omitempty"`
+	Commands    []Command    `json:"commands"`
+	Phases      []Phase      `json:"phases"`
+
+	current *Phase    // Fase em andamento
+	start   time.Time // Início dela
+}
+
+// Options: As opções da linha de comando que mudam o resultado.
+type Options struct {
+	Backend  string   `json:"backend"`
+	OptLevel int      `json:"opt_level"`
+	Input    string   `json:"input"`
+	Peephole bool     `json:"peephole"`
+	NoLibc   bool     `json:"nolibc"`
+	PIE      bool     `json:"pie"`
+	Debug    bool     `json:"debug"`
+	Emit     []string `json:"emit"`
+}
+
+// Token: Um token do analisador léxico, com a posição no fonte.
+type Token struct {
+	Type    string `json:"type"`
+	Literal string `json:"literal"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+}
+
+// Statement: Um comando da AST, como no dump do relatório em texto.
+type Statement struct {
+	Kind string `json:"kind"`
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+// Symbol: Uma entrada da tabela de símbolos do analisador semântico.
+type Symbol struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Size     int    `json:"size,omitempty"` // Elementos (vetores)
+	Constant bool   `json:"constant,omitempty"`
+	Value    string `json:"value,omitempty"` // Constantes
+}
+
+// Diagnostic: Um erro ou aviso de uma fase.
+type Diagnostic struct {
+	Phase    string `json:"phase"`
+	Severity string `json:"severity"` // "error" ou "warning"
+	Line     int    `json:"line,omitempty"`
+	Message  string `json:"message"`
+}
+
+// Output: O código gerado (Assembly ou C) e o arquivo em que foi gravado.
+type Output struct {
+	Backend string `json:"backend"` // nasm, gas, elf (Assembly NASM) ou c
+	Path    string `json:"path"`
+	Code    string `json:"code"`
+	Listing string `json:"listing,omitempty"` // Só com --emit=listing
+}
+
+// Command: Um programa externo chamado na montagem ou na linkagem.
+type Command struct {
+	Args     []string `json:"args"`
+	ExitCode int      `json:"exit_code"` // -1 se nem chegou a rodar
+	Stderr   string   `json:"stderr"`
+	Seconds  float64  `json:"seconds"`
+}
+
+// Phase: O tempo gasto em uma fase.
+type Phase struct {
+	Name    string  `json:"name"`
+	Seconds float64 `json:"seconds"`
+}
+
+// New: Um relatório vazio para o fonte.
+func New(source string, opts Options) *Report {
+	return &Report{
+		Version:     Version,
+		Source:      source,
+		Date:        time.Now().Format(time.RFC3339),
+		Options:     opts,
+		Tokens:      []Token{},
+		AST:         []Statement{},
+		Symbols:     []Symbol{},
+		Diagnostics: []Diagnostic{},
+		Commands:    []Command{},
+		Phases:      []Phase{},
+	}
+}
+
+// Begin: Encerra a fase em andamento (se houver) e começa a cronometrar outra.
+func (r *Report) Begin(name string) {
+	r.end()
+	r.Phases = append(r.Phases, Phase{Name: name})
+	r.current = &r.Phases[len(r.Phases)-1]
+	r.start = time.Now()
+}
+
+func (r *Report) end() {
+	if r.current != nil {
+		r.current.Seconds = time.Since(r.start).Seconds()
+		r.current = nil
+	}
+}
+
+// Error e Warning: Registram um diagnóstico da fase em andamento. ErrorAt e
+// WarningAt também guardam a linha do fonte (0: sem linha).
+func (r *Report) Error(msg string)               { r.diagnostic("error", 0, msg) }
+func (r *Report) Warning(msg string)             { r.diagnostic("warning", 0, msg) }
+func (r *Report) ErrorAt(line int, msg string)   { r.diagnostic("error", line, msg) }
+func (r *Report) WarningAt(line int, msg string) { r.diagnostic("warning", line, msg) }
+
+func (r *Report) diagnostic(severity string, line int, msg string) {
+	d := Diagnostic{Severity: severity, Line: line, Message: msg}
+	if r.current != nil {
+		d.Phase = r.current.Name
+	}
+	r.Diagnostics = append(r.Diagnostics, d)
+}
+
+// Write: Encerra a última fase e grava o documento em path.
+func (r *Report) Write(path string) error {
+	r.end()
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -4,17 +4,40 @@ import (
 	"csigma/lexer"
 	"csigma/parser"
 	"fmt"
+	"math"
 	"strconv"
+	"strings"
 )
 
 type Simbolo struct {
-	Nome string
-	Tipo string // SIGMA_INT ou SIGMA_FLT
+	Nome      string
+	Tipo      string // SIGMA_INT, SIGMA_FLT ou SIGMA_STR (nos vetores, o tipo dos elementos)
+	Tamanho   int    // Quantidade de elementos; 0 para variáveis simples
+	Constante bool   // Declarado com 'const': somente leitura
+	Valor     string // Valor calculado em tempo de compilação (apenas constantes)
 }
 
+// funcoesEmbutidas: Funções nativas do Sigma e o tipo que cada uma devolve.
+var funcoesEmbutidas = map[string]string{
+	"eof": "SIGMA_INT", // 1 depois que um 'input' encontrou o fim da entrada
+	"len": "SIGMA_INT", // Quantidade de elementos de um vetor: len(v)
+}
+
+// FuncaoEmbutida: O tipo devolvido pela função nativa (eof, len), se existir.
+func FuncaoEmbutida(nome string) (string, bool) {
+	tipo, existe := funcoesEmbutidas[nome]
+	return tipo, existe
+}
+
+// limiteVetores: Quantos elementos (de 8 bytes) todos os vetores do programa
+// somam no máximo: 2^24, ou 128 MiB. Eles ficam na .bss, e o código chega a
+// eles por deslocamentos de 32 bits relativos ao RIP, que não passam de 2 GiB.
+const limiteVetores = 1 << 24
+
 type SemanticAnalyzer struct {
 	TabelaSimbolos map[string]Simbolo
 	Erros          []string
+	Linhas         []int // Linha do comando que causou cada erro de Erros
 }
 
 func NewAnalyzer() *SemanticAnalyzer {
@@ -25,14 +48,7 @@ func NewAnalyzer() *SemanticAnalyzer {
 }
 
 func (a *SemanticAnalyzer) Analisar(statements []parser.Statement) error {
-	for _, stmt := range statements {
-		switch n := stmt.(type) {
-		case *parser.VarDeclNode:
-			a.validarDeclaracao(n)
-		case *parser.AssignmentNode:
-			a.validarAtribuicao(n)
-		}
-	}
+	a.Validar(statements)
 
 	if len(a.Erros) > 0 {
 		fmt.Println("\n--- ERROS SEMÂNTICOS ENCONTRADOS ---")
@@ -44,14 +60,75 @@ func (a *SemanticAnalyzer) Analisar(statements []parser.Statement) error {
 	return nil
 }
 
+// Validar: Valida os comandos e acumula os problemas em Erros, sem imprimir
+// nada. A Tabela de Símbolos continua entre chamadas, então as ferramentas
+// (lint, editor) podem validar um comando por vez. A linha do comando de cada
+// erro fica em Linhas, na mesma posição.
+func (a *SemanticAnalyzer) Validar(statements []parser.Statement) {
+	for _, stmt := range statements {
+		a.validarComando(stmt)
+		for len(a.Linhas) < len(a.Erros) {
+			a.Linhas = append(a.Linhas, parser.SpanOf(stmt).Line)
+		}
+	}
+}
+
+func (a *SemanticAnalyzer) validarComando(stmt parser.Statement) {
+	switch n := stmt.(type) {
+	case *parser.VarDeclNode:
+		a.validarDeclaracao(n)
+	case *parser.ConstDeclNode:
+		a.validarConstante(n)
+	case *parser.AssignmentNode:
+		a.validarAtribuicao(n)
+	case *parser.PrintNode:
+		a.validarPrint(n)
+	case *parser.InputNode:
+		a.validarInput(n)
+	}
+}
+
 func (a *SemanticAnalyzer) validarDeclaracao(n *parser.VarDeclNode) {
 	if _, existe := a.TabelaSimbolos[n.Name]; existe {
 		a.Erros = append(a.Erros, fmt.Sprintf("variável '%s' já declarada", n.Name))
 		return
 	}
 
-	// Comentário didático: Identifica o tipo do valor inicial (10 vs 10.5)
+	// Vetores: o tipo vem da declaração ('var v: float[10]'), não de um valor inicial
+	if n.ElemType != "" {
+		if n.SizeConst != "" {
+			tamanho, ok := a.tamanhoConstante(n.SizeConst)
+			if !ok {
+				return
+			}
+			n.Size = tamanho
+		}
+		if n.Size <= 0 {
+			a.Erros = append(a.Erros, fmt.Sprintf("Vetor Inválido: '%s' precisa de ao menos 1 elemento", n.Name))
+			return
+		}
+		total := n.Size
+		for _, s := range a.TabelaSimbolos {
+			total += s.Tamanho
+		}
+		if n.Size > limiteVetores || total > limiteVetores {
+			a.Erros = append(a.Erros, fmt.Sprintf("Vetor Grande Demais: '%s' tem %d elementos, e os vetores do programa somam no máximo %d (128 MiB)",
+				n.Name, n.Size, limiteVetores))
+			return
+		}
+		tipo := "SIGMA_INT"
+		if n.ElemType == "float" {
+			tipo = "SIGMA_FLT"
+		}
+		a.TabelaSimbolos[n.Name] = Simbolo{Nome: n.Name, Tipo: tipo, Tamanho: n.Size}
+		return
+	}
+
+	// Comentário didático: Identifica o tipo do valor inicial (10 vs 10.5 vs "texto")
 	tipo := a.inferirTipo(fmt.Sprintf("%v", n.Value))
+	if n.IsString {
+		tipo = "SIGMA_STR"
+	}
 	a.TabelaSimbolos[n.Name] = Simbolo{Nome: n.Name, Tipo: tipo}
 }
 
@@ -87,28 +164,178 @@ func (a *SemanticAnalyzer) validarDeclaracao(n *parser.VarDeclNode) {
 // }
 
 func (a *SemanticAnalyzer) validarAtribuicao(n *parser.AssignmentNode) {
-	// 1. Verifica se a variável de destino existe
-	simboloDest, existe := a.TabelaSimbolos[n.Dest]
+	// 1. Verifica se a variável de destino existe (e se o índice faz sentido)
+	simboloDest, existe := a.validarAcesso(n.Dest, n.DestIndex)
 	if !existe {
-		a.Erros = append(a.Erros, fmt.Sprintf("variável '%s' não declarada", n.Dest))
 		return
 	}
+	if simboloDest.Constante {
+		a.Erros = append(a.Erros, fmt.Sprintf("Atribuição Inválida: '%s' é uma constante", n.Dest))
+		return
+	}
+
+	// 2. e 3. Obtém o tipo da expressão, validando cada operação encadeada
+	tipoExpressao := a.tipoDaExpressao(&n.ExprNode)
+
+	// 4. Regra B: O resultado da expressão deve caber no tipo da variável de destino
+	// (SIGMA_UNKNOWN já foi relatado na própria expressão)
+	if tipoExpressao != simboloDest.Tipo && tipoExpressao != "SIGMA_UNKNOWN" {
+		a.Erros = append(a.Erros, fmt.Sprintf("Conflito de Atribuição: '%s' é %s, mas recebeu %s",
+			n.Dest, simboloDest.Tipo, tipoExpressao))
+	}
+}
+
+// validarConstante: Tipa e avalia 'const NOME = expressão' em tempo de compilação.
+// O resultado fica na Tabela de Símbolos e no próprio nó, para o CodeGen embutir.
+func (a *SemanticAnalyzer) validarConstante(n *parser.ConstDeclNode) {
+	if _, existe := a.TabelaSimbolos[n.Name]; existe {
+		a.Erros = append(a.Erros, fmt.Sprintf("variável '%s' já declarada", n.Name))
+		return
+	}
+
+	tipo := a.tipoDaExpressao(&n.ExprNode)
+	simbolo := Simbolo{Nome: n.Name, Tipo: tipo, Constante: true}
+	if tipo != "SIGMA_UNKNOWN" {
+		valor, err := a.avaliarConstante(&n.ExprNode)
+		if err != nil {
+			a.Erros = append(a.Erros, fmt.Sprintf("Constante Inválida: '%s': %v", n.Name, err))
+		}
+		n.Valor = valor
+		simbolo.Valor = valor
+	}
+	a.TabelaSimbolos[n.Name] = simbolo
+}
+
+// avaliarConstante: Calcula a expressão linear (já tipada) da esquerda para a direita,
+// aceitando apenas literais, outras constantes e len(v).
+func (a *SemanticAnalyzer) avaliarConstante(e *parser.ExprNode) (string, error) {
+	if e.Tipo == "SIGMA_STR" {
+		if len(e.Ops) > 0 {
+			return "", fmt.Errorf("textos não participam da aritmética")
+		}
+		return a.valorConstante(e.First)
+	}
+
+	primeiro, err := a.valorConstante(e.First)
+	if err != nil {
+		return "", err
+	}
+
+	if e.Tipo == "SIGMA_FLT" {
+		acc, _ := strconv.ParseFloat(primeiro, 64)
+		for _, op := range e.Ops {
+			s, err := a.valorConstante(op.Operand)
+			if err != nil {
+				return "", err
+			}
+			v, _ := strconv.ParseFloat(s, 64)
+			switch op.Operator {
+			case "+":
+				acc += v
+			case "-":
+				acc -= v
+			case "*":
+				acc *= v
+			case "/":
+				if v == 0 {
+					return "", fmt.Errorf("divisão por zero")
+				}
+				acc /= v
+			}
+		}
+		return formatarDecimal(acc), nil
+	}
+
+	acc, _ := strconv.ParseInt(primeiro, 10, 64)
+	for _, op := range e.Ops {
+		s, err := a.valorConstante(op.Operand)
+		if err != nil {
+			return "", err
+		}
+		v, _ := strconv.ParseInt(s, 10, 64)
+		switch op.Operator {
+		case "+":
+			acc += v
+		case "-":
+			acc -= v
+		case "*":
+			acc *= v
+		case "/":
+			if v == 0 {
+				return "", fmt.Errorf("divisão por zero")
+			}
+			if acc == math.MinInt64 && v == -1 {
+				// O Go daria -9223372036854775808; o idiv derruba o programa.
+				return "", fmt.Errorf("divisão de %d por -1 transborda (o idiv termina com SIGFPE)", acc)
+			}
+			acc /= v // Go também trunca em direção ao zero, como o idiv
+		}
+	}
+	return strconv.FormatInt(acc, 10), nil
+}
 
-	// 2. Obtém o tipo do primeiro operando (ex: 'x' em 'x / 2.5')
-	tipoExpressao := a.obterTipoDoValor(n.First)
+// valorConstante: O valor conhecido em compilação de um único operando.
+func (a *SemanticAnalyzer) valorConstante(o parser.Operand) (string, error) {
+	switch {
+	case o.IsCall && o.Value == "len" && len(o.Args) == 1:
+		return strconv.Itoa(a.TabelaSimbolos[o.Args[0].Value].Tamanho), nil
+	case o.IsCall:
+		return "", fmt.Errorf("%s() só é conhecido em execução", o.Value)
+	case o.IsVar:
+		simbolo := a.TabelaSimbolos[o.Value]
+		if !simbolo.Constante {
+			return "", fmt.Errorf("'%s' é uma variável, não uma constante", o.Value)
+		}
+		return simbolo.Valor, nil
+	}
+	return o.Value, nil
+}
 
-	// 3. Verifica as operações seguintes (o que vem depois do operador)
-	for _, op := range n.Rest {
-		tipoOperando := a.obterTipoDoValor(op.Value)
+// tamanhoConstante: Resolve 'var v[MAX]', exigindo uma constante inteira.
+func (a *SemanticAnalyzer) tamanhoConstante(nome string) (int, bool) {
+	simbolo, existe := a.TabelaSimbolos[nome]
+	if !existe || !simbolo.Constante || simbolo.Tipo != "SIGMA_INT" {
+		a.Erros = append(a.Erros, fmt.Sprintf("Vetor Inválido: o tamanho '%s' deve ser uma constante inteira", nome))
+		return 0, false
+	}
+	n, _ := strconv.Atoi(simbolo.Valor)
+	return n, true
+}
+
+// formatarDecimal: Escreve um float64 sempre com ponto decimal ("2" vira "2.0"),
+// para que o NASM o reconheça como ponto flutuante em 'dq'.
+func formatarDecimal(v float64) string {
+	s := strconv.FormatFloat(v, 'f', -1, 64)
+	if !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	return s
+}
+
+// tipoDaExpressao: Percorre a expressão linear, acumula os erros de tipo e
+// anota o resultado em e.Tipo para que o CodeGen saiba como avaliá-la.
+func (a *SemanticAnalyzer) tipoDaExpressao(e *parser.ExprNode) string {
+	// Obtém o tipo do primeiro operando (ex: 'x' em 'x / 2.5')
+	tipoExpressao := a.obterTipoDoOperando(e.First)
+
+	// Verifica as operações seguintes (o que vem depois do operador)
+	for _, op := range e.Ops {
+		tipoOperando := a.obterTipoDoOperando(op.Operand)
 
 		// LOG DIDÁTICO: Vamos ver o que o Sigma está comparando
-		// fmt.Printf("[DEBUG] Comparando %s (%s) com %s (%s)\n", n.First, tipoExpressao, op.Value, tipoOperando)
+		// fmt.Printf("[DEBUG] Comparando %s (%s) com %s (%s)\n", e.First.Value, tipoExpressao, op.Value, tipoOperando)
+
+		// Textos não participam da aritmética
+		if tipoExpressao == "SIGMA_STR" || tipoOperando == "SIGMA_STR" {
+			a.Erros = append(a.Erros, fmt.Sprintf("Operação Inválida: '%s' não se aplica a textos", op.Operator))
+			continue
+		}
 
 		// Opção A: Divisão Estrita - Se for divisão, os tipos TEM que ser iguais
-		if op.Operator == lexer.TokenDiv {
+		if op.Operator == string(lexer.TokenDiv) {
 			if tipoExpressao != tipoOperando {
 				a.Erros = append(a.Erros, fmt.Sprintf("Divisão Inválida: '%s' é %s, mas '%s' é %s",
-					n.First, tipoExpressao, op.Value, tipoOperando))
+					e.First.Value, tipoExpressao, op.Value, tipoOperando))
 			}
 		}
 
@@ -119,11 +346,39 @@ func (a *SemanticAnalyzer) validarAtribuicao(n *parser.AssignmentNode) {
 		}
 	}
 
-	// 4. Regra B: O resultado da expressão deve caber no tipo da variável de destino
-	if tipoExpressao != simboloDest.Tipo {
-		a.Erros = append(a.Erros, fmt.Sprintf("Conflito de Atribuição: '%s' é %s, mas recebeu %s",
-			n.Dest, simboloDest.Tipo, tipoExpressao))
+	e.Tipo = tipoExpressao
+	return tipoExpressao
+}
+
+// validarPrint: Tipa cada expressão a ser impressa. Não há limite de valores:
+// os que não cabem nos registradores de argumento do printf vão pela pilha.
+func (a *SemanticAnalyzer) validarPrint(n *parser.PrintNode) {
+	for _, part := range n.Parts {
+		if part.Expr != nil {
+			a.tipoDaExpressao(part.Expr)
+		}
+	}
+}
+
+// validarInput: Só é possível ler números para uma variável já declarada.
+// O prompt opcional segue as mesmas regras de um print.
+func (a *SemanticAnalyzer) validarInput(n *parser.InputNode) {
+	if n.Prompt != nil {
+		a.validarPrint(n.Prompt)
+	}
+	simbolo, existe := a.validarAcesso(n.VarName, n.Index)
+	if !existe {
+		return
+	}
+	if simbolo.Constante {
+		a.Erros = append(a.Erros, fmt.Sprintf("Entrada Inválida: '%s' é uma constante", n.VarName))
+		return
 	}
+	if simbolo.Tipo == "SIGMA_STR" {
+		a.Erros = append(a.Erros, fmt.Sprintf("Entrada Inválida: 'input' só lê números, mas '%s' é %s",
+			n.VarName, simbolo.Tipo))
+	}
+	n.Tipo = simbolo.Tipo
 }
 
 // inferirTipo verifica se a string é um inteiro ou decimal.
@@ -143,3 +398,84 @@ func (a *SemanticAnalyzer) obterTipoDoValor(v string) string {
 	}
 	return a.inferirTipo(v)
 }
+
+// obterTipoDoOperando: Como obterTipoDoValor, mas sabe distinguir texto e
+// acusa variáveis usadas sem declaração.
+func (a *SemanticAnalyzer) obterTipoDoOperando(o parser.Operand) string {
+	if o.IsString {
+		return "SIGMA_STR"
+	}
+	if o.IsCall {
+		return a.validarChamada(o)
+	}
+	if o.IsVar {
+		simbolo, existe := a.validarAcesso(o.Value, o.Index)
+		if !existe {
+			return "SIGMA_UNKNOWN"
+		}
+		return simbolo.Tipo
+	}
+	return a.obterTipoDoValor(o.Value)
+}
+
+// validarAcesso: Confere o uso de 'x' ou 'v[i]'. Vetores só podem ser usados
+// elemento a elemento, e apenas vetores aceitam índice.
+func (a *SemanticAnalyzer) validarAcesso(nome string, indice *parser.ExprNode) (Simbolo, bool) {
+	simbolo, existe := a.TabelaSimbolos[nome]
+	if !existe {
+		a.Erros = append(a.Erros, fmt.Sprintf("variável '%s' não declarada", nome))
+		return simbolo, false
+	}
+
+	if indice == nil {
+		if simbolo.Tamanho > 0 {
+			a.Erros = append(a.Erros, fmt.Sprintf("Uso de Vetor: '%s' tem %d elementos, use '%s[i]' ou 'len(%s)'",
+				nome, simbolo.Tamanho, nome, nome))
+			return simbolo, false
+		}
+		return simbolo, true
+	}
+
+	if simbolo.Tamanho == 0 {
+		a.Erros = append(a.Erros, fmt.Sprintf("Índice Inválido: '%s' não é um vetor", nome))
+		return simbolo, false
+	}
+	if tipo := a.tipoDaExpressao(indice); tipo != "SIGMA_INT" {
+		a.Erros = append(a.Erros, fmt.Sprintf("Índice Inválido: o índice de '%s' deve ser SIGMA_INT, mas é %s", nome, tipo))
+	}
+
+	// Índices conhecidos em compilação (literais, constantes e len) já podem ser
+	// verificados agora; os demais são conferidos em execução.
+	if valor, err := a.avaliarConstante(indice); err == nil {
+		if i, err := strconv.Atoi(valor); err == nil && (i < 0 || i >= simbolo.Tamanho) {
+			a.Erros = append(a.Erros, fmt.Sprintf("Índice Fora dos Limites: '%s[%d]', mas '%s' tem %d elementos",
+				nome, i, nome, simbolo.Tamanho))
+		}
+	}
+	return simbolo, true
+}
+
+// validarChamada: Verifica o nome e os argumentos de uma função embutida.
+func (a *SemanticAnalyzer) validarChamada(o parser.Operand) string {
+	tipo, existe := funcoesEmbutidas[o.Value]
+	if !existe {
+		a.Erros = append(a.Erros, fmt.Sprintf("função '%s' não existe", o.Value))
+		return "SIGMA_UNKNOWN"
+	}
+
+	switch o.Value {
+	case "len":
+		if len(o.Args) != 1 || !o.Args[0].IsVar || o.Args[0].Index != nil {
+			a.Erros = append(a.Erros, "Chamada Inválida: len espera exatamente um vetor, como em len(v)")
+			break
+		}
+		if simbolo, ok := a.TabelaSimbolos[o.Args[0].Value]; !ok || simbolo.Tamanho == 0 {
+			a.Erros = append(a.Erros, fmt.Sprintf("Chamada Inválida: '%s' não é um vetor declarado", o.Args[0].Value))
+		}
+	default:
+		if len(o.Args) > 0 {
+			a.Erros = append(a.Erros, fmt.Sprintf("Chamada Inválida: %s() não recebe argumentos", o.Value))
+		}
+	}
+	return tipo
+}